@@ -3,42 +3,68 @@ package cli
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"net/http"
-	"os"
 
-	"github.com/replicatedhq/troubleshoot/cmd/util"
-	analyzer "github.com/replicatedhq/troubleshoot/pkg/analyze"
 	"github.com/replicatedhq/troubleshoot/pkg/convert"
 	"github.com/replicatedhq/troubleshoot/pkg/logger"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"gopkg.in/yaml.v2"
+
+	"github.com/croomes/kubectl-plugin/pkg/exitcode"
+	"github.com/croomes/kubectl-plugin/pkg/specloader"
 )
 
 func Analyze() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "analyze [url]",
-		Args:  cobra.MinimumNArgs(1),
+		Use:   "analyze [spec]",
+		Args:  cobra.MaximumNArgs(1),
 		Short: "analyze a support bundle",
 		Long:  `Analyze a support bundle using the Analyzer definitions provided`,
 		PreRun: func(cmd *cobra.Command, args []string) {
 			viper.BindPFlag("bundle", cmd.Flags().Lookup("bundle"))
 			viper.BindPFlag("output", cmd.Flags().Lookup("output"))
+			viper.BindPFlag("compatibility", cmd.Flags().Lookup("compatibility"))
 			viper.BindPFlag("quiet", cmd.Flags().Lookup("quiet"))
+			viper.BindPFlag("private-key", cmd.Flags().Lookup("private-key"))
+			viper.BindPFlag("verify", cmd.Flags().Lookup("verify"))
+			viper.BindPFlag("public-key", cmd.Flags().Lookup("public-key"))
+			viper.BindPFlag("signature", cmd.Flags().Lookup("signature"))
+			viper.BindPFlag("fail-on", cmd.Flags().Lookup("fail-on"))
+			viper.BindPFlag("values", cmd.Flags().Lookup("values"))
+			viper.BindPFlag("set", cmd.Flags().Lookup("set"))
+			viper.BindPFlag("registry-username", cmd.Flags().Lookup("registry-username"))
+			viper.BindPFlag("registry-password-stdin", cmd.Flags().Lookup("registry-password-stdin"))
+			viper.BindPFlag("known-issues", cmd.Flags().Lookup("known-issues"))
+			viper.BindPFlag("signatures", cmd.Flags().Lookup("signatures"))
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			v := viper.GetViper()
 
 			logger.SetQuiet(v.GetBool("quiet"))
 
-			specPath := args[0]
-			analyzerSpec, err := downloadAnalyzerSpec(specPath)
+			if err := exitcode.ValidateFailOn(v.GetString("fail-on")); err != nil {
+				return err
+			}
+
+			if v.GetBool("verify") {
+				signer, err := verifyBundle(v, v.GetString("bundle"))
+				if err != nil {
+					return err
+				}
+				logger.Printf("Good signature from %q\n", signer)
+			}
+
+			dir, cleanup, err := extractBundle(v, v.GetString("bundle"))
 			if err != nil {
 				return err
 			}
+			defer cleanup()
 
-			result, err := analyzer.DownloadAndAnalyze(v.GetString("bundle"), analyzerSpec)
+			spec := specloader.Embedded
+			if len(args) > 0 {
+				spec = args[0]
+			}
+			result, err := analyzeExtractedBundle(v, dir, spec, v.GetBool("known-issues"))
 			if err != nil {
 				return err
 			}
@@ -66,7 +92,7 @@ func Analyze() *cobra.Command {
 			}
 
 			fmt.Printf("%s", formatted)
-			return nil
+			return exitcode.FromResults(result, v.GetString("fail-on"))
 		},
 	}
 
@@ -75,49 +101,18 @@ func Analyze() *cobra.Command {
 	cmd.Flags().String("output", "", "output format: json, yaml")
 	cmd.Flags().String("compatibility", "", "output compatibility mode: support-bundle")
 	cmd.Flags().MarkHidden("compatibility")
+	cmd.Flags().StringSlice("values", []string{}, "values files to render the spec template with")
+	cmd.Flags().StringSlice("set", []string{}, "values to render the spec template with, e.g. namespace=storageos. these override --values")
+	cmd.Flags().String("registry-username", "", "username to authenticate to OCI registries with")
+	cmd.Flags().Bool("registry-password-stdin", false, "read the password to authenticate to OCI registries with from standard input, instead of the REGISTRY_PASSWORD environment variable")
+	cmd.Flags().Bool("known-issues", true, "search the logs of the support bundle for known issues")
+	cmd.Flags().String("signatures", "", "file of known issue signatures to search the logs for. defaults to the built-in signatures")
+	cmd.Flags().String("fail-on", exitcode.FailOnFail, "lowest analysis result that makes the command exit non-zero, one of warn, fail")
 	cmd.Flags().Bool("quiet", false, "enable/disable error messaging and only show parseable output")
-
-	viper.BindPFlags(cmd.Flags())
+	cmd.Flags().String("private-key", "", "OpenPGP private key file to decrypt an encrypted support bundle with")
+	cmd.Flags().Bool("verify", false, "refuse to analyze the support bundle unless its signature is valid")
+	cmd.Flags().String("public-key", "", "OpenPGP public key file of the signer, used with --verify")
+	cmd.Flags().String("signature", "", "detached signature file, used with --verify. defaults to the bundle filename with a .asc extension")
 
 	return cmd
 }
-
-func downloadAnalyzerSpec(specPath string) (string, error) {
-	specContent := ""
-	var err error
-	if _, err = os.Stat(specPath); err == nil {
-		if _, err := os.Stat(specPath); os.IsNotExist(err) {
-			return "", fmt.Errorf("%s was not found", specPath)
-		}
-
-		b, err := ioutil.ReadFile(specPath)
-		if err != nil {
-			return "", err
-		}
-
-		specContent = string(b)
-	} else {
-		if !util.IsURL(specPath) {
-			return "", fmt.Errorf("%s is not a URL and was not found (err %s)", specPath, err)
-		}
-
-		req, err := http.NewRequest("GET", specPath, nil)
-		if err != nil {
-			return "", err
-		}
-		req.Header.Set("User-Agent", "Replicated_Analyzer/v1beta1")
-		resp, err := http.DefaultClient.Do(req)
-		if err != nil {
-			return "", err
-		}
-		defer resp.Body.Close()
-
-		body, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return "", err
-		}
-
-		specContent = string(body)
-	}
-	return specContent, nil
-}
@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/croomes/kubectl-plugin/pkg/bundlecrypt"
+	"github.com/manifoldco/promptui"
+	"github.com/mattn/go-isatty"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func Decrypt() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "decrypt [bundle]",
+		Args:  cobra.ExactArgs(1),
+		Short: "decrypt a support bundle",
+		Long: `Decrypt a support bundle that was encrypted with --encrypt-to. If the
+private key is encrypted, the passphrase is read from the environment, e.g.
+STORAGEOS_PRIVATE_KEY_PASSPHRASE, or prompted for.`,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlag("private-key", cmd.Flags().Lookup("private-key"))
+			viper.BindPFlag("output-file", cmd.Flags().Lookup("output-file"))
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+
+			bundlePath := args[0]
+			outputPath := v.GetString("output-file")
+			if outputPath == "" {
+				outputPath = strings.TrimSuffix(filepath.Base(bundlePath), bundlecrypt.Extension)
+				if outputPath == filepath.Base(bundlePath) {
+					outputPath = outputPath + ".tar.gz"
+				}
+			}
+
+			if err := bundlecrypt.DecryptFile(bundlePath, outputPath, v.GetString("private-key"), keyPassphrase(v, "private-key-passphrase")); err != nil {
+				return errors.Wrap(err, "decrypt support bundle")
+			}
+
+			fmt.Printf("A decrypted support bundle has been created named %q\n", outputPath)
+			return nil
+		},
+	}
+
+	cmd.Flags().String("private-key", "", "OpenPGP private key file to decrypt the support bundle with")
+	cmd.MarkFlagRequired("private-key")
+	cmd.Flags().String("output-file", "", "filename of the decrypted support bundle. defaults to the bundle filename without the .gpg extension")
+
+	return cmd
+}
+
+// decryptedBundle returns the path of a decrypted copy of bundlePath, and a
+// function that removes it. Bundles that aren't encrypted, or aren't local
+// files, are returned as they are.
+func decryptedBundle(v *viper.Viper, bundlePath string) (string, func(), error) {
+	noop := func() {}
+
+	if _, err := os.Stat(bundlePath); err != nil {
+		return bundlePath, noop, nil
+	}
+
+	encrypted, err := bundlecrypt.IsEncrypted(bundlePath)
+	if err != nil {
+		return "", noop, errors.Wrap(err, "check if support bundle is encrypted")
+	}
+	if !encrypted {
+		return bundlePath, noop, nil
+	}
+
+	privateKey := v.GetString("private-key")
+	if privateKey == "" {
+		return "", noop, errors.New("support bundle is encrypted, use --private-key to decrypt it")
+	}
+
+	tmpDir, err := ioutil.TempDir("", "troubleshoot")
+	if err != nil {
+		return "", noop, errors.Wrap(err, "create temp dir")
+	}
+	cleanup := func() {
+		os.RemoveAll(tmpDir)
+	}
+
+	decryptedPath := filepath.Join(tmpDir, strings.TrimSuffix(filepath.Base(bundlePath), bundlecrypt.Extension))
+	if err := bundlecrypt.DecryptFile(bundlePath, decryptedPath, privateKey, keyPassphrase(v, "private-key-passphrase")); err != nil {
+		cleanup()
+		return "", noop, errors.Wrap(err, "decrypt support bundle")
+	}
+
+	return decryptedPath, cleanup, nil
+}
+
+// keyPassphrase returns a function that reads the passphrase of an encrypted
+// key from the environment variable for key, or prompts for it.
+func keyPassphrase(v *viper.Viper, key string) bundlecrypt.PassphraseFunc {
+	return func() ([]byte, error) {
+		if passphrase := v.GetString(key); passphrase != "" {
+			return []byte(passphrase), nil
+		}
+
+		if !isatty.IsTerminal(os.Stdin.Fd()) {
+			return nil, errors.New("private key is encrypted and no passphrase was set in the environment")
+		}
+
+		prompt := promptui.Prompt{
+			Label: "Private key passphrase",
+			Mask:  '*',
+		}
+		passphrase, err := prompt.Run()
+		if err != nil {
+			return nil, err
+		}
+		return []byte(passphrase), nil
+	}
+}
@@ -0,0 +1,171 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/troubleshoot/pkg/logger"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/croomes/kubectl-plugin/pkg/bundlediff"
+	"github.com/croomes/kubectl-plugin/pkg/specloader"
+)
+
+func Diff() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff [bundle-a] [bundle-b]",
+		Args:  cobra.ExactArgs(2),
+		Short: "compare two support bundles",
+		Long: `Compare two support bundles of the same cluster, bundle-a taken before
+bundle-b. Shows the cluster version, nodes, deployments, daemonsets and
+StorageOSCluster specs that changed, the analyzers whose outcome got worse and
+the errors that are only logged in bundle-b.`,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlag("output", cmd.Flags().Lookup("output"))
+			viper.BindPFlag("spec", cmd.Flags().Lookup("spec"))
+			viper.BindPFlag("values", cmd.Flags().Lookup("values"))
+			viper.BindPFlag("set", cmd.Flags().Lookup("set"))
+			viper.BindPFlag("registry-username", cmd.Flags().Lookup("registry-username"))
+			viper.BindPFlag("registry-password-stdin", cmd.Flags().Lookup("registry-password-stdin"))
+			viper.BindPFlag("private-key", cmd.Flags().Lookup("private-key"))
+			viper.BindPFlag("signatures", cmd.Flags().Lookup("signatures"))
+			viper.BindPFlag("quiet", cmd.Flags().Lookup("quiet"))
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+
+			logger.SetQuiet(v.GetBool("quiet"))
+
+			output := v.GetString("output")
+			if output != "" && output != "human" && output != "json" {
+				return fmt.Errorf("unsupported output format: %q", output)
+			}
+
+			a, cleanupA, err := analyzeBundleForDiff(v, args[0])
+			if err != nil {
+				return err
+			}
+			defer cleanupA()
+
+			b, cleanupB, err := analyzeBundleForDiff(v, args[1])
+			if err != nil {
+				return err
+			}
+			defer cleanupB()
+
+			report, err := bundlediff.Diff(a, b)
+			if err != nil {
+				return errors.Wrap(err, "compare support bundles")
+			}
+
+			if output == "json" {
+				formatted, err := json.MarshalIndent(report, "", "    ")
+				if err != nil {
+					return err
+				}
+				fmt.Printf("%s\n", formatted)
+				return nil
+			}
+			printDiffReport(os.Stdout, report)
+			return nil
+		},
+	}
+
+	cmd.Flags().String("output", "human", "output format: human, json")
+	cmd.Flags().String("spec", specloader.Embedded, "spec whose analyzers are run on both support bundles")
+	cmd.Flags().StringSlice("values", []string{}, "values files to render the spec template with")
+	cmd.Flags().StringSlice("set", []string{}, "values to render the spec template with, e.g. namespace=storageos. these override --values")
+	cmd.Flags().String("registry-username", "", "username to authenticate to OCI registries with")
+	cmd.Flags().Bool("registry-password-stdin", false, "read the password to authenticate to OCI registries with from standard input, instead of the REGISTRY_PASSWORD environment variable")
+	cmd.Flags().String("private-key", "", "OpenPGP private key file to decrypt encrypted support bundles with")
+	cmd.Flags().String("signatures", "", "file of known issue signatures to search the logs for. defaults to the built-in signatures")
+	cmd.Flags().Bool("quiet", false, "enable/disable error messaging and only show parseable output")
+
+	return cmd
+}
+
+// analyzeBundleForDiff extracts and analyzes a support bundle. The returned
+// function removes the extracted bundle.
+func analyzeBundleForDiff(v *viper.Viper, bundlePath string) (bundlediff.Bundle, func(), error) {
+	dir, cleanup, err := extractBundle(v, bundlePath)
+	if err != nil {
+		return bundlediff.Bundle{}, nil, err
+	}
+
+	results, err := analyzeExtractedBundle(v, dir, v.GetString("spec"), true)
+	if err != nil {
+		cleanup()
+		return bundlediff.Bundle{}, nil, errors.Wrapf(err, "analyze %s", bundlePath)
+	}
+	return bundlediff.Bundle{Dir: dir, Results: results}, cleanup, nil
+}
+
+func printDiffReport(w io.Writer, report *bundlediff.Report) {
+	if report.Empty() {
+		fmt.Fprintln(w, "No differences found.")
+		return
+	}
+
+	if report.ClusterVersion != nil {
+		fmt.Fprintf(w, "Cluster version: %s -> %s\n\n", valueOrNone(report.ClusterVersion.Before), valueOrNone(report.ClusterVersion.After))
+	}
+
+	printResourceDiffs(w, "Nodes", report.Nodes)
+	printResourceDiffs(w, "Deployments", report.Deployments)
+	printResourceDiffs(w, "DaemonSets", report.DaemonSets)
+	printResourceDiffs(w, "StorageOSClusters", report.StorageOSClusters)
+
+	if len(report.Regressions) > 0 {
+		fmt.Fprintln(w, "Analyzers:")
+		for _, r := range report.Regressions {
+			fmt.Fprintf(w, "  %s: %s -> %s\n", r.Title, valueOrNone(r.Before), r.After)
+			if r.Message != "" {
+				fmt.Fprintf(w, "    %s\n", r.Message)
+			}
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(report.NewLogErrors) > 0 {
+		fmt.Fprintln(w, "New log errors:")
+		for _, s := range report.NewLogErrors {
+			fmt.Fprintf(w, "  %dx %s\n", s.Count, s.Signature)
+			fmt.Fprintf(w, "    in %s\n", strings.Join(s.Files, ", "))
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+func printResourceDiffs(w io.Writer, title string, diffs []bundlediff.ResourceDiff) {
+	if len(diffs) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "%s:\n", title)
+	for _, d := range diffs {
+		switch d.Status {
+		case bundlediff.StatusAdded:
+			fmt.Fprintf(w, "  + %s\n", d.Name)
+		case bundlediff.StatusRemoved:
+			fmt.Fprintf(w, "  - %s\n", d.Name)
+		default:
+			fmt.Fprintf(w, "  ~ %s\n", d.Name)
+			for _, c := range d.Changes {
+				fmt.Fprintf(w, "      %s: %s -> %s\n", c.Field, valueOrNone(c.Before), valueOrNone(c.After))
+			}
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+func valueOrNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
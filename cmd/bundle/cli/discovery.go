@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+
+	"github.com/croomes/kubectl-plugin/pkg/discovery"
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/troubleshoot/pkg/k8sutil"
+	"github.com/spf13/viper"
+)
+
+// discoverInstallation finds where StorageOS is installed, or returns nil if
+// --discover is not set.
+func discoverInstallation(ctx context.Context, v *viper.Viper) (*discovery.Installation, error) {
+	if !v.GetBool("discover") {
+		return nil, nil
+	}
+
+	config, err := k8sutil.GetRESTConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to convert kube flags to rest config")
+	}
+
+	installation, err := discovery.Discover(ctx, config)
+	if err != nil {
+		return nil, errors.Wrap(err, "discover storageos")
+	}
+	return installation, nil
+}
+
+func writeDiscoveryFile(path string, installation *discovery.Installation) error {
+	b, err := json.MarshalIndent(installation, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFile(filepath.Join(path, discovery.Filename), b)
+}
@@ -0,0 +1,253 @@
+package cli
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	analyzer "github.com/replicatedhq/troubleshoot/pkg/analyze"
+	"github.com/spf13/viper"
+
+	"github.com/croomes/kubectl-plugin/pkg/bundlearchive"
+	"github.com/croomes/kubectl-plugin/pkg/defaultspecs"
+	"github.com/croomes/kubectl-plugin/pkg/discovery"
+	"github.com/croomes/kubectl-plugin/pkg/hostcollector"
+	"github.com/croomes/kubectl-plugin/pkg/specloader"
+	"github.com/croomes/kubectl-plugin/pkg/storageos"
+)
+
+// extractBundle extracts a local or http(s) support bundle, decrypting it
+// first if needed, to a temp dir. The returned function removes the temp dir.
+func extractBundle(v *viper.Viper, bundlePath string) (string, func(), error) {
+	if strings.HasPrefix(bundlePath, "http://") || strings.HasPrefix(bundlePath, "https://") {
+		downloaded, cleanupDownloaded, err := downloadBundle(bundlePath)
+		if err != nil {
+			return "", nil, err
+		}
+		defer cleanupDownloaded()
+		bundlePath = downloaded
+	}
+
+	decrypted, cleanupDecrypted, err := decryptedBundle(v, bundlePath)
+	if err != nil {
+		return "", nil, err
+	}
+	defer cleanupDecrypted()
+
+	dir, err := ioutil.TempDir("", "troubleshoot")
+	if err != nil {
+		return "", nil, errors.Wrap(err, "create temp dir")
+	}
+	cleanup := func() {
+		os.RemoveAll(dir)
+	}
+
+	// bundlearchive drops the top-level folder troubleshoot archives bundles
+	// in, so that the analyzers find the files at the root of dir
+	err = bundlearchive.Walk(decrypted, func(f bundlearchive.File, r io.Reader) error {
+		return extractFile(dir, f, r)
+	})
+	if err != nil {
+		cleanup()
+		return "", nil, errors.Wrapf(err, "extract %s", bundlePath)
+	}
+	return dir, cleanup, nil
+}
+
+// extractFile writes a file of a bundle under dir, with the time it was
+// modified in the bundle.
+func extractFile(dir string, file bundlearchive.File, r io.Reader) error {
+	name := file.Name
+	filename := filepath.Join(dir, filepath.FromSlash(name))
+	if !strings.HasPrefix(filename, dir+string(filepath.Separator)) {
+		return errors.Errorf("file %s is outside the support bundle", name)
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return errors.Wrapf(err, "create directory for %s", name)
+	}
+
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return errors.Wrapf(err, "create %s", name)
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, r); err != nil {
+		return errors.Wrapf(err, "write %s", name)
+	}
+	if err := f.Close(); err != nil {
+		return errors.Wrapf(err, "write %s", name)
+	}
+	if !file.ModTime.IsZero() {
+		return os.Chtimes(filename, file.ModTime, file.ModTime)
+	}
+	return nil
+}
+
+// downloadBundle downloads a support bundle to a temp dir. The returned
+// function removes the temp dir.
+func downloadBundle(url string) (string, func(), error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "download %s", url)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, errors.Errorf("download %s: unexpected status %s", url, resp.Status)
+	}
+
+	dir, err := ioutil.TempDir("", "troubleshoot")
+	if err != nil {
+		return "", nil, errors.Wrap(err, "create temp dir")
+	}
+	cleanup := func() {
+		os.RemoveAll(dir)
+	}
+
+	filename := filepath.Join(dir, path.Base(resp.Request.URL.Path))
+	f, err := os.Create(filename)
+	if err != nil {
+		cleanup()
+		return "", nil, errors.Wrap(err, "create bundle file")
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		cleanup()
+		return "", nil, errors.Wrapf(err, "download %s", url)
+	}
+	return filename, cleanup, nil
+}
+
+// analyzeExtractedBundle analyzes an extracted support bundle with the
+// analyzers of spec, rendered for the installation the bundle was collected
+// from, and the StorageOS analyzers. Logs are searched for known issues too,
+// unless knownIssues is false.
+func analyzeExtractedBundle(v *viper.Viper, dir string, spec string, knownIssues bool) ([]*analyzer.AnalyzeResult, error) {
+	installation, err := readDiscoveryFile(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	userValues, err := specloader.LoadValues(v.GetStringSlice("values"), v.GetStringSlice("set"))
+	if err != nil {
+		return nil, err
+	}
+	values := specloader.Values{}
+	if installation != nil {
+		values = installation.Values()
+	}
+	specloader.MergeValues(values, userValues)
+
+	loader := specloader.New(specloader.SupportBundleKey, v.GetBool("insecure-skip-tls-verify"))
+	loader.Default = defaultspecs.SupportBundle
+	if err := setRegistryCredentials(v, loader); err != nil {
+		return nil, err
+	}
+	loader.Values = values
+	loader.RenderAll = len(v.GetStringSlice("values")) > 0 || len(v.GetStringSlice("set")) > 0
+
+	b, err := loader.Load(spec)
+	if err != nil {
+		return nil, err
+	}
+	supportBundleSpec, err := parseSupportBundleFromDoc(b)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse support bundle spec")
+	}
+
+	results, err := analyzer.AnalyzeLocal(dir, supportBundleSpec.Spec.Analyzers)
+	if err != nil {
+		return nil, err
+	}
+
+	getFile := func(name string) ([]byte, error) {
+		return ioutil.ReadFile(filepath.Join(dir, name))
+	}
+
+	resourceResults, err := storageos.AnalyzeResources(getFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "analyze storageos resources")
+	}
+	results = append(results, resourceResults...)
+
+	csiResults, err := storageos.AnalyzeCSI(getFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "analyze csi resources")
+	}
+	results = append(results, csiResults...)
+
+	hostResults, err := hostcollector.AnalyzeFile(getFile, installation.Running())
+	if err != nil {
+		return nil, errors.Wrap(err, "analyze host reports")
+	}
+	results = append(results, hostResults...)
+
+	if !knownIssues {
+		return results, nil
+	}
+	walk := func(fn bundlearchive.WalkFunc) error {
+		return walkDir(dir, fn)
+	}
+	collected, err := collectionTime(walk)
+	if err != nil {
+		return nil, err
+	}
+	knownIssueResults, err := analyzeKnownIssues(v, collected, walk)
+	if err != nil {
+		return nil, err
+	}
+	results = append(results, knownIssueResults...)
+
+	return results, nil
+}
+
+// readDiscoveryFile returns the installation discovered when the bundle was
+// collected, or nil if it was collected without discovery.
+func readDiscoveryFile(dir string) (*discovery.Installation, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, discovery.Filename))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "read discovery file")
+	}
+
+	installation := &discovery.Installation{}
+	if err := json.Unmarshal(b, installation); err != nil {
+		return nil, errors.Wrap(err, "parse discovery file")
+	}
+	return installation, nil
+}
+
+// walkDir calls fn for each regular file in an extracted bundle, like
+// bundlearchive.Walk does for an archived one.
+func walkDir(dir string, fn bundlearchive.WalkFunc) error {
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return fn(bundlearchive.File{Name: filepath.ToSlash(rel), Size: info.Size(), ModTime: info.ModTime()}, f)
+	})
+	if err == bundlearchive.ErrStop {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// writeBundle writes a tar.gz of files, in order, to filename.
+func writeBundle(t *testing.T, filename string, files [][2]string) {
+	t.Helper()
+
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for _, file := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: file[0], Mode: 0644, Size: int64(len(file[1])), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(file[1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExtractBundle(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "extract")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	tests := []struct {
+		name    string
+		files   [][2]string
+		wantErr bool
+	}{
+		{
+			name: "top-level folder",
+			files: [][2]string{
+				{"support-bundle-2020-06-01T15_00_00/version.yaml", "apiVersion: troubleshoot.sh/v1beta2\n"},
+				{"support-bundle-2020-06-01T15_00_00/cluster-resources/nodes.json", "[]"},
+			},
+		},
+		{
+			name: "root",
+			files: [][2]string{
+				{"version.yaml", "apiVersion: troubleshoot.sh/v1beta2\n"},
+				{"./cluster-resources/nodes.json", "[]"},
+			},
+		},
+		{
+			name: "outside the bundle",
+			files: [][2]string{
+				{"version.yaml", "apiVersion: troubleshoot.sh/v1beta2\n"},
+				{"../nodes.json", "[]"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bundlePath := filepath.Join(tmp, "bundle.tar.gz")
+			writeBundle(t, bundlePath, tt.files)
+
+			dir, cleanup, err := extractBundle(viper.New(), bundlePath)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("extractBundle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			defer cleanup()
+
+			for _, name := range []string{"version.yaml", "cluster-resources/nodes.json"} {
+				if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+					t.Errorf("%s not extracted: %v", name, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,310 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/croomes/kubectl-plugin/pkg/bundlearchive"
+	"github.com/croomes/kubectl-plugin/pkg/timeline"
+)
+
+func Inspect() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "inspect",
+		Short: "browse a support bundle without extracting it",
+		Long: `Browse the files of a support bundle: list them, print them, search them and
+list the cluster resources collected, straight from the tar.gz`,
+	}
+
+	cmd.AddCommand(inspectLs())
+	cmd.AddCommand(inspectCat())
+	cmd.AddCommand(inspectGrep())
+	cmd.AddCommand(inspectResources())
+
+	return cmd
+}
+
+func inspectLs() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ls [bundle] [path]",
+		Args:  cobra.RangeArgs(1, 2),
+		Short: "list the files in a support bundle with their sizes",
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlag("private-key", cmd.Flags().Lookup("private-key"))
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+
+			dir := ""
+			if len(args) > 1 {
+				dir = lsPrefix(args[1])
+			}
+
+			files := []bundlearchive.File{}
+			err := walkBundle(v, args[0], func(f bundlearchive.File, r io.Reader) error {
+				if strings.HasPrefix(f.Name, dir) || f.Name+"/" == dir {
+					files = append(files, f)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if len(files) == 0 && dir != "" {
+				return fmt.Errorf("%s is not in the support bundle", strings.TrimSuffix(dir, "/"))
+			}
+
+			sort.Slice(files, func(i, j int) bool {
+				return files[i].Name < files[j].Name
+			})
+			var total int64
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+			for _, f := range files {
+				fmt.Fprintf(w, "%s\t  %s\n", formatSize(f.Size), f.Name)
+				total += f.Size
+			}
+			fmt.Fprintf(w, "%s\t  total in %d files\n", formatSize(total), len(files))
+			return w.Flush()
+		},
+	}
+
+	addInspectFlags(cmd)
+
+	return cmd
+}
+
+// lsPrefix returns the prefix of the files under dir in a bundle, or "" for
+// the root of the bundle, which is "", "." or "/".
+func lsPrefix(dir string) string {
+	dir = strings.Trim(path.Clean(dir), "/")
+	if dir == "" || dir == "." {
+		return ""
+	}
+	return dir + "/"
+}
+
+func inspectCat() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cat [bundle] [path]",
+		Args:  cobra.ExactArgs(2),
+		Short: "print a file in a support bundle",
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlag("private-key", cmd.Flags().Lookup("private-key"))
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+
+			name := path.Clean(strings.TrimPrefix(args[1], "/"))
+			found := false
+			err := walkBundle(v, args[0], func(f bundlearchive.File, r io.Reader) error {
+				if f.Name != name {
+					return nil
+				}
+				found = true
+				if _, err := io.Copy(os.Stdout, r); err != nil {
+					return errors.Wrapf(err, "read %s", name)
+				}
+				return bundlearchive.ErrStop
+			})
+			if err != nil {
+				return err
+			}
+			if !found {
+				return fmt.Errorf("%s is not in the support bundle", name)
+			}
+			return nil
+		},
+	}
+
+	addInspectFlags(cmd)
+
+	return cmd
+}
+
+func inspectGrep() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "grep [bundle] [regex]",
+		Args:  cobra.ExactArgs(2),
+		Short: "search the logs in a support bundle",
+		Long: `Print the lines matching a regular expression in the files of a support bundle
+that match --include, the logs by default. timeline.log, which repeats every
+log line, is only searched with --include timeline.log`,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlag("private-key", cmd.Flags().Lookup("private-key"))
+			viper.BindPFlag("include", cmd.Flags().Lookup("include"))
+			viper.BindPFlag("ignore-case", cmd.Flags().Lookup("ignore-case"))
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+
+			expr := args[1]
+			if v.GetBool("ignore-case") {
+				expr = "(?i)" + expr
+			}
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return errors.Wrap(err, "invalid regex")
+			}
+
+			include := v.GetString("include")
+			if _, err := path.Match(include, ""); err != nil {
+				return errors.Wrap(err, "invalid --include pattern")
+			}
+
+			out := bufio.NewWriter(os.Stdout)
+			defer out.Flush()
+
+			return walkBundle(v, args[0], func(f bundlearchive.File, r io.Reader) error {
+				if matched, _ := path.Match(include, path.Base(f.Name)); !matched {
+					return nil
+				}
+				// the timeline repeats every line of the other logs
+				if f.Name == timeline.Filename && include != timeline.Filename {
+					return nil
+				}
+
+				scanner := bufio.NewScanner(r)
+				scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+				for line := 1; scanner.Scan(); line++ {
+					if re.Match(scanner.Bytes()) {
+						fmt.Fprintf(out, "%s:%d:%s\n", f.Name, line, scanner.Text())
+					}
+				}
+				return errors.Wrapf(scanner.Err(), "read %s", f.Name)
+			})
+		},
+	}
+
+	addInspectFlags(cmd)
+	cmd.Flags().String("include", "*.log", "search the files whose name matches this pattern")
+	cmd.Flags().BoolP("ignore-case", "i", false, "match the regex case-insensitively")
+
+	return cmd
+}
+
+func inspectResources() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "resources [bundle] [kind]",
+		Args:  cobra.RangeArgs(1, 2),
+		Short: "list the cluster resources in a support bundle",
+		Long: `List the objects of a kind collected in cluster-resources, e.g. pods or
+deployments. Without a kind, the kinds collected are listed.`,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlag("private-key", cmd.Flags().Lookup("private-key"))
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+
+			kind := ""
+			if len(args) > 1 {
+				kind = strings.ToLower(args[1])
+			}
+
+			counts := map[string]int{}
+			objects := []bundlearchive.ObjectSummary{}
+			err := walkBundle(v, args[0], func(f bundlearchive.File, r io.Reader) error {
+				fileKind, ok := bundlearchive.ResourceKind(f.Name)
+				if !ok || (kind != "" && fileKind != kind) {
+					return nil
+				}
+
+				b, err := ioutil.ReadAll(r)
+				if err != nil {
+					return errors.Wrapf(err, "read %s", f.Name)
+				}
+				items, err := bundlearchive.DecodeObjects(b)
+				if err != nil {
+					// some files in cluster-resources are not lists of objects
+					return nil
+				}
+				counts[fileKind] += len(items)
+				for _, item := range items {
+					objects = append(objects, bundlearchive.Summarize(item))
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			defer w.Flush()
+
+			if kind == "" {
+				kinds := []string{}
+				for k := range counts {
+					kinds = append(kinds, k)
+				}
+				sort.Strings(kinds)
+				fmt.Fprintln(w, "KIND\tCOUNT")
+				for _, k := range kinds {
+					fmt.Fprintf(w, "%s\t%d\n", k, counts[k])
+				}
+				return nil
+			}
+
+			if _, ok := counts[kind]; !ok {
+				return fmt.Errorf("no %s were collected, run with no kind to list the kinds collected", kind)
+			}
+
+			bundlearchive.SortSummaries(objects)
+			fmt.Fprintln(w, "NAMESPACE\tNAME\tSTATUS\tCREATED")
+			for _, o := range objects {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", o.Namespace, o.Name, o.Status, o.Created)
+			}
+			return nil
+		},
+	}
+
+	addInspectFlags(cmd)
+
+	return cmd
+}
+
+func addInspectFlags(cmd *cobra.Command) {
+	cmd.Flags().String("private-key", "", "OpenPGP private key file to decrypt an encrypted support bundle with")
+}
+
+// walkBundle walks the files of a local support bundle, decrypting it first
+// if needed.
+func walkBundle(v *viper.Viper, bundlePath string, fn bundlearchive.WalkFunc) error {
+	if _, err := os.Stat(bundlePath); err != nil {
+		return errors.Wrap(err, "only local support bundles can be inspected")
+	}
+
+	decrypted, cleanup, err := decryptedBundle(v, bundlePath)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	return bundlearchive.Walk(decrypted, fn)
+}
+
+// formatSize returns a size in bytes in the largest binary unit it has at
+// least one of.
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ci", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,24 @@
+package cli
+
+import "testing"
+
+func TestLsPrefix(t *testing.T) {
+	tests := []struct {
+		dir  string
+		want string
+	}{
+		{dir: "", want: ""},
+		{dir: ".", want: ""},
+		{dir: "./", want: ""},
+		{dir: "/", want: ""},
+		{dir: "cluster-resources", want: "cluster-resources/"},
+		{dir: "/cluster-resources/", want: "cluster-resources/"},
+		{dir: "./storageos/logs", want: "storageos/logs/"},
+		{dir: "storageos/../cluster-resources", want: "cluster-resources/"},
+	}
+	for _, tt := range tests {
+		if got := lsPrefix(tt.dir); got != tt.want {
+			t.Errorf("lsPrefix(%q) = %q, want %q", tt.dir, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/troubleshoot/pkg/collect"
+
+	"github.com/croomes/kubectl-plugin/pkg/bundlearchive"
+)
+
+const ManifestFilename = "manifest.json"
+
+type CollectorStatus string
+
+const (
+	CollectorStatusSucceeded CollectorStatus = "succeeded"
+	CollectorStatusFailed    CollectorStatus = "failed"
+	CollectorStatusSkipped   CollectorStatus = "skipped"
+)
+
+// Manifest records what each collector did during bundle collection, so that
+// whoever receives the bundle can tell what is missing and why.
+type Manifest struct {
+	// CollectedAt is when collection started. Log timestamps without a year
+	// are read as the last such time before it.
+	CollectedAt time.Time            `json:"collectedAt"`
+	Collectors  []*CollectorManifest `json:"collectors"`
+}
+
+type CollectorManifest struct {
+	Name            string          `json:"name"`
+	Status          CollectorStatus `json:"status"`
+	DurationSeconds float64         `json:"durationSeconds"`
+	Error           string          `json:"error,omitempty"`
+	SkippedReason   string          `json:"skippedReason,omitempty"`
+	RBACErrors      []string        `json:"rbacErrors,omitempty"`
+	Files           []*FileManifest `json:"files,omitempty"`
+}
+
+type FileManifest struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+func newCollectorManifest(collector *collect.Collector) *CollectorManifest {
+	m := &CollectorManifest{
+		Name: collector.GetDisplayName(),
+	}
+	for _, e := range collector.RBACErrors {
+		m.RBACErrors = append(m.RBACErrors, e.Error())
+	}
+	return m
+}
+
+// addFiles records the size and checksum of each file, given as a path
+// relative to the bundle directory.
+func (m *CollectorManifest) addFiles(bundlePath string, filenames []string) error {
+	sort.Strings(filenames)
+	for _, filename := range filenames {
+		fileManifest, err := getFileManifest(bundlePath, filename)
+		if err != nil {
+			return errors.Wrapf(err, "checksum %s", filename)
+		}
+		m.Files = append(m.Files, fileManifest)
+	}
+	return nil
+}
+
+func getFileManifest(bundlePath string, filename string) (*FileManifest, error) {
+	f, err := os.Open(filepath.Join(bundlePath, filename))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileManifest{
+		Path:   filepath.ToSlash(filename),
+		Size:   size,
+		SHA256: hex.EncodeToString(h.Sum(nil)),
+	}, nil
+}
+
+// collectionTime returns when a bundle was collected: the time in its
+// manifest, or else when its version file was written, for bundles without
+// one. walk walks the files of the bundle.
+func collectionTime(walk func(fn bundlearchive.WalkFunc) error) (time.Time, error) {
+	collected := time.Time{}
+	versionWritten := time.Time{}
+	err := walk(func(f bundlearchive.File, r io.Reader) error {
+		switch f.Name {
+		case ManifestFilename:
+			manifest := &Manifest{}
+			// a manifest that cannot be read has no time
+			if err := json.NewDecoder(r).Decode(manifest); err == nil && !manifest.CollectedAt.IsZero() {
+				collected = manifest.CollectedAt
+				return bundlearchive.ErrStop
+			}
+		case VersionFilename:
+			versionWritten = f.ModTime
+		}
+		return nil
+	})
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "read collection time")
+	}
+
+	switch {
+	case !collected.IsZero():
+		return collected, nil
+	case !versionWritten.IsZero():
+		return versionWritten, nil
+	}
+	return time.Now(), nil
+}
+
+func writeManifestFile(path string, manifest *Manifest) error {
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFile(filepath.Join(path, ManifestFilename), b)
+}
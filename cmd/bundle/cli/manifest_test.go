@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/croomes/kubectl-plugin/pkg/bundlearchive"
+)
+
+func TestCollectionTime(t *testing.T) {
+	collected := time.Date(2020, 6, 1, 15, 0, 0, 0, time.UTC)
+	versionWritten := time.Date(2020, 6, 1, 15, 5, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  time.Time
+	}{
+		{
+			name: "manifest",
+			files: map[string]string{
+				VersionFilename:  "kind: SupportBundle\n",
+				ManifestFilename: `{"collectedAt": "2020-06-01T15:00:00Z", "collectors": []}`,
+			},
+			want: collected,
+		},
+		{
+			name: "manifest without a time",
+			files: map[string]string{
+				VersionFilename:  "kind: SupportBundle\n",
+				ManifestFilename: `{"collectors": []}`,
+			},
+			want: versionWritten,
+		},
+		{
+			name: "no manifest",
+			files: map[string]string{
+				VersionFilename: "kind: SupportBundle\n",
+			},
+			want: versionWritten,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "manifest")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			for name, content := range tt.files {
+				filename := filepath.Join(dir, name)
+				if err := ioutil.WriteFile(filename, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Chtimes(filename, versionWritten, versionWritten); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := collectionTime(func(fn bundlearchive.WalkFunc) error {
+				return walkDir(dir, fn)
+			})
+			if err != nil {
+				t.Fatalf("collectionTime() error = %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("collectionTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/replicatedhq/troubleshoot/pkg/redact"
+)
+
+// RedactionReport summarises the redactions made during collection, so that
+// they can be reviewed before a bundle is shared.
+type RedactionReport struct {
+	Files []*RedactionReportFile `json:"files"`
+}
+
+type RedactionReportFile struct {
+	File      string                     `json:"file"`
+	Redactors []*RedactionReportRedactor `json:"redactors"`
+}
+
+type RedactionReportRedactor struct {
+	Name      string `json:"name"`
+	IsDefault bool   `json:"isDefault"`
+	Count     int    `json:"count"`
+}
+
+func getRedactionReport(redactions redact.RedactionList) *RedactionReport {
+	report := &RedactionReport{
+		Files: []*RedactionReportFile{},
+	}
+
+	files := make([]string, 0, len(redactions.ByFile))
+	for file := range redactions.ByFile {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		byRedactor := map[string]*RedactionReportRedactor{}
+		for _, r := range redactions.ByFile[file] {
+			if _, ok := byRedactor[r.RedactorName]; !ok {
+				byRedactor[r.RedactorName] = &RedactionReportRedactor{
+					Name:      r.RedactorName,
+					IsDefault: r.IsDefaultRedactor,
+				}
+			}
+			byRedactor[r.RedactorName].Count++
+		}
+
+		reportFile := &RedactionReportFile{
+			File:      file,
+			Redactors: []*RedactionReportRedactor{},
+		}
+		for _, r := range byRedactor {
+			reportFile.Redactors = append(reportFile.Redactors, r)
+		}
+		sort.Slice(reportFile.Redactors, func(i, j int) bool {
+			return reportFile.Redactors[i].Name < reportFile.Redactors[j].Name
+		})
+
+		report.Files = append(report.Files, reportFile)
+	}
+
+	return report
+}
+
+func writeRedactionReport(filename string, report *RedactionReport) error {
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFile(filename, b)
+}
+
+func showRedactionReport(report *RedactionReport) {
+	if len(report.Files) == 0 {
+		fmt.Println("No redactions would be made")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FILE\tREDACTOR\tCOUNT")
+	for _, file := range report.Files {
+		for _, r := range file.Redactors {
+			fmt.Fprintf(w, "%s\t%s\t%d\n", file.File, r.Name, r.Count)
+		}
+	}
+	w.Flush()
+}
@@ -10,13 +10,31 @@ import (
 	"github.com/replicatedhq/troubleshoot/pkg/logger"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-)
 
-const (
-	defaultSpec = "https://raw.githubusercontent.com/croomes/kubectl-plugin/master/examples/bundle.yaml"
+	"github.com/croomes/kubectl-plugin/pkg/defaultspecs"
+	"github.com/croomes/kubectl-plugin/pkg/exitcode"
+	"github.com/croomes/kubectl-plugin/pkg/hostcollector"
 )
 
+// RootCmd returns the standalone bundle command, with its own config and
+// kube flags.
 func RootCmd() *cobra.Command {
+	cmd := BundleCmd()
+
+	cobra.OnInitialize(initConfig)
+
+	cmd.AddCommand(VersionCmd())
+
+	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+
+	k8sutil.AddFlags(cmd.Flags())
+
+	return cmd
+}
+
+// BundleCmd returns the bundle command without the kube flags, so that it can
+// be mounted under a root command that provides them.
+func BundleCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "bundle [url]",
 		Args:  cobra.MinimumNArgs(0),
@@ -32,7 +50,10 @@ from a server that can be used to assist when troubleshooting a Kubernetes clust
 
 			logger.SetQuiet(v.GetBool("quiet"))
 
-			spec := defaultSpec
+			spec, err := defaultspecs.Source(v.GetString("spec-source"), defaultspecs.SupportBundleURL)
+			if err != nil {
+				return err
+			}
 			if len(args) > 0 {
 				spec = args[0]
 			}
@@ -40,31 +61,56 @@ from a server that can be used to assist when troubleshooting a Kubernetes clust
 		},
 	}
 
-	cobra.OnInitialize(initConfig)
-
 	cmd.AddCommand(Analyze())
-	cmd.AddCommand(VersionCmd())
+	cmd.AddCommand(Decrypt())
+	cmd.AddCommand(Diff())
+	cmd.AddCommand(Inspect())
+	cmd.AddCommand(Serve())
+	cmd.AddCommand(Timeline())
+	cmd.AddCommand(Verify())
 
 	cmd.Flags().StringSlice("redactors", []string{}, "names of the additional redactors to use")
 	cmd.Flags().Bool("redact", true, "enable/disable default redactions")
+	cmd.Flags().Bool("storageos-redactors", true, "enable/disable the built-in StorageOS redactors")
+	cmd.Flags().Bool("storageos-resources", true, "collect and analyze the StorageOSCluster, StorageOS node and volume resources")
+	cmd.Flags().Bool("csi-resources", true, "collect and analyze the CSI drivers and nodes, volume attachments, storage classes, persistent volumes and claims")
+	cmd.Flags().Bool("host-collector", false, "run a temporary privileged DaemonSet to check the kernel modules, ports, disk space and hugepages of every node")
+	cmd.Flags().Duration("host-collector-timeout", hostcollector.DefaultTimeout, "how long to wait for every node to report its host checks")
+	cmd.Flags().Bool("known-issues", true, "search the collected logs for known issues")
+	cmd.Flags().String("signatures", "", "file of known issue signatures to search the logs for. defaults to the built-in signatures")
+	cmd.Flags().String("collector-image", "", "the full name of the collector image to use")
+	cmd.Flags().String("collector-pullpolicy", "", "the pull policy of the collector image")
+	cmd.Flags().Bool("redact-dry-run", false, "collect and redact, but only write a report of the redactions that would be made instead of a support bundle")
 	cmd.Flags().Bool("collect-without-permissions", false, "always generate a support bundle, even if it some require additional permissions")
+	cmd.Flags().String("encrypt-to", "", "OpenPGP public key file to encrypt the support bundle to")
+	cmd.Flags().String("sign-with", "", "OpenPGP private key file to write a detached signature of the support bundle with")
+	cmd.Flags().String("upload", "", "upload the support bundle and redaction report to S3-compatible object storage, e.g. s3://bucket/prefix")
+	cmd.Flags().String("s3-endpoint", "", "endpoint of the S3-compatible object storage to upload to, e.g. https://minio.example.com:9000")
+	cmd.Flags().String("s3-region", "", "region of the bucket to upload to. by default the region is taken from the environment or profile")
+	cmd.Flags().String("s3-profile", "", "profile in the shared AWS credentials file to upload with. by default credentials are taken from the environment")
+	cmd.Flags().String("upload-chunk-size", "", "upload the support bundle in resumable chunks, or S3 multipart upload parts, of this size, e.g. 16Mi. by default it is uploaded in a single request")
+	cmd.Flags().Int("upload-retries", 5, "number of times in a row a failed upload request is retried")
+	cmd.Flags().StringSlice("values", []string{}, "values files to render the spec template with")
+	cmd.Flags().StringSlice("set", []string{}, "values to render the spec template with, e.g. namespace=storageos. these override --values")
+	cmd.Flags().Bool("discover", true, "find where StorageOS is installed and point the spec at it. the spec values are overridden by --values and --set")
+	cmd.Flags().String("registry-username", "", "username to authenticate to OCI registries with")
+	cmd.Flags().Bool("registry-password-stdin", false, "read the password to authenticate to OCI registries with from standard input, instead of the REGISTRY_PASSWORD environment variable")
+	cmd.Flags().String("spec-source", defaultspecs.SourceEmbedded, "where to load the default spec from when none is given, one of embedded, remote. remote fetches the latest spec from GitHub")
+	cmd.Flags().String("fail-on", exitcode.FailOnFail, "lowest analysis result that makes the command exit non-zero, one of warn, fail")
+	cmd.Flags().Int("parallelism", 1, "number of collectors to run concurrently")
+	cmd.Flags().Duration("timeout", 0, "maximum time to spend collecting, after which a partial bundle is written. 0 means no limit")
+	cmd.Flags().Duration("collector-timeout", 0, "maximum time for each collector to run. 0 means no limit")
 
 	// hidden in favor of the `insecure-skip-tls-verify` flag
 	cmd.Flags().Bool("allow-insecure-connections", false, "when set, do not verify TLS certs when retrieving spec and reporting results")
 	cmd.Flags().MarkHidden("allow-insecure-connections")
 
-	viper.BindPFlags(cmd.Flags())
-
-	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
-
-	k8sutil.AddFlags(cmd.Flags())
-
 	return cmd
 }
 
 func InitAndExecute() {
 	if err := RootCmd().Execute(); err != nil {
-		os.Exit(1)
+		os.Exit(exitcode.Code(err))
 	}
 }
 
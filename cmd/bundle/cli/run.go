@@ -4,7 +4,6 @@ import (
 	"archive/tar"
 	"bytes"
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -12,12 +11,24 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	cursor "github.com/ahmetalpbalkan/go-cursor"
+	"github.com/croomes/kubectl-plugin/pkg/bundlecrypt"
+	"github.com/croomes/kubectl-plugin/pkg/defaultspecs"
+	"github.com/croomes/kubectl-plugin/pkg/discovery"
+	"github.com/croomes/kubectl-plugin/pkg/exitcode"
+	"github.com/croomes/kubectl-plugin/pkg/hostcollector"
+	"github.com/croomes/kubectl-plugin/pkg/redactors"
+	"github.com/croomes/kubectl-plugin/pkg/specloader"
+	"github.com/croomes/kubectl-plugin/pkg/storageos"
+	"github.com/croomes/kubectl-plugin/pkg/upload"
 	"github.com/fatih/color"
 	"github.com/manifoldco/promptui"
 	"github.com/mattn/go-isatty"
@@ -33,9 +44,9 @@ import (
 	"github.com/replicatedhq/troubleshoot/pkg/docrewrite"
 	"github.com/replicatedhq/troubleshoot/pkg/k8sutil"
 	"github.com/replicatedhq/troubleshoot/pkg/redact"
-	"github.com/replicatedhq/troubleshoot/pkg/specs"
 	"github.com/spf13/viper"
 	spin "github.com/tj/go-spin"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -47,33 +58,63 @@ func runTroubleshoot(v *viper.Viper, arg string) error {
 	fmt.Print(cursor.Hide())
 	defer fmt.Print(cursor.Show())
 
-	if v.GetBool("allow-insecure-connections") || v.GetBool("insecure-skip-tls-verify") {
-		httpClient = &http.Client{Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		}}
-	} else {
-		httpClient = http.DefaultClient
+	if err := exitcode.ValidateFailOn(v.GetString("fail-on")); err != nil {
+		return err
 	}
 
-	collectorContent, err := loadSpec(v, arg)
-	if err != nil {
-		return errors.Wrap(err, "failed to load collector spec")
+	if v.GetBool("redact-dry-run") && !v.GetBool("redact") {
+		return errors.New("--redact-dry-run cannot be used with --redact=false")
 	}
 
-	multidocs := strings.Split(string(collectorContent), "\n---\n")
+	// Ctrl-C cancels discovery and in-flight collectors, and a partial bundle
+	// is still written with what was collected so far. A second Ctrl-C quits
+	// straight away.
+	interruptCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	signalChan := make(chan os.Signal, 1)
+	signal.Notify(signalChan, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signalChan)
+	go func() {
+		select {
+		case <-signalChan:
+			signal.Stop(signalChan)
+			cancel()
+		case <-interruptCtx.Done():
+		}
+	}()
 
-	// we suppory both raw collector kinds and supportbundle kinds here
-	supportBundleSpec, err := parseSupportBundleFromDoc([]byte(multidocs[0]))
+	// --timeout bounds discovery as well as collection
+	collectCtx := interruptCtx
+	if timeout := v.GetDuration("timeout"); timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		collectCtx, cancelTimeout = context.WithTimeout(interruptCtx, timeout)
+		defer cancelTimeout()
+	}
+
+	installation, err := discoverInstallation(collectCtx, v)
 	if err != nil {
-		return errors.Wrap(err, "failed to parse collector")
+		return err
 	}
 
+	loader, err := newSpecLoader(v, installation)
+	if err != nil {
+		return err
+	}
+	loader.Header = bundleUploadHostHeader(arg)
+	supportBundleSpec, multidocs, err := loadCollectorSpec(loader, installation, arg)
+	if err != nil {
+		return err
+	}
+	loader.Header = http.Header{}
+	// redactor specs are only rendered if they are marked as templates
+	loader.RenderAll = false
+
 	troubleshootclientsetscheme.AddToScheme(scheme.Scheme)
 	decode := scheme.Codecs.UniversalDeserializer().Decode
 
 	additionalRedactors := &troubleshootv1beta2.Redactor{}
 	for idx, redactor := range v.GetStringSlice("redactors") {
-		redactorContent, err := loadSpec(v, redactor)
+		redactorContent, err := loader.Load(redactor)
 		if err != nil {
 			return errors.Wrapf(err, "failed to load redactor spec #%d", idx)
 		}
@@ -94,6 +135,21 @@ func runTroubleshoot(v *viper.Viper, arg string) error {
 		}
 	}
 
+	// keep using an insecure connection if the user accepted one for the spec
+	httpClient = loader.HTTPClient
+
+	if v.GetBool("storageos-redactors") {
+		obj, _, err := decode([]byte(redactors.StorageOS), nil, nil)
+		if err != nil {
+			return errors.Wrap(err, "failed to parse storageos redactors")
+		}
+		storageosRedactors, ok := obj.(*troubleshootv1beta2.Redactor)
+		if !ok {
+			return errors.New("storageos redactors are not a troubleshootv1beta2 redactor type")
+		}
+		additionalRedactors.Spec.Redactors = append(additionalRedactors.Spec.Redactors, storageosRedactors.Spec.Redactors...)
+	}
+
 	for i, additionalDoc := range multidocs {
 		if i == 0 {
 			continue
@@ -119,6 +175,7 @@ func runTroubleshoot(v *viper.Viper, arg string) error {
 	isFinishedChClosed := false
 	go func() {
 		currentDir := ""
+		var uploading *uploadProgress
 		for {
 			select {
 			case msg := <-progressChan:
@@ -128,12 +185,19 @@ func runTroubleshoot(v *viper.Viper, arg string) error {
 					c.Println(fmt.Sprintf("%s\r * %v", cursor.ClearEntireLine(), msg))
 				case string:
 					currentDir = filepath.Base(msg)
+				case uploadProgress:
+					uploading = &msg
+					if msg.total == 0 {
+						uploading = nil
+					}
 				}
 			case <-finishedCh:
 				fmt.Printf("\r%s\r", cursor.ClearEntireLine())
 				return
 			case <-time.After(time.Millisecond * 100):
-				if currentDir == "" {
+				if uploading != nil {
+					fmt.Printf("\r%s \033[36mUploading support bundle\033[m %s %s", cursor.ClearEntireLine(), s.Next(), progressBar(uploading.sent, uploading.total))
+				} else if currentDir == "" {
 					fmt.Printf("\r%s \033[36mCollecting support bundle\033[m %s", cursor.ClearEntireLine(), s.Next())
 				} else {
 					fmt.Printf("\r%s \033[36mCollecting support bundle\033[m %s %s", cursor.ClearEntireLine(), s.Next(), currentDir)
@@ -147,19 +211,80 @@ func runTroubleshoot(v *viper.Viper, arg string) error {
 		}
 	}()
 
-	archivePath, err := runCollectors(v, supportBundleSpec.Spec.Collectors, additionalRedactors, progressChan)
+	// a bundle that will be encrypted is written to a private directory, so
+	// that the plaintext never appears where the encrypted bundle is shared
+	// from
+	archiveDir := ""
+	if v.GetString("encrypt-to") != "" {
+		archiveDir, err = ioutil.TempDir("", "support-bundle")
+		if err != nil {
+			return exitcode.New(exitcode.CollectionError, errors.Wrap(err, "create directory for unencrypted support bundle"))
+		}
+	}
+
+	archivePath, err := runCollectors(collectCtx, v, supportBundleSpec.Spec.Collectors, additionalRedactors, installation, archiveDir, progressChan)
+	if archiveDir != "" && (err != nil || v.GetBool("redact-dry-run")) {
+		os.RemoveAll(archiveDir)
+	}
 	if err != nil {
-		return errors.Wrap(err, "run collectors")
+		return exitcode.New(exitcode.CollectionError, errors.Wrap(err, "run collectors"))
 	}
 
 	fmt.Printf("\r%s\r", cursor.ClearEntireLine())
 
+	if v.GetBool("redact-dry-run") {
+		close(finishedCh) // this removes the spinner
+		isFinishedChClosed = true
+
+		showRedactionReport(getRedactionReport(redact.GetRedactionList()))
+		if interruptCtx.Err() != nil {
+			fmt.Printf("Collection was interrupted, so this report is incomplete.\n")
+		}
+		fmt.Printf("A redaction report has been created in the current directory named %q\n", archivePath)
+		return nil
+	}
+
+	// only the encrypted archive is kept. the plaintext one is used for
+	// analysis, and removed when done, or kept and reported if it can't be
+	// encrypted so that what was collected isn't lost.
+	analysisPath := archivePath
+	if publicKey := v.GetString("encrypt-to"); publicKey != "" {
+		encryptedPath, err := findFileName(strings.TrimSuffix(filepath.Base(archivePath), ".tar.gz"), "tar.gz"+bundlecrypt.Extension)
+		if err == nil {
+			err = bundlecrypt.EncryptFile(archivePath, encryptedPath, publicKey)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "encrypt support bundle, the unencrypted bundle was kept at %q", archivePath)
+		}
+		defer os.RemoveAll(archiveDir)
+		archivePath = encryptedPath
+	}
+
+	if privateKey := v.GetString("sign-with"); privateKey != "" {
+		sigPath := archivePath + bundlecrypt.SignatureExtension
+		if err := bundlecrypt.SignFile(archivePath, sigPath, privateKey, keyPassphrase(v, "sign-with-passphrase")); err != nil {
+			return errors.Wrap(err, "sign support bundle")
+		}
+	}
+
+	if interruptCtx.Err() != nil {
+		fmt.Printf("Collection was interrupted. A partial support bundle has been created in the current directory named %q\n", archivePath)
+		return exitcode.New(exitcode.CollectionError, errors.New("support bundle collection interrupted"))
+	}
+
 	// upload if needed
 	fileUploaded := false
 	if len(supportBundleSpec.Spec.AfterCollection) > 0 {
+		uploader, err := newUploader(v, progressChan)
+		if err != nil {
+			return errors.Wrap(err, "create uploader")
+		}
+
 		for _, ac := range supportBundleSpec.Spec.AfterCollection {
 			if ac.UploadResultsTo != nil {
-				if err := uploadSupportBundle(ac.UploadResultsTo, archivePath); err != nil {
+				err := uploadSupportBundle(uploader, ac.UploadResultsTo, archivePath)
+				progressChan <- uploadProgress{}
+				if err != nil {
 					c := color.New(color.FgHiRed)
 					c.Printf("%s\r * Failed to upload support bundle: %v\n", cursor.ClearEntireLine(), err)
 				} else {
@@ -175,15 +300,28 @@ func runTroubleshoot(v *viper.Viper, arg string) error {
 
 	}
 
+	uploadedTo := ""
+	if uri := v.GetString("upload"); uri != "" {
+		location, err := uploadSupportBundleToS3(interruptCtx, v, uri, archivePath, progressChan)
+		progressChan <- uploadProgress{}
+		if err != nil {
+			c := color.New(color.FgHiRed)
+			c.Printf("%s\r * Failed to upload support bundle: %v\n", cursor.ClearEntireLine(), err)
+		} else {
+			uploadedTo = location
+		}
+	}
+
 	// perform analysis, if possible
-	if len(supportBundleSpec.Spec.Analyzers) > 0 {
+	var resultsErr error
+	if len(supportBundleSpec.Spec.Analyzers) > 0 || v.GetBool("storageos-resources") || v.GetBool("csi-resources") || v.GetBool("host-collector") || v.GetBool("known-issues") {
 		tmpDir, err := ioutil.TempDir("", "troubleshoot")
 		if err != nil {
 			c := color.New(color.FgHiRed)
 			c.Printf("%s\r * Failed to make directory for analysis: %v\n", cursor.ClearEntireLine(), err)
 		}
 
-		f, err := os.Open(archivePath)
+		f, err := os.Open(analysisPath)
 		if err != nil {
 			c := color.New(color.FgHiRed)
 			c.Printf("%s\r * Failed to open support bundle for analysis: %v\n", cursor.ClearEntireLine(), err)
@@ -200,6 +338,15 @@ func runTroubleshoot(v *viper.Viper, arg string) error {
 			c.Printf("%s\r * Failed to analyze support bundle: %v\n", cursor.ClearEntireLine(), err)
 		}
 
+		storageosResults, err := analyzeStorageOSCollectorOutput(v, tmpDir, installation)
+		if err != nil {
+			c := color.New(color.FgHiRed)
+			c.Printf("%s\r * Failed to analyze support bundle: %v\n", cursor.ClearEntireLine(), err)
+		}
+		analyzeResults = append(analyzeResults, storageosResults...)
+
+		resultsErr = exitcode.FromResults(analyzeResults, v.GetString("fail-on"))
+
 		interactive := isatty.IsTerminal(os.Stdout.Fd())
 
 		if interactive {
@@ -223,6 +370,10 @@ func runTroubleshoot(v *viper.Viper, arg string) error {
 		}
 	}
 
+	if uploadedTo != "" {
+		fmt.Printf("\r%s\rThe support bundle was uploaded to %s\n", cursor.ClearEntireLine(), uploadedTo)
+	}
+
 	if !fileUploaded {
 		msg := archivePath
 		if appName := supportBundleSpec.Labels["applicationName"]; appName != "" {
@@ -234,7 +385,7 @@ the %s Admin Console to begin analysis.`
 
 		fmt.Printf("%s\n", msg)
 
-		return nil
+		return resultsErr
 	}
 
 	fmt.Printf("\r%s\r", cursor.ClearEntireLine())
@@ -244,71 +395,89 @@ the %s Admin Console to begin analysis.`
 	} else {
 		fmt.Printf("A support bundle has been created in the current directory named %q\n", archivePath)
 	}
-	return nil
+	return resultsErr
 }
 
-func loadSpec(v *viper.Viper, arg string) ([]byte, error) {
-	var err error
-	if strings.HasPrefix(arg, "secret/") {
-		// format secret/namespace-name/secret-name
-		pathParts := strings.Split(arg, "/")
-		if len(pathParts) != 3 {
-			return nil, errors.Errorf("path %s must have 3 components", arg)
-		}
-
-		spec, err := specs.LoadFromSecret(pathParts[1], pathParts[2], "support-bundle-spec")
-		if err != nil {
-			return nil, errors.Wrap(err, "failed to get spec from secret")
-		}
+// newSpecLoader returns a spec loader that renders specs with the values of
+// the discovered installation, if any, overridden by the --values and --set
+// values, and offers to retry without TLS verification when a spec server's
+// certificate is not trusted.
+func newSpecLoader(v *viper.Viper, installation *discovery.Installation) (*specloader.Loader, error) {
+	userValues, err := specloader.LoadValues(v.GetStringSlice("values"), v.GetStringSlice("set"))
+	if err != nil {
+		return nil, err
+	}
 
-		return spec, nil
+	values := specloader.Values{}
+	if installation != nil {
+		values = installation.Values()
 	}
+	specloader.MergeValues(values, userValues)
 
-	if _, err = os.Stat(arg); err == nil {
-		b, err := ioutil.ReadFile(arg)
-		if err != nil {
-			return nil, errors.Wrap(err, "read spec file")
-		}
+	insecure := v.GetBool("allow-insecure-connections") || v.GetBool("insecure-skip-tls-verify")
+	loader := specloader.New(specloader.SupportBundleKey, insecure)
+	loader.Default = defaultspecs.SupportBundle
+	loader.InsecureFallback = func() bool {
+		return canTryInsecure(v)
+	}
+	if err := setRegistryCredentials(v, loader); err != nil {
+		return nil, err
+	}
+	loader.Values = values
+	loader.RenderAll = len(v.GetStringSlice("values")) > 0 || len(v.GetStringSlice("set")) > 0
+	return loader, nil
+}
 
-		return b, nil
-	} else if !util.IsURL(arg) {
-		return nil, fmt.Errorf("%s is not a URL and was not found (err %s)", arg, err)
+// loadCollectorSpec loads the support bundle spec at arg, and the other
+// documents in it. A spec that was not rendered, with the discovered values or
+// --values and --set, has its collectors pointed at the installation instead.
+func loadCollectorSpec(loader *specloader.Loader, installation *discovery.Installation, arg string) (*troubleshootv1beta2.SupportBundle, []string, error) {
+	collectorContent, err := loader.Load(arg)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to load collector spec")
 	}
 
-	spec, err := loadSpecFromURL(v, arg)
+	multidocs := strings.Split(string(collectorContent), "\n---\n")
+
+	// we suppory both raw collector kinds and supportbundle kinds here
+	supportBundleSpec, err := parseSupportBundleFromDoc([]byte(multidocs[0]))
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to get spec from URL")
+		return nil, nil, errors.Wrap(err, "failed to parse collector")
 	}
-	return spec, nil
+	if installation != nil && !loader.Rendered {
+		installation.RewriteCollectors(supportBundleSpec.Spec.Collectors)
+	}
+	return supportBundleSpec, multidocs, nil
 }
 
-func loadSpecFromURL(v *viper.Viper, arg string) ([]byte, error) {
-	for {
-		req, err := http.NewRequest("GET", arg, nil)
-		if err != nil {
-			return nil, errors.Wrap(err, "make request")
-		}
-		req.Header.Set("User-Agent", "Replicated_Troubleshoot/v1beta1")
-		req.Header.Set("Bundle-Upload-Host", fmt.Sprintf("%s://%s", req.URL.Scheme, req.URL.Host))
-		resp, err := httpClient.Do(req)
-		if err != nil {
-			if strings.Contains(err.Error(), "x509") && httpClient == http.DefaultClient && canTryInsecure(v) {
-				httpClient = &http.Client{Transport: &http.Transport{
-					TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-				}}
-				continue
-			}
-			return nil, errors.Wrap(err, "execute request")
-		}
-		defer resp.Body.Close()
+// setRegistryCredentials sets the OCI registry credentials of loader from
+// --registry-username, and --registry-password-stdin or the REGISTRY_PASSWORD
+// environment variable.
+func setRegistryCredentials(v *viper.Viper, loader *specloader.Loader) error {
+	loader.RegistryUsername = v.GetString("registry-username")
+	loader.RegistryPassword = v.GetString("registry-password")
+	if !v.GetBool("registry-password-stdin") {
+		return nil
+	}
 
-		body, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return nil, errors.Wrap(err, "read responce body")
-		}
+	if err := loader.ReadRegistryPassword(); err != nil {
+		return err
+	}
+	// standard input can only be read once, so later loaders get the
+	// password from here
+	v.Set("registry-password", loader.RegistryPassword)
+	v.Set("registry-password-stdin", false)
+	return nil
+}
 
-		return body, nil
+// bundleUploadHostHeader tells a spec server which host it was reached on, so
+// that upload URLs in the spec it returns use the same host.
+func bundleUploadHostHeader(arg string) http.Header {
+	header := http.Header{}
+	if u, err := url.Parse(arg); err == nil && util.IsURL(arg) {
+		header.Set("Bundle-Upload-Host", fmt.Sprintf("%s://%s", u.Scheme, u.Host))
 	}
+	return header
 }
 
 func parseSupportBundleFromDoc(doc []byte) (*troubleshootv1beta2.SupportBundle, error) {
@@ -368,17 +537,15 @@ func canTryInsecure(v *viper.Viper) bool {
 	return true
 }
 
-func runCollectors(v *viper.Viper, collectors []*troubleshootv1beta2.Collect, additionalRedactors *troubleshootv1beta2.Redactor, progressChan chan interface{}) (string, error) {
+// runCollectors collects a support bundle and returns the path of its archive,
+// written to archiveDir, or the current directory if archiveDir is "".
+func runCollectors(ctx context.Context, v *viper.Viper, collectors []*troubleshootv1beta2.Collect, additionalRedactors *troubleshootv1beta2.Redactor, installation *discovery.Installation, archiveDir string, progressChan chan interface{}) (string, error) {
 	bundlePath, err := ioutil.TempDir("", "troubleshoot")
 	if err != nil {
 		return "", errors.Wrap(err, "create temp dir")
 	}
 	defer os.RemoveAll(bundlePath)
 
-	if err = writeVersionFile(bundlePath); err != nil {
-		return "", errors.Wrap(err, "write version file")
-	}
-
 	collectSpecs := make([]*troubleshootv1beta2.Collect, 0, 0)
 	collectSpecs = append(collectSpecs, collectors...)
 	collectSpecs = ensureCollectorInList(collectSpecs, troubleshootv1beta2.Collect{ClusterInfo: &troubleshootv1beta2.ClusterInfo{}})
@@ -392,7 +559,7 @@ func runCollectors(v *viper.Viper, collectors []*troubleshootv1beta2.Collect, ad
 	var cleanedCollectors collect.Collectors
 	for _, desiredCollector := range collectSpecs {
 		collector := collect.Collector{
-			Redact:       true,
+			Redact:       v.GetBool("redact"),
 			Collect:      desiredCollector,
 			ClientConfig: config,
 			Namespace:    v.GetString("namespace"),
@@ -400,7 +567,7 @@ func runCollectors(v *viper.Viper, collectors []*troubleshootv1beta2.Collect, ad
 		cleanedCollectors = append(cleanedCollectors, &collector)
 	}
 
-	if err := cleanedCollectors.CheckRBAC(context.Background()); err != nil {
+	if err := cleanedCollectors.CheckRBAC(ctx); err != nil {
 		return "", errors.Wrap(err, "failed to check RBAC for collectors")
 	}
 
@@ -421,34 +588,122 @@ func runCollectors(v *viper.Viper, collectors []*troubleshootv1beta2.Collect, ad
 		globalRedactors = additionalRedactors.Spec.Redactors
 	}
 
-	// Run preflights collectors synchronously
+	collectorTimeout := v.GetDuration("collector-timeout")
+	parallelism := v.GetInt("parallelism")
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	// Every collector gets a manifest entry, including the ones that are
+	// skipped. Each worker only writes to the entry of the collector it runs.
+	manifest := &Manifest{CollectedAt: time.Now()}
 	for _, collector := range cleanedCollectors {
+		manifest.Collectors = append(manifest.Collectors, newCollectorManifest(collector))
+	}
+
+	type collectorJob struct {
+		collector *collect.Collector
+		manifest  *CollectorManifest
+	}
+
+	// Run collectors on a bounded pool of workers. Each collector writes to its
+	// own paths in the bundle directory, so they don't need to be serialized.
+	jobsCh := make(chan collectorJob)
+	var wg sync.WaitGroup
+	for i := 0; i < parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobsCh {
+				runCollector(ctx, job.collector, job.manifest, globalRedactors, collectorTimeout, bundlePath, progressChan)
+			}
+		}()
+	}
+
+	dispatched := 0
+dispatch:
+	for i, collector := range cleanedCollectors {
 		if len(collector.RBACErrors) > 0 {
 			// don't skip clusterResources collector due to RBAC issues
 			if collector.Collect.ClusterResources == nil {
 				progressChan <- fmt.Sprintf("skipping collector %s with insufficient RBAC permissions", collector.GetDisplayName())
+				manifest.Collectors[i].Status = CollectorStatusSkipped
+				manifest.Collectors[i].SkippedReason = "insufficient RBAC permissions"
+				dispatched++
 				continue
 			}
 		}
 
-		progressChan <- collector.GetDisplayName()
+		select {
+		case jobsCh <- collectorJob{collector: collector, manifest: manifest.Collectors[i]}:
+			dispatched++
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobsCh)
+	wg.Wait()
+
+	// troubleshoot has no collectors for the StorageOS custom resources, the
+	// CSI objects with their events, or the hosts
+	storageosManifests := []*CollectorManifest{}
+	if v.GetBool("storageos-resources") {
+		storageosManifests = append(storageosManifests, runStorageOSCollector(ctx, storageos.CollectorName, storageos.CollectResources, config, v.GetBool("redact"), globalRedactors, collectorTimeout, bundlePath, progressChan))
+	}
+	if v.GetBool("csi-resources") {
+		storageosManifests = append(storageosManifests, runStorageOSCollector(ctx, storageos.CSICollectorName, storageos.CollectCSI, config, v.GetBool("redact"), globalRedactors, collectorTimeout, bundlePath, progressChan))
+	}
+	if v.GetBool("host-collector") {
+		storageosManifests = append(storageosManifests, runStorageOSCollector(ctx, hostcollector.CollectorName, collectHosts(v), config, v.GetBool("redact"), globalRedactors, collectorTimeout, bundlePath, progressChan))
+	}
+
+	incompleteReason := ""
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		incompleteReason = "collection timed out"
+	case context.Canceled:
+		incompleteReason = "collection was interrupted"
+	}
 
-		result, err := collector.RunCollectorSync(globalRedactors)
+	if v.GetBool("redact-dry-run") {
+		filename, err := findFileName("redaction-report-"+time.Now().Format("2006-01-02T15:04:05"), "json")
 		if err != nil {
-			progressChan <- fmt.Errorf("failed to run collector %q: %v", collector.GetDisplayName(), err)
-			continue
+			return "", errors.Wrap(err, "find file name")
 		}
 
-		if result != nil {
-			err = saveCollectorOutput(result, bundlePath, collector)
-			if err != nil {
-				progressChan <- fmt.Errorf("failed to parse collector spec %q: %v", collector.GetDisplayName(), err)
-				continue
-			}
+		if err := writeRedactionReport(filename, getRedactionReport(redact.GetRedactionList())); err != nil {
+			return "", errors.Wrap(err, "write redaction report")
 		}
+
+		return filename, nil
+	}
+
+	for _, m := range manifest.Collectors[dispatched:] {
+		m.Status = CollectorStatusSkipped
+		m.SkippedReason = incompleteReason
 	}
+	manifest.Collectors = append(manifest.Collectors, storageosManifests...)
 
-	filename, err := findFileName("support-bundle-"+time.Now().Format("2006-01-02T15:04:05"), "tar.gz")
+	if err = writeVersionFile(bundlePath, incompleteReason); err != nil {
+		return "", errors.Wrap(err, "write version file")
+	}
+
+	if err = writeManifestFile(bundlePath, manifest); err != nil {
+		return "", errors.Wrap(err, "write manifest file")
+	}
+
+	if installation != nil {
+		if err = writeDiscoveryFile(bundlePath, installation); err != nil {
+			return "", errors.Wrap(err, "write discovery file")
+		}
+	}
+
+	// a bundle without a timeline is still useful
+	if err := writeTimelineFile(bundlePath); err != nil {
+		progressChan <- errors.Wrap(err, "failed to write timeline")
+	}
+
+	filename, err := findFileName(filepath.Join(archiveDir, "support-bundle-"+time.Now().Format("2006-01-02T15:04:05")), "tar.gz")
 	if err != nil {
 		return "", errors.Wrap(err, "find file name")
 	}
@@ -460,12 +715,86 @@ func runCollectors(v *viper.Viper, collectors []*troubleshootv1beta2.Collect, ad
 	return filename, nil
 }
 
-func saveCollectorOutput(output map[string][]byte, bundlePath string, c *collect.Collector) error {
+func runCollector(ctx context.Context, collector *collect.Collector, manifest *CollectorManifest, globalRedactors []*troubleshootv1beta2.Redact, timeout time.Duration, bundlePath string, progressChan chan interface{}) {
+	if ctx.Err() != nil {
+		manifest.Status = CollectorStatusSkipped
+		manifest.SkippedReason = ctx.Err().Error()
+		return
+	}
+
+	progressChan <- collector.GetDisplayName()
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	defer func() {
+		manifest.DurationSeconds = time.Since(start).Seconds()
+	}()
+
+	result, err := runCollectorWithContext(ctx, collector, globalRedactors)
+	if err != nil {
+		manifest.Status = CollectorStatusFailed
+		manifest.Error = err.Error()
+		progressChan <- fmt.Errorf("failed to run collector %q: %v", collector.GetDisplayName(), err)
+		return
+	}
+
+	if result != nil {
+		savedFiles, err := saveCollectorOutput(result, bundlePath, collector)
+		if err == nil {
+			err = manifest.addFiles(bundlePath, savedFiles)
+		}
+		if err != nil {
+			manifest.Status = CollectorStatusFailed
+			manifest.Error = err.Error()
+			progressChan <- fmt.Errorf("failed to parse collector spec %q: %v", collector.GetDisplayName(), err)
+			return
+		}
+	}
+
+	manifest.Status = CollectorStatusSucceeded
+}
+
+// runCollectorWithContext returns when the collector finishes or ctx is done,
+// whichever comes first. Collectors don't take a context, so one that is still
+// running when ctx is done is left to finish in the background and its output
+// is discarded.
+func runCollectorWithContext(ctx context.Context, collector *collect.Collector, globalRedactors []*troubleshootv1beta2.Redact) (map[string][]byte, error) {
+	type collectorResult struct {
+		result map[string][]byte
+		err    error
+	}
+
+	resultCh := make(chan collectorResult, 1)
+	go func() {
+		result, err := collector.RunCollectorSync(globalRedactors)
+		resultCh <- collectorResult{result: result, err: err}
+	}()
+
+	select {
+	case r := <-resultCh:
+		return r.result, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// saveCollectorOutput writes the collector output to the bundle directory and
+// returns the paths of the files written, relative to the bundle directory.
+func saveCollectorOutput(output map[string][]byte, bundlePath string, c *collect.Collector) ([]string, error) {
+	savedFiles := []string{}
 	for filename, maybeContents := range output {
 		if c.Collect.Copy != nil {
-			err := untarAndSave(maybeContents, filepath.Join(bundlePath, filepath.Dir(filename)))
+			copiedFiles, err := untarAndSave(maybeContents, filepath.Join(bundlePath, filepath.Dir(filename)))
 			if err != nil {
-				return errors.Wrap(err, "extract copied files")
+				return nil, errors.Wrap(err, "extract copied files")
+			}
+			for _, copiedFile := range copiedFiles {
+				savedFiles = append(savedFiles, filepath.Join(filepath.Dir(filename), copiedFile))
 			}
 			continue
 		}
@@ -473,18 +802,21 @@ func saveCollectorOutput(output map[string][]byte, bundlePath string, c *collect
 		outPath := filepath.Join(bundlePath, fileDir)
 
 		if err := os.MkdirAll(outPath, 0777); err != nil {
-			return errors.Wrap(err, "create output file")
+			return nil, errors.Wrap(err, "create output file")
 		}
 
 		if err := writeFile(filepath.Join(outPath, fileName), maybeContents); err != nil {
-			return errors.Wrap(err, "write collector output")
+			return nil, errors.Wrap(err, "write collector output")
 		}
+		savedFiles = append(savedFiles, filename)
 	}
 
-	return nil
+	return savedFiles, nil
 }
 
-func untarAndSave(tarFile []byte, bundlePath string) error {
+// untarAndSave extracts tarFile to bundlePath and returns the names of the
+// files extracted, relative to bundlePath.
+func untarAndSave(tarFile []byte, bundlePath string) ([]string, error) {
 	keys := make([]string, 0)
 	dirs := make(map[string]*tar.Header)
 	files := make(map[string][]byte)
@@ -495,7 +827,7 @@ func untarAndSave(tarFile []byte, bundlePath string) error {
 		header, err := tarReader.Next()
 		if err != nil {
 			if err != io.EOF {
-				return err
+				return nil, err
 			}
 			break
 		}
@@ -506,17 +838,17 @@ func untarAndSave(tarFile []byte, bundlePath string) error {
 			file := new(bytes.Buffer)
 			_, err = io.Copy(file, tarReader)
 			if err != nil {
-				return err
+				return nil, err
 			}
 			files[header.Name] = file.Bytes()
 			fileHeaders[header.Name] = header
 		default:
-			return fmt.Errorf("Tar file entry %s contained unsupported file type %v", header.Name, header.FileInfo().Mode())
+			return nil, fmt.Errorf("Tar file entry %s contained unsupported file type %v", header.Name, header.FileInfo().Mode())
 		}
 	}
 	//Create directories from base path: <namespace>/<pod name>/containerPath
 	if err := os.MkdirAll(filepath.Join(bundlePath), 0777); err != nil {
-		return errors.Wrap(err, "create output file")
+		return nil, errors.Wrap(err, "create output file")
 	}
 	//Order folders stored in variable keys to start always by parent folder. That way folder info is preserved.
 	for k := range dirs {
@@ -526,50 +858,83 @@ func untarAndSave(tarFile []byte, bundlePath string) error {
 	//Orderly create folders.
 	for _, k := range keys {
 		if err := os.Mkdir(filepath.Join(bundlePath, k), dirs[k].FileInfo().Mode().Perm()); err != nil {
-			return errors.Wrap(err, "create output file")
+			return nil, errors.Wrap(err, "create output file")
 		}
 	}
 	//Populate folders with respective files and its permissions stored in the header.
+	savedFiles := make([]string, 0, len(files))
 	for k, v := range files {
 		if err := ioutil.WriteFile(filepath.Join(bundlePath, k), v, fileHeaders[k].FileInfo().Mode().Perm()); err != nil {
-			return err
+			return nil, err
 		}
+		savedFiles = append(savedFiles, k)
 	}
-	return nil
+	return savedFiles, nil
 }
-func uploadSupportBundle(r *troubleshootv1beta2.ResultRequest, archivePath string) error {
-	contentType := getExpectedContentType(r.URI)
-	if contentType != "" && contentType != "application/tar+gzip" {
-		return fmt.Errorf("cannot upload content type %s", contentType)
+
+// uploadProgress is sent on the progress channel as the support bundle is
+// uploaded. A zero value means the upload has finished.
+type uploadProgress struct {
+	sent  int64
+	total int64
+}
+
+func progressBar(sent int64, total int64) string {
+	const width = 30
+
+	filled := int(sent * width / total)
+	bar := strings.Repeat("=", filled)
+	if filled < width {
+		bar = bar + ">" + strings.Repeat(" ", width-filled-1)
 	}
+	return fmt.Sprintf("[%s] %3d%%", bar, sent*100/total)
+}
 
-	f, err := os.Open(archivePath)
+func newUploader(v *viper.Viper, progressChan chan interface{}) (*upload.Uploader, error) {
+	chunkSize, err := uploadChunkSize(v)
 	if err != nil {
-		return errors.Wrap(err, "open file")
+		return nil, err
 	}
-	defer f.Close()
 
-	fileStat, err := f.Stat()
-	if err != nil {
-		return errors.Wrap(err, "stat file")
+	uploader := upload.NewUploader(httpClient, chunkSize, v.GetInt("upload-retries"))
+	uploader.Progress = throttledProgress(progressChan)
+
+	return uploader, nil
+}
+
+func uploadChunkSize(v *viper.Viper) (int64, error) {
+	s := v.GetString("upload-chunk-size")
+	if s == "" {
+		return 0, nil
 	}
 
-	req, err := http.NewRequest(r.Method, r.URI, f)
+	q, err := resource.ParseQuantity(s)
 	if err != nil {
-		return errors.Wrap(err, "create request")
+		return 0, errors.Wrap(err, "parse upload chunk size")
 	}
-	req.ContentLength = fileStat.Size()
-	if contentType != "" {
-		req.Header.Set("Content-Type", contentType)
+	return q.Value(), nil
+}
+
+// throttledProgress returns a progress func that sends uploadProgress on the
+// progress channel, only reporting whole percentages to keep it quiet.
+func throttledProgress(progressChan chan interface{}) func(sent int64, total int64) {
+	lastPercent := int64(-1)
+	return func(sent int64, total int64) {
+		if percent := sent * 100 / total; percent != lastPercent {
+			lastPercent = percent
+			progressChan <- uploadProgress{sent: sent, total: total}
+		}
 	}
+}
 
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return errors.Wrap(err, "execute request")
+func uploadSupportBundle(uploader *upload.Uploader, r *troubleshootv1beta2.ResultRequest, archivePath string) error {
+	contentType := getExpectedContentType(r.URI)
+	if contentType != "" && contentType != "application/tar+gzip" {
+		return fmt.Errorf("cannot upload content type %s", contentType)
 	}
 
-	if resp.StatusCode >= 300 {
-		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	if err := uploader.Upload(r.Method, r.URI, archivePath, contentType); err != nil {
+		return err
 	}
 
 	// send redaction report
@@ -602,6 +967,62 @@ func uploadSupportBundle(r *troubleshootv1beta2.ResultRequest, archivePath strin
 	return nil
 }
 
+// uploadSupportBundleToS3 uploads the support bundle, its signature if there
+// is one, and the redaction report to the s3://bucket/prefix uri, and returns
+// the URL of the uploaded bundle.
+func uploadSupportBundleToS3(ctx context.Context, v *viper.Viper, uri string, archivePath string, progressChan chan interface{}) (string, error) {
+	location, err := upload.ParseS3URI(uri)
+	if err != nil {
+		return "", err
+	}
+
+	partSize, err := uploadChunkSize(v)
+	if err != nil {
+		return "", err
+	}
+
+	uploader, err := upload.NewS3Uploader(upload.S3Options{
+		Endpoint:   v.GetString("s3-endpoint"),
+		Region:     v.GetString("s3-region"),
+		Profile:    v.GetString("s3-profile"),
+		PartSize:   partSize,
+		MaxRetries: v.GetInt("upload-retries"),
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "create s3 uploader")
+	}
+	uploader.Progress = throttledProgress(progressChan)
+
+	bundleURL, err := uploader.UploadFile(ctx, location, archivePath, "application/tar+gzip")
+	if err != nil {
+		return "", err
+	}
+	uploader.Progress = nil
+
+	sigPath := archivePath + bundlecrypt.SignatureExtension
+	if _, err := os.Stat(sigPath); err == nil {
+		if _, err := uploader.UploadFile(ctx, location, sigPath, "application/pgp-signature"); err != nil {
+			return "", errors.Wrap(err, "upload signature")
+		}
+	}
+
+	type PutSupportBundleRedactions struct {
+		Redactions redact.RedactionList `json:"redactions"`
+	}
+
+	redactBytes, err := json.Marshal(PutSupportBundleRedactions{Redactions: redact.GetRedactionList()})
+	if err != nil {
+		return "", errors.Wrap(err, "get redaction report")
+	}
+
+	redactName := filepath.Base(archivePath) + ".redactions.json"
+	if err := uploader.UploadBytes(ctx, location, redactName, redactBytes, "application/json"); err != nil {
+		return "", errors.Wrap(err, "upload redaction report")
+	}
+
+	return bundleURL, nil
+}
+
 func getExpectedContentType(uploadURL string) string {
 	parsedURL, err := url.Parse(uploadURL)
 	if err != nil {
@@ -656,8 +1077,3 @@ func tarSupportBundleDir(inputDir, outputFilename string) error {
 
 	return nil
 }
-
-type CollectorFailure struct {
-	Collector *troubleshootv1beta2.Collect
-	Failure   string
-}
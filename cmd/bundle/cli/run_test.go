@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/croomes/kubectl-plugin/pkg/discovery"
+	"github.com/croomes/kubectl-plugin/pkg/specloader"
+)
+
+func TestLoadCollectorSpec(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	template := filepath.Join(dir, "template.yaml")
+	if err := ioutil.WriteFile(template, []byte(specloader.TemplateMarker+`
+apiVersion: troubleshoot.sh/v1beta2
+kind: SupportBundle
+spec:
+  collectors:
+    - logs:
+        namespace: {{ .Values.namespace | default "kube-system" }}
+        selector: ["app=storageos"]
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	plain := filepath.Join(dir, "plain.yaml")
+	if err := ioutil.WriteFile(plain, []byte(`apiVersion: troubleshoot.sh/v1beta2
+kind: SupportBundle
+spec:
+  collectors:
+    - logs:
+        namespace: kube-system
+        selector: ["app=storageos"]
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	installation := &discovery.Installation{Namespace: "storageos", Name: "storageos", DaemonSet: "storageos-node"}
+
+	tests := []struct {
+		name string
+		spec string
+		set  []string
+		want string
+	}{
+		{name: "template rendered with discovered values", spec: template, want: "storageos"},
+		{name: "--set survives discovery", spec: template, set: []string{"namespace=foo"}, want: "foo"},
+		{name: "--set on a spec that is not a template", spec: plain, set: []string{"namespace=foo"}, want: "kube-system"},
+		{name: "spec that is not a template is rewritten", spec: plain, want: "storageos"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := viper.New()
+			v.Set("set", tt.set)
+			loader, err := newSpecLoader(v, installation)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			spec, _, err := loadCollectorSpec(loader, installation, tt.spec)
+			if err != nil {
+				t.Fatalf("loadCollectorSpec() error = %v", err)
+			}
+			if got := spec.Spec.Collectors[0].Logs.Namespace; got != tt.want {
+				t.Errorf("namespace = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/croomes/kubectl-plugin/pkg/bundleui"
+	"github.com/croomes/kubectl-plugin/pkg/specloader"
+)
+
+// shutdownTimeout bounds how long open requests are waited for on exit.
+const shutdownTimeout = 5 * time.Second
+
+func Serve() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve [bundle]",
+		Args:  cobra.ExactArgs(1),
+		Short: "browse a support bundle in a web browser",
+		Long: `Start a local web server to browse a support bundle: its analyzer results,
+files, logs and cluster resources. The UI is served from the plugin, so it
+works without internet access.`,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlag("port", cmd.Flags().Lookup("port"))
+			viper.BindPFlag("address", cmd.Flags().Lookup("address"))
+			viper.BindPFlag("spec", cmd.Flags().Lookup("spec"))
+			viper.BindPFlag("values", cmd.Flags().Lookup("values"))
+			viper.BindPFlag("set", cmd.Flags().Lookup("set"))
+			viper.BindPFlag("registry-username", cmd.Flags().Lookup("registry-username"))
+			viper.BindPFlag("registry-password-stdin", cmd.Flags().Lookup("registry-password-stdin"))
+			viper.BindPFlag("private-key", cmd.Flags().Lookup("private-key"))
+			viper.BindPFlag("signatures", cmd.Flags().Lookup("signatures"))
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+
+			dir, cleanup, err := extractBundle(v, args[0])
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			results, err := analyzeExtractedBundle(v, dir, v.GetString("spec"), true)
+			if err != nil {
+				return errors.Wrapf(err, "analyze %s", args[0])
+			}
+
+			server := &bundleui.Server{
+				Dir:     dir,
+				Title:   filepath.Base(args[0]),
+				Results: results,
+			}
+			return serveBundle(v, server)
+		},
+	}
+
+	cmd.Flags().Int("port", 8800, "port to serve the UI on")
+	cmd.Flags().String("address", "127.0.0.1", "address to serve the UI on. the bundle is not redacted further, so only listen on other addresses on trusted networks")
+	cmd.Flags().String("spec", specloader.Embedded, "spec whose analyzers are run on the support bundle")
+	cmd.Flags().StringSlice("values", []string{}, "values files to render the spec template with")
+	cmd.Flags().StringSlice("set", []string{}, "values to render the spec template with, e.g. namespace=storageos. these override --values")
+	cmd.Flags().String("registry-username", "", "username to authenticate to OCI registries with")
+	cmd.Flags().Bool("registry-password-stdin", false, "read the password to authenticate to OCI registries with from standard input, instead of the REGISTRY_PASSWORD environment variable")
+	cmd.Flags().String("private-key", "", "OpenPGP private key file to decrypt an encrypted support bundle with")
+	cmd.Flags().String("signatures", "", "file of known issue signatures to search the logs for. defaults to the built-in signatures")
+
+	return cmd
+}
+
+// serveBundle serves the UI until interrupted.
+func serveBundle(v *viper.Viper, server *bundleui.Server) error {
+	addr := net.JoinHostPort(v.GetString("address"), strconv.Itoa(v.GetInt("port")))
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return errors.Wrap(err, "failed to listen")
+	}
+
+	server.Addr = addr
+	srv := &http.Server{Handler: server.Handler()}
+
+	signalChan := make(chan os.Signal, 1)
+	signal.Notify(signalChan, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signalChan)
+	go func() {
+		<-signalChan
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		srv.Shutdown(ctx)
+	}()
+
+	fmt.Printf("Serving %s at http://%s, press Ctrl+C to stop\n", server.Title, listener.Addr())
+
+	if err := srv.Serve(listener); err != http.ErrServerClosed {
+		return errors.Wrap(err, "serve")
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package cli
+
+import (
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+	analyzer "github.com/replicatedhq/troubleshoot/pkg/analyze"
+	"github.com/spf13/viper"
+
+	"github.com/croomes/kubectl-plugin/pkg/bundlearchive"
+	"github.com/croomes/kubectl-plugin/pkg/signatures"
+)
+
+// analyzeKnownIssues searches the logs of a bundle for the known issues in
+// the --signatures database. walk walks the files of the bundle, which was
+// collected at reference.
+func analyzeKnownIssues(v *viper.Viper, reference time.Time, walk func(fn bundlearchive.WalkFunc) error) ([]*analyzer.AnalyzeResult, error) {
+	db, err := signatures.Load(v.GetString("signatures"))
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := signatures.NewScanner(db, reference)
+	err = walk(func(f bundlearchive.File, r io.Reader) error {
+		return scanner.Add(f.Name, r)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "search logs for known issues")
+	}
+	return scanner.Results(), nil
+}
@@ -0,0 +1,170 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/croomes/kubectl-plugin/pkg/bundlearchive"
+	"github.com/croomes/kubectl-plugin/pkg/discovery"
+	"github.com/croomes/kubectl-plugin/pkg/hostcollector"
+	"github.com/croomes/kubectl-plugin/pkg/storageos"
+	"github.com/pkg/errors"
+	analyzer "github.com/replicatedhq/troubleshoot/pkg/analyze"
+	troubleshootv1beta2 "github.com/replicatedhq/troubleshoot/pkg/apis/troubleshoot/v1beta2"
+	"github.com/replicatedhq/troubleshoot/pkg/redact"
+	"github.com/spf13/viper"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/rest"
+)
+
+// storageosCollectFunc returns the files a StorageOS collector adds to the
+// bundle.
+type storageosCollectFunc func(ctx context.Context, config *rest.Config) (map[string][]byte, error)
+
+// runStorageOSCollector runs a collector that troubleshoot has no collector
+// for, redacting its output like that of any other collector.
+func runStorageOSCollector(ctx context.Context, name string, collect storageosCollectFunc, config *rest.Config, redactOutput bool, globalRedactors []*troubleshootv1beta2.Redact, timeout time.Duration, bundlePath string, progressChan chan interface{}) *CollectorManifest {
+	manifest := &CollectorManifest{
+		Name: name,
+	}
+	if ctx.Err() != nil {
+		manifest.Status = CollectorStatusSkipped
+		manifest.SkippedReason = ctx.Err().Error()
+		return manifest
+	}
+
+	progressChan <- name
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	defer func() {
+		manifest.DurationSeconds = time.Since(start).Seconds()
+	}()
+
+	savedFiles, err := saveStorageOSCollectorOutput(ctx, collect, config, redactOutput, globalRedactors, bundlePath)
+	if err == nil {
+		err = manifest.addFiles(bundlePath, savedFiles)
+	}
+	if err != nil {
+		manifest.Status = CollectorStatusFailed
+		manifest.Error = err.Error()
+		progressChan <- fmt.Errorf("failed to run collector %q: %v", name, err)
+		return manifest
+	}
+
+	manifest.Status = CollectorStatusSucceeded
+	return manifest
+}
+
+func saveStorageOSCollectorOutput(ctx context.Context, collect storageosCollectFunc, config *rest.Config, redactOutput bool, globalRedactors []*troubleshootv1beta2.Redact, bundlePath string) ([]string, error) {
+	output, err := collect(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	savedFiles := []string{}
+	for filename, contents := range output {
+		if redactOutput {
+			contents, err = redact.Redact(contents, filename, globalRedactors)
+			if err != nil {
+				return nil, errors.Wrapf(err, "redact %s", filename)
+			}
+		}
+
+		if err := os.MkdirAll(filepath.Join(bundlePath, filepath.Dir(filename)), 0777); err != nil {
+			return nil, errors.Wrap(err, "create output file")
+		}
+		if err := writeFile(filepath.Join(bundlePath, filename), contents); err != nil {
+			return nil, errors.Wrap(err, "write collector output")
+		}
+		savedFiles = append(savedFiles, filename)
+	}
+	return savedFiles, nil
+}
+
+// collectHosts returns a collect function for the host collector DaemonSet.
+func collectHosts(v *viper.Viper) storageosCollectFunc {
+	return func(ctx context.Context, config *rest.Config) (map[string][]byte, error) {
+		// a second Ctrl-C would quit before the DaemonSet is deleted, so
+		// signals are caught until it has been. the first one has already
+		// cancelled ctx.
+		signalChan := make(chan os.Signal, 1)
+		signal.Notify(signalChan, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(signalChan)
+
+		reports, err := hostcollector.Collect(ctx, config, hostcollector.Options{
+			Namespace:  v.GetString("namespace"),
+			Image:      v.GetString("collector-image"),
+			PullPolicy: corev1.PullPolicy(v.GetString("collector-pullpolicy")),
+			Timeout:    v.GetDuration("host-collector-timeout"),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := json.MarshalIndent(reports, "", "  ")
+		if err != nil {
+			return nil, errors.Wrap(err, "marshal host reports")
+		}
+		return map[string][]byte{hostcollector.Filename: b}, nil
+	}
+}
+
+// analyzeStorageOSCollectorOutput runs the analyzers of the enabled StorageOS
+// collectors, and the known issue analyzer, over the extracted bundle at
+// bundleDir.
+func analyzeStorageOSCollectorOutput(v *viper.Viper, bundleDir string, installation *discovery.Installation) ([]*analyzer.AnalyzeResult, error) {
+	getFile := func(name string) ([]byte, error) {
+		return ioutil.ReadFile(filepath.Join(bundleDir, name))
+	}
+
+	results := []*analyzer.AnalyzeResult{}
+	if v.GetBool("storageos-resources") {
+		resourceResults, err := storageos.AnalyzeResources(getFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "analyze storageos resources")
+		}
+		results = append(results, resourceResults...)
+	}
+	if v.GetBool("csi-resources") {
+		csiResults, err := storageos.AnalyzeCSI(getFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "analyze csi resources")
+		}
+		results = append(results, csiResults...)
+	}
+	if v.GetBool("host-collector") {
+		hostResults, err := hostcollector.AnalyzeFile(getFile, installation.Running())
+		if err != nil {
+			return nil, errors.Wrap(err, "analyze host reports")
+		}
+		results = append(results, hostResults...)
+	}
+	if v.GetBool("known-issues") {
+		walk := func(fn bundlearchive.WalkFunc) error {
+			return walkDir(bundleDir, fn)
+		}
+		collected, err := collectionTime(walk)
+		if err != nil {
+			return nil, err
+		}
+		knownIssueResults, err := analyzeKnownIssues(v, collected, walk)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, knownIssueResults...)
+	}
+	return results, nil
+}
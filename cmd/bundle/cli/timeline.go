@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/troubleshoot/pkg/logger"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/croomes/kubectl-plugin/pkg/bundlearchive"
+	"github.com/croomes/kubectl-plugin/pkg/timeline"
+)
+
+func Timeline() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "timeline [bundle]",
+		Args:  cobra.ExactArgs(1),
+		Short: "print the logs and events of a support bundle in time order",
+		Long: `Merge every log line and Kubernetes event in a support bundle by time, each
+prefixed with the log file or namespace it is from.
+
+--since and --until take a time, e.g. 2020-06-01T15:04:05Z, or a duration
+before the last entry, e.g. 30m.`,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlag("since", cmd.Flags().Lookup("since"))
+			viper.BindPFlag("until", cmd.Flags().Lookup("until"))
+			viper.BindPFlag("private-key", cmd.Flags().Lookup("private-key"))
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+
+			if _, err := os.Stat(args[0]); err != nil {
+				return errors.Wrap(err, "only local support bundles have a timeline")
+			}
+
+			decrypted, cleanup, err := decryptedBundle(v, args[0])
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			walk := func(fn bundlearchive.WalkFunc) error {
+				return bundlearchive.Walk(decrypted, fn)
+			}
+			collected, err := collectionTime(walk)
+			if err != nil {
+				return err
+			}
+			t := timeline.New(collected)
+			err = walk(func(f bundlearchive.File, r io.Reader) error {
+				return t.Add(f.Name, r)
+			})
+			if err != nil {
+				return err
+			}
+
+			last := t.Last()
+			since, err := parseTimelineFlag(v.GetString("since"), last)
+			if err != nil {
+				return errors.Wrap(err, "invalid --since")
+			}
+			until, err := parseTimelineFlag(v.GetString("until"), last)
+			if err != nil {
+				return errors.Wrap(err, "invalid --until")
+			}
+
+			if len(t.Untimed) > 0 {
+				logger.Printf("Left out logs without timestamps: %s\n", strings.Join(t.Untimed, ", "))
+			}
+			return timeline.Write(os.Stdout, t.Entries(since, until))
+		},
+	}
+
+	cmd.Flags().String("since", "", "only show entries at or after this time, or this long before the last entry")
+	cmd.Flags().String("until", "", "only show entries at or before this time, or this long before the last entry")
+	cmd.Flags().String("private-key", "", "OpenPGP private key file to decrypt an encrypted support bundle with")
+
+	return cmd
+}
+
+// parseTimelineFlag parses a time, or a duration before last. "" is zero.
+func parseTimelineFlag(s string, last time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return last.Add(-d), nil
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+// writeTimelineFile writes the timeline of the logs and events collected to
+// bundlePath.
+func writeTimelineFile(bundlePath string) error {
+	walk := func(fn bundlearchive.WalkFunc) error {
+		return walkDir(bundlePath, fn)
+	}
+	collected, err := collectionTime(walk)
+	if err != nil {
+		return err
+	}
+	t := timeline.New(collected)
+	err = walk(func(f bundlearchive.File, r io.Reader) error {
+		return t.Add(f.Name, r)
+	})
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(filepath.Join(bundlePath, timeline.Filename))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := timeline.Write(f, t.Entries(time.Time{}, time.Time{})); err != nil {
+		return err
+	}
+	return f.Close()
+}
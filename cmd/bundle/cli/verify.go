@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/croomes/kubectl-plugin/pkg/bundlecrypt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func Verify() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify [bundle]",
+		Args:  cobra.ExactArgs(1),
+		Short: "verify the signature of a support bundle",
+		Long:  `Verify that a support bundle was signed with --sign-with and has not been modified since`,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlag("public-key", cmd.Flags().Lookup("public-key"))
+			viper.BindPFlag("signature", cmd.Flags().Lookup("signature"))
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+
+			signer, err := verifyBundle(v, args[0])
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Good signature from %q\n", signer)
+			return nil
+		},
+	}
+
+	cmd.Flags().String("public-key", "", "OpenPGP public key file of the signer")
+	cmd.MarkFlagRequired("public-key")
+	cmd.Flags().String("signature", "", "detached signature file. defaults to the bundle filename with a .asc extension")
+
+	return cmd
+}
+
+// verifyBundle checks the detached signature of a local bundle and returns
+// the name of the signer.
+func verifyBundle(v *viper.Viper, bundlePath string) (string, error) {
+	if _, err := os.Stat(bundlePath); err != nil {
+		return "", errors.Wrap(err, "only local support bundles can be verified")
+	}
+
+	publicKey := v.GetString("public-key")
+	if publicKey == "" {
+		return "", errors.New("--public-key is required to verify a support bundle")
+	}
+
+	sigPath := v.GetString("signature")
+	if sigPath == "" {
+		sigPath = bundlePath + bundlecrypt.SignatureExtension
+	}
+
+	signer, err := bundlecrypt.VerifyFile(bundlePath, sigPath, publicKey)
+	if err != nil {
+		return "", errors.Wrapf(err, "verify %s", bundlePath)
+	}
+
+	return bundlecrypt.SignerName(signer), nil
+}
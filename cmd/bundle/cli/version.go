@@ -28,14 +28,37 @@ func VersionCmd() *cobra.Command {
 
 const VersionFilename = "version.yaml"
 
-func writeVersionFile(path string) error {
-	version := troubleshootv1beta2.SupportBundleVersion{
-		ApiVersion: "troubleshoot.sh/v1beta2",
-		Kind:       "SupportBundle",
-		Spec: troubleshootv1beta2.SupportBundleVersionSpec{
-			VersionNumber: version.Version(),
+// SupportBundleVersion extends the troubleshoot version file with the
+// collection status, so that a partial bundle can be told apart from a
+// complete one.
+type SupportBundleVersion struct {
+	troubleshootv1beta2.SupportBundleVersion `yaml:",inline"`
+	Status                                   *SupportBundleStatus `yaml:"status,omitempty"`
+}
+
+type SupportBundleStatus struct {
+	Incomplete bool   `yaml:"incomplete"`
+	Reason     string `yaml:"reason,omitempty"`
+}
+
+// writeVersionFile writes the version file to path. A non-empty
+// incompleteReason marks the bundle as incomplete.
+func writeVersionFile(path string, incompleteReason string) error {
+	version := SupportBundleVersion{
+		SupportBundleVersion: troubleshootv1beta2.SupportBundleVersion{
+			ApiVersion: "troubleshoot.sh/v1beta2",
+			Kind:       "SupportBundle",
+			Spec: troubleshootv1beta2.SupportBundleVersionSpec{
+				VersionNumber: version.Version(),
+			},
 		},
 	}
+	if incompleteReason != "" {
+		version.Status = &SupportBundleStatus{
+			Incomplete: true,
+			Reason:     incompleteReason,
+		}
+	}
 	b, err := yaml.Marshal(version)
 	if err != nil {
 		return err
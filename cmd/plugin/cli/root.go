@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"os"
+	"strings"
+
+	bundlecli "github.com/croomes/kubectl-plugin/cmd/bundle/cli"
+	preflightcli "github.com/croomes/kubectl-plugin/cmd/preflight/cli"
+	"github.com/croomes/kubectl-plugin/pkg/exitcode"
+	"github.com/replicatedhq/troubleshoot/pkg/k8sutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func RootCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "kubectl-storageos",
+		Short: "Troubleshoot StorageOS clusters",
+		Long: `Run preflight checks before installing StorageOS, and generate and analyze
+support bundles from clusters where StorageOS is running.`,
+		SilenceUsage: true,
+	}
+
+	cobra.OnInitialize(initConfig)
+
+	cmd.AddCommand(bundlecli.BundleCmd())
+	cmd.AddCommand(preflightcli.PreflightCmd())
+	cmd.AddCommand(bundlecli.Analyze())
+	cmd.AddCommand(SpecCmd())
+	cmd.AddCommand(VersionCmd())
+
+	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+
+	// kube flags are shared by every subcommand
+	k8sutil.AddFlags(cmd.PersistentFlags())
+	viper.BindPFlags(cmd.PersistentFlags())
+
+	return cmd
+}
+
+func InitAndExecute() {
+	if err := RootCmd().Execute(); err != nil {
+		os.Exit(exitcode.Code(err))
+	}
+}
+
+func initConfig() {
+	viper.SetEnvPrefix("STORAGEOS")
+	viper.AutomaticEnv()
+}
@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/croomes/kubectl-plugin/pkg/defaultspecs"
+	"github.com/croomes/kubectl-plugin/pkg/speclint"
+	"github.com/croomes/kubectl-plugin/pkg/specloader"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// embeddedSpecs are the default specs by the name given to spec print.
+var embeddedSpecs = map[string]string{
+	"bundle":    defaultspecs.SupportBundle,
+	"preflight": defaultspecs.Preflight,
+}
+
+func SpecCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "spec",
+		Short: "Work with troubleshoot specs",
+	}
+
+	cmd.AddCommand(SpecPrintCmd())
+	cmd.AddCommand(SpecLintCmd())
+
+	return cmd
+}
+
+func SpecPrintCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "print (bundle|preflight)",
+		Args:  cobra.ExactArgs(1),
+		Short: "Print an embedded default spec",
+		Long: `Print the default spec compiled into the plugin. Save it to a file to
+customise it, then pass the file to the bundle or preflight command.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			spec, ok := embeddedSpecs[args[0]]
+			if !ok {
+				return fmt.Errorf("unknown spec %q, must be one of %s", args[0], strings.Join(embeddedSpecNames(), ", "))
+			}
+
+			fmt.Print(spec)
+			if !strings.HasSuffix(spec, "\n") {
+				fmt.Println()
+			}
+
+			return nil
+		},
+	}
+	return cmd
+}
+
+func SpecLintCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lint [spec...]",
+		Args:  cobra.MinimumNArgs(1),
+		Short: "Check bundle, preflight and redactor specs for mistakes",
+		Long: `Check every document in each spec for unknown kinds and types, analyzers
+without outcomes, invalid when expressions, invalid redactor regexes and
+duplicate analyzers. Problems are printed with the line they are on.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			valuesFiles, _ := cmd.Flags().GetStringSlice("values")
+			sets, _ := cmd.Flags().GetStringSlice("set")
+			values, err := specloader.LoadValues(valuesFiles, sets)
+			if err != nil {
+				return err
+			}
+
+			loader := specloader.New(specloader.SupportBundleKey, false)
+			loader.Values = values
+			loader.RenderAll = len(valuesFiles) > 0 || len(sets) > 0
+
+			failed := false
+			for _, arg := range args {
+				content, err := loader.Load(arg)
+				if err != nil {
+					return err
+				}
+
+				diagnostics := speclint.Lint(content)
+				for _, d := range diagnostics {
+					fmt.Printf("%s:%s\n", arg, d)
+				}
+				if speclint.HasErrors(diagnostics) {
+					failed = true
+				}
+			}
+
+			if failed {
+				return errors.New("specs have errors")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSlice("values", []string{}, "values files to render the spec templates with")
+	cmd.Flags().StringSlice("set", []string{}, "values to render the spec templates with, e.g. namespace=storageos. these override --values")
+
+	return cmd
+}
+
+func embeddedSpecNames() []string {
+	names := []string{}
+	for name := range embeddedSpecs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,22 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/croomes/kubectl-plugin/pkg/version"
+	"github.com/spf13/cobra"
+)
+
+func VersionCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "version",
+		Short: "Print the current version and exit",
+		Long:  `Print the current version and exit`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fmt.Printf("StorageOS kubectl plugin %s\n", version.Version())
+
+			return nil
+		},
+	}
+	return cmd
+}
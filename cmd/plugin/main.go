@@ -0,0 +1,10 @@
+package main
+
+import (
+	"github.com/croomes/kubectl-plugin/cmd/plugin/cli"
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+)
+
+func main() {
+	cli.InitAndExecute()
+}
@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+	analyzerunner "github.com/replicatedhq/troubleshoot/pkg/analyze"
+)
+
+// JUnit has no warning status, so warnings are reported as skipped test
+// cases. They show up in CI test reports without failing the build. Test
+// case names are unique, since CI test reports merge cases with the same
+// name; analyzers with the same title get a number after it.
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+func showStdoutResultsJUnit(w io.Writer, preflightName string, analyzeResults []*analyzerunner.AnalyzeResult) error {
+	suite := junitTestSuite{
+		Name:      preflightName,
+		TestCases: []junitTestCase{},
+	}
+
+	titleCounts := map[string]int{}
+	for _, analyzeResult := range analyzeResults {
+		if !analyzeResult.IsPass && !analyzeResult.IsWarn && !analyzeResult.IsFail {
+			continue
+		}
+
+		name := analyzeResult.Title
+		titleCounts[analyzeResult.Title]++
+		if n := titleCounts[analyzeResult.Title]; n > 1 {
+			name = fmt.Sprintf("%s (%d)", analyzeResult.Title, n)
+		}
+
+		testCase := junitTestCase{
+			Name:      name,
+			Classname: preflightName,
+			SystemOut: junitSystemOut(analyzeResult),
+		}
+
+		if analyzeResult.IsWarn {
+			testCase.Skipped = &junitSkipped{Message: analyzeResult.Message}
+			suite.Skipped++
+		} else if analyzeResult.IsFail {
+			testCase.Failure = &junitFailure{Message: analyzeResult.Message, Type: "fail"}
+			suite.Failures++
+		}
+
+		suite.TestCases = append(suite.TestCases, testCase)
+		suite.Tests++
+	}
+
+	output := junitTestSuites{
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Skipped:  suite.Skipped,
+		Suites:   []junitTestSuite{suite},
+	}
+
+	b, err := xml.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal results")
+	}
+
+	fmt.Fprintf(w, "%s%s\n", xml.Header, b)
+
+	return nil
+}
+
+func junitSystemOut(analyzeResult *analyzerunner.AnalyzeResult) string {
+	lines := []string{analyzeResult.Message}
+	if analyzeResult.URI != "" {
+		lines = append(lines, analyzeResult.URI)
+	}
+	return strings.Join(lines, "\n")
+}
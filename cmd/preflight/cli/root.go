@@ -7,13 +7,31 @@ import (
 	"github.com/replicatedhq/troubleshoot/pkg/k8sutil"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-)
 
-const (
-	defaultSpec = "https://raw.githubusercontent.com/croomes/kubectl-plugin/master/examples/preflight.yaml"
+	"github.com/croomes/kubectl-plugin/pkg/defaultspecs"
+	"github.com/croomes/kubectl-plugin/pkg/exitcode"
+	"github.com/croomes/kubectl-plugin/pkg/hostcollector"
 )
 
+// RootCmd returns the standalone preflight command, with its own config and
+// kube flags.
 func RootCmd() *cobra.Command {
+	cmd := PreflightCmd()
+
+	cobra.OnInitialize(initConfig)
+
+	cmd.AddCommand(VersionCmd())
+
+	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+
+	k8sutil.AddFlags(cmd.Flags())
+
+	return cmd
+}
+
+// PreflightCmd returns the preflight command without the kube flags, so that
+// it can be mounted under a root command that provides them.
+func PreflightCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "preflight [url]",
 		Args:  cobra.MinimumNArgs(0),
@@ -26,7 +44,10 @@ that a cluster meets the requirements to run an application.`,
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			v := viper.GetViper()
-			spec := defaultSpec
+			spec, err := defaultspecs.Source(v.GetString("spec-source"), defaultspecs.PreflightURL)
+			if err != nil {
+				return err
+			}
 			if len(args) > 0 {
 				spec = args[0]
 			}
@@ -34,26 +55,28 @@ that a cluster meets the requirements to run an application.`,
 		},
 	}
 
-	cobra.OnInitialize(initConfig)
-
-	cmd.AddCommand(VersionCmd())
-
 	cmd.Flags().Bool("interactive", true, "interactive preflights")
-	cmd.Flags().String("format", "human", "output format, one of human, json, yaml. only used when interactive is set to false")
+	cmd.Flags().String("format", "human", "output format, one of human, json, yaml, junit, sarif. only used when interactive is set to false")
+	cmd.Flags().StringSlice("values", []string{}, "values files to render the spec template with")
+	cmd.Flags().StringSlice("set", []string{}, "values to render the spec template with, e.g. namespace=storageos. these override --values")
+	cmd.Flags().Bool("csi-resources", true, "check the CSI drivers and nodes, volume attachments, storage classes and persistent volume claims")
+	cmd.Flags().Bool("discover", true, "find where StorageOS is installed and point the spec at it. the spec values are overridden by --values and --set")
+	cmd.Flags().String("registry-username", "", "username to authenticate to OCI registries with")
+	cmd.Flags().Bool("registry-password-stdin", false, "read the password to authenticate to OCI registries with from standard input, instead of the REGISTRY_PASSWORD environment variable")
+	cmd.Flags().String("spec-source", defaultspecs.SourceEmbedded, "where to load the default spec from when none is given, one of embedded, remote. remote fetches the latest spec from GitHub")
+	cmd.Flags().String("fail-on", exitcode.FailOnFail, "lowest check result that makes the command exit non-zero, one of warn, fail")
+	cmd.Flags().Bool("host-collector", false, "run a temporary privileged DaemonSet to check the kernel modules, ports, disk space and hugepages of every node")
+	cmd.Flags().Duration("host-collector-timeout", hostcollector.DefaultTimeout, "how long to wait for every node to report its host checks")
 	cmd.Flags().String("collector-image", "", "the full name of the collector image to use")
 	cmd.Flags().String("collector-pullpolicy", "", "the pull policy of the collector image")
 	cmd.Flags().Bool("collect-without-permissions", false, "always run preflight checks even if some require permissions that preflight does not have")
 
-	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
-
-	k8sutil.AddFlags(cmd.Flags())
-
 	return cmd
 }
 
 func InitAndExecute() {
 	if err := RootCmd().Execute(); err != nil {
-		os.Exit(1)
+		os.Exit(exitcode.Code(err))
 	}
 }
 
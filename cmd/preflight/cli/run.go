@@ -1,91 +1,95 @@
 package cli
 
 import (
+	"context"
 	"fmt"
-	"io/ioutil"
-	"net/http"
+	"io"
 	"os"
-	"strings"
+	"os/signal"
+	"syscall"
 	"time"
 
 	cursor "github.com/ahmetalpbalkan/go-cursor"
 	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
 	"github.com/pkg/errors"
-	"github.com/replicatedhq/troubleshoot/cmd/util"
+	analyzerunner "github.com/replicatedhq/troubleshoot/pkg/analyze"
 	troubleshootv1beta2 "github.com/replicatedhq/troubleshoot/pkg/apis/troubleshoot/v1beta2"
 	troubleshootclientsetscheme "github.com/replicatedhq/troubleshoot/pkg/client/troubleshootclientset/scheme"
 	"github.com/replicatedhq/troubleshoot/pkg/docrewrite"
 	"github.com/replicatedhq/troubleshoot/pkg/k8sutil"
 	"github.com/replicatedhq/troubleshoot/pkg/preflight"
-	"github.com/replicatedhq/troubleshoot/pkg/specs"
 	"github.com/spf13/viper"
 	spin "github.com/tj/go-spin"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+
+	"github.com/croomes/kubectl-plugin/pkg/defaultspecs"
+	"github.com/croomes/kubectl-plugin/pkg/discovery"
+	"github.com/croomes/kubectl-plugin/pkg/exitcode"
+	"github.com/croomes/kubectl-plugin/pkg/hostcollector"
+	"github.com/croomes/kubectl-plugin/pkg/specloader"
+	"github.com/croomes/kubectl-plugin/pkg/storageos"
 )
 
 func runPreflights(v *viper.Viper, arg string) error {
-	fmt.Print(cursor.Hide())
-	defer fmt.Print(cursor.Show())
-
-	var preflightContent []byte
-	var err error
-	if strings.HasPrefix(arg, "secret/") {
-		// format secret/namespace-name/secret-name
-		pathParts := strings.Split(arg, "/")
-		if len(pathParts) != 3 {
-			return errors.Errorf("path %s must have 3 components", arg)
-		}
-
-		spec, err := specs.LoadFromSecret(pathParts[1], pathParts[2], "preflight-spec")
-		if err != nil {
-			return errors.Wrap(err, "failed to get spec from secret")
-		}
+	if err := exitcode.ValidateFailOn(v.GetString("fail-on")); err != nil {
+		return err
+	}
 
-		preflightContent = spec
-	} else if _, err = os.Stat(arg); err == nil {
-		b, err := ioutil.ReadFile(arg)
-		if err != nil {
-			return err
-		}
+	// without the interactive UI the results are written to stdout, so that
+	// they can be redirected to a file, and progress goes to stderr. the
+	// spinner is only shown on a terminal.
+	progress := io.Writer(os.Stdout)
+	if !v.GetBool("interactive") {
+		progress = os.Stderr
+	}
+	showSpinner := progress == io.Writer(os.Stdout) || isatty.IsTerminal(os.Stderr.Fd())
 
-		preflightContent = b
-	} else {
-		if !util.IsURL(arg) {
-			return fmt.Errorf("%s is not a URL and was not found (err %s)", arg, err)
-		}
+	if showSpinner {
+		fmt.Fprint(progress, cursor.Hide())
+		defer fmt.Fprint(progress, cursor.Show())
+	}
 
-		req, err := http.NewRequest("GET", arg, nil)
-		if err != nil {
-			return err
-		}
-		req.Header.Set("User-Agent", "Replicated_Preflight/v1beta2")
-		resp, err := http.DefaultClient.Do(req)
-		if err != nil {
-			return err
-		}
-		defer resp.Body.Close()
+	restConfig, err := k8sutil.GetRESTConfig()
+	if err != nil {
+		return errors.Wrap(err, "failed to convert kube flags to rest config")
+	}
 
-		body, err := ioutil.ReadAll(resp.Body)
+	// values found in the cluster are overridden by --values and --set
+	values := specloader.Values{}
+	var installation *discovery.Installation
+	if v.GetBool("discover") {
+		installation, err = discovery.Discover(context.Background(), restConfig)
 		if err != nil {
-			return err
+			return errors.Wrap(err, "discover storageos")
 		}
-
-		preflightContent = body
+		values = installation.Values()
 	}
 
-	preflightContent, err = docrewrite.ConvertToV1Beta2(preflightContent)
+	userValues, err := specloader.LoadValues(v.GetStringSlice("values"), v.GetStringSlice("set"))
 	if err != nil {
-		return errors.Wrap(err, "failed to convert to v1beta2")
+		return err
 	}
+	specloader.MergeValues(values, userValues)
 
-	troubleshootclientsetscheme.AddToScheme(scheme.Scheme)
-	decode := scheme.Codecs.UniversalDeserializer().Decode
-	obj, _, err := decode([]byte(preflightContent), nil, nil)
-	if err != nil {
-		return errors.Wrapf(err, "failed to parse %s", arg)
+	loader := specloader.New(specloader.PreflightKey, v.GetBool("insecure-skip-tls-verify"))
+	loader.Default = defaultspecs.Preflight
+	loader.RegistryUsername = v.GetString("registry-username")
+	loader.RegistryPassword = v.GetString("registry-password")
+	if v.GetBool("registry-password-stdin") {
+		if err := loader.ReadRegistryPassword(); err != nil {
+			return err
+		}
 	}
+	loader.Values = values
+	loader.RenderAll = len(v.GetStringSlice("values")) > 0 || len(v.GetStringSlice("set")) > 0
 
-	preflightSpec := obj.(*troubleshootv1beta2.Preflight)
+	preflightSpec, err := loadPreflightSpec(loader, installation, arg)
+	if err != nil {
+		return err
+	}
 
 	s := spin.New()
 	finishedCh := make(chan bool, 1)
@@ -97,18 +101,26 @@ func runPreflights(v *viper.Viper, arg string) error {
 				if !ok {
 					continue
 				}
+				clear := ""
+				if showSpinner {
+					clear = cursor.ClearEntireLine() + "\r"
+				}
 				switch msg := msg.(type) {
 				case error:
 					c := color.New(color.FgHiRed)
-					c.Println(fmt.Sprintf("%s\r * %v", cursor.ClearEntireLine(), msg))
+					c.Fprintln(progress, fmt.Sprintf("%s * %v", clear, msg))
 				case string:
 					c := color.New(color.FgCyan)
-					c.Println(fmt.Sprintf("%s\r * %s", cursor.ClearEntireLine(), msg))
+					c.Fprintln(progress, fmt.Sprintf("%s * %s", clear, msg))
 				}
 			case <-time.After(time.Millisecond * 100):
-				fmt.Printf("\r  \033[36mRunning Preflight checks\033[m %s ", s.Next())
+				if showSpinner {
+					fmt.Fprintf(progress, "\r  \033[36mRunning Preflight checks\033[m %s ", s.Next())
+				}
 			case <-finishedCh:
-				fmt.Printf("\r%s\r", cursor.ClearEntireLine())
+				if showSpinner {
+					fmt.Fprintf(progress, "\r%s\r", cursor.ClearEntireLine())
+				}
 				return
 			}
 		}
@@ -117,11 +129,6 @@ func runPreflights(v *viper.Viper, arg string) error {
 		close(finishedCh)
 	}()
 
-	restConfig, err := k8sutil.GetRESTConfig()
-	if err != nil {
-		return errors.Wrap(err, "failed to convert kube flags to rest config")
-	}
-
 	collectOpts := preflight.CollectOpts{
 		Namespace:              v.GetString("namespace"),
 		IgnorePermissionErrors: v.GetBool("collect-without-permissions"),
@@ -139,10 +146,24 @@ func runPreflights(v *viper.Viper, arg string) error {
 				}
 			}
 		}
-		return err
+		return exitcode.New(exitcode.CollectionError, err)
 	}
 
 	analyzeResults := collectResults.Analyze()
+	if v.GetBool("csi-resources") {
+		csiResults, err := analyzeCSI(restConfig)
+		if err != nil {
+			progressChan <- err
+		}
+		analyzeResults = append(analyzeResults, csiResults...)
+	}
+	if v.GetBool("host-collector") {
+		hostResults, err := analyzeHosts(v, restConfig, installation)
+		if err != nil {
+			return exitcode.New(exitcode.CollectionError, err)
+		}
+		analyzeResults = append(analyzeResults, hostResults...)
+	}
 	if preflightSpec.Spec.UploadResultsTo != "" {
 		err := uploadResults(preflightSpec.Spec.UploadResultsTo, analyzeResults)
 		if err != nil {
@@ -156,8 +177,99 @@ func runPreflights(v *viper.Viper, arg string) error {
 		if len(analyzeResults) == 0 {
 			return errors.New("no data has been collected")
 		}
-		return showInteractiveResults(preflightSpec.Name, analyzeResults)
+		if err := showInteractiveResults(preflightSpec.Name, analyzeResults); err != nil {
+			return err
+		}
+		return exitcode.FromResults(analyzeResults, v.GetString("fail-on"))
+	}
+
+	if err := showStdoutResults(os.Stdout, v.GetString("format"), preflightSpec.Name, arg, analyzeResults); err != nil {
+		return err
 	}
+	return exitcode.FromResults(analyzeResults, v.GetString("fail-on"))
+}
 
-	return showStdoutResults(v.GetString("format"), preflightSpec.Name, analyzeResults)
+// analyzeCSI collects the CSI and volume objects, which troubleshoot has no
+// collector for, and analyzes them with the preflight checks.
+func analyzeCSI(restConfig *rest.Config) ([]*analyzerunner.AnalyzeResult, error) {
+	output, err := storageos.CollectCSI(context.Background(), restConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "collect csi resources")
+	}
+
+	results, err := storageos.AnalyzeCSI(func(name string) ([]byte, error) {
+		b, ok := output[name]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+		return b, nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "analyze csi resources")
+	}
+	return results, nil
+}
+
+// analyzeHosts runs the host collector DaemonSet and analyzes what it
+// reports. Ctrl-C stops waiting for the nodes, and the DaemonSet is deleted
+// before returning.
+func analyzeHosts(v *viper.Viper, restConfig *rest.Config, installation *discovery.Installation) ([]*analyzerunner.AnalyzeResult, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	signalChan := make(chan os.Signal, 1)
+	signal.Notify(signalChan, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signalChan)
+	go func() {
+		select {
+		case <-signalChan:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	reports, err := hostcollector.Collect(ctx, restConfig, hostcollector.Options{
+		Namespace:  v.GetString("namespace"),
+		Image:      v.GetString("collector-image"),
+		PullPolicy: corev1.PullPolicy(v.GetString("collector-pullpolicy")),
+		Timeout:    v.GetDuration("host-collector-timeout"),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "run host collector")
+	}
+	if ctx.Err() != nil {
+		return nil, errors.New("host checks were interrupted")
+	}
+
+	return hostcollector.Analyze(reports, installation.Running()), nil
+}
+
+// loadPreflightSpec loads the preflight spec at arg. A spec that was not
+// rendered, with the discovered values or --values and --set, has its
+// collectors pointed at the installation instead.
+func loadPreflightSpec(loader *specloader.Loader, installation *discovery.Installation, arg string) (*troubleshootv1beta2.Preflight, error) {
+	preflightContent, err := loader.Load(arg)
+	if err != nil {
+		return nil, err
+	}
+
+	preflightContent, err = docrewrite.ConvertToV1Beta2(preflightContent)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to convert to v1beta2")
+	}
+
+	troubleshootclientsetscheme.AddToScheme(scheme.Scheme)
+	decode := scheme.Codecs.UniversalDeserializer().Decode
+	obj, _, err := decode([]byte(preflightContent), nil, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", arg)
+	}
+
+	preflightSpec, ok := obj.(*troubleshootv1beta2.Preflight)
+	if !ok {
+		return nil, errors.Errorf("%s is not a preflight spec", arg)
+	}
+	if installation != nil && !loader.Rendered {
+		installation.RewriteCollectors(preflightSpec.Spec.Collectors)
+	}
+	return preflightSpec, nil
 }
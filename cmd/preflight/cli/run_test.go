@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/croomes/kubectl-plugin/pkg/discovery"
+	"github.com/croomes/kubectl-plugin/pkg/specloader"
+)
+
+func TestLoadPreflightSpec(t *testing.T) {
+	dir, err := ioutil.TempDir("", "preflight")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	specs := map[string]string{
+		"template.yaml": "namespace: {{ .Values.namespace | default \"kube-system\" }}",
+		"plain.yaml":    "namespace: kube-system",
+	}
+	for name, namespace := range specs {
+		spec := `apiVersion: troubleshoot.sh/v1beta2
+kind: Preflight
+spec:
+  collectors:
+    - logs:
+        ` + namespace + `
+        selector: ["app=storageos"]
+`
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(spec), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	installation := &discovery.Installation{Namespace: "storageos", DaemonSet: "storageos-node"}
+
+	tests := []struct {
+		name      string
+		spec      string
+		renderAll bool
+		want      string
+	}{
+		// as with --set namespace=foo
+		{name: "rendered", spec: "template.yaml", renderAll: true, want: "foo"},
+		{name: "not rendered", spec: "plain.yaml", want: "storageos"},
+	}
+	for _, tt := range tests {
+		loader := specloader.New(specloader.PreflightKey, false)
+		loader.Values = specloader.Values{"namespace": "foo"}
+		loader.RenderAll = tt.renderAll
+
+		preflightSpec, err := loadPreflightSpec(loader, installation, filepath.Join(dir, tt.spec))
+		if err != nil {
+			t.Fatalf("%s: loadPreflightSpec() error = %v", tt.name, err)
+		}
+		if got := preflightSpec.Spec.Collectors[0].Logs.Namespace; got != tt.want {
+			t.Errorf("%s: namespace = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	analyzerunner "github.com/replicatedhq/troubleshoot/pkg/analyze"
+
+	"github.com/croomes/kubectl-plugin/pkg/version"
+)
+
+const (
+	sarifSchema  = "https://raw.githubusercontent.com/oasis-tcs/sarif-spec/master/Schemata/sarif-schema-2.1.0.json"
+	sarifVersion = "2.1.0"
+)
+
+var sarifRuleIDInvalid = regexp.MustCompile(`[^a-z0-9]+`)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri,omitempty"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	Name             string       `json:"name,omitempty"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+	HelpURI          string       `json:"helpUri,omitempty"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Kind      string          `json:"kind"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// showStdoutResultsSARIF writes the results as a SARIF log, with a rule for
+// each analyzer title. When the spec is a local file, each result is located
+// in it so that code scanning dashboards can attribute it.
+func showStdoutResultsSARIF(w io.Writer, preflightName string, specPath string, analyzeResults []*analyzerunner.AnalyzeResult) error {
+	run := sarifRun{
+		Tool: sarifTool{
+			Driver: sarifDriver{
+				Name:           "kubectl-storageos preflight",
+				Version:        version.Version(),
+				InformationURI: "https://github.com/croomes/kubectl-plugin",
+				Rules:          []sarifRule{},
+			},
+		},
+		Results: []sarifResult{},
+	}
+
+	var locations []sarifLocation
+	if info, err := os.Stat(specPath); err == nil && !info.IsDir() {
+		locations = []sarifLocation{
+			{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: specPath}}},
+		}
+	}
+
+	ruleIndexes := map[string]int{}
+	for _, analyzeResult := range analyzeResults {
+		result := sarifResult{
+			Message:   sarifMessage{Text: analyzeResult.Message},
+			Locations: locations,
+		}
+		if analyzeResult.IsPass {
+			result.Kind, result.Level = "pass", "none"
+		} else if analyzeResult.IsWarn {
+			result.Kind, result.Level = "fail", "warning"
+		} else if analyzeResult.IsFail {
+			result.Kind, result.Level = "fail", "error"
+		} else {
+			continue
+		}
+
+		result.RuleID = sarifRuleID(preflightName, analyzeResult.Title)
+		index, ok := ruleIndexes[result.RuleID]
+		if !ok {
+			index = len(run.Tool.Driver.Rules)
+			ruleIndexes[result.RuleID] = index
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+				ID:               result.RuleID,
+				Name:             analyzeResult.Title,
+				ShortDescription: sarifMessage{Text: analyzeResult.Title},
+			})
+		}
+		if analyzeResult.URI != "" {
+			run.Tool.Driver.Rules[index].HelpURI = analyzeResult.URI
+		}
+		result.RuleIndex = index
+
+		run.Results = append(run.Results, result)
+	}
+
+	output := sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs:    []sarifRun{run},
+	}
+
+	b, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal results")
+	}
+
+	fmt.Fprintf(w, "%s\n", b)
+
+	return nil
+}
+
+// sarifRuleID returns a stable rule id for an analyzer title, e.g.
+// "storageos-preflight/kernel-version".
+func sarifRuleID(preflightName string, title string) string {
+	slug := func(s string) string {
+		return strings.Trim(sarifRuleIDInvalid.ReplaceAllString(strings.ToLower(s), "-"), "-")
+	}
+	return slug(preflightName) + "/" + slug(title)
+}
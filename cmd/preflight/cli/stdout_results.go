@@ -3,93 +3,119 @@ package cli
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 
 	"github.com/pkg/errors"
 	analyzerunner "github.com/replicatedhq/troubleshoot/pkg/analyze"
+	"gopkg.in/yaml.v2"
 )
 
-func showStdoutResults(format string, preflightName string, analyzeResults []*analyzerunner.AnalyzeResult) error {
-	if format == "human" {
-		return showStdoutResultsHuman(preflightName, analyzeResults)
-	} else if format == "json" {
-		return showStdoutResultsJSON(preflightName, analyzeResults)
+// showStdoutResults writes the results to w in format.
+func showStdoutResults(w io.Writer, format string, preflightName string, specPath string, analyzeResults []*analyzerunner.AnalyzeResult) error {
+	switch format {
+	case "human":
+		return showStdoutResultsHuman(w, preflightName, analyzeResults)
+	case "json":
+		return showStdoutResultsJSON(w, preflightName, analyzeResults)
+	case "yaml":
+		return showStdoutResultsYAML(w, preflightName, analyzeResults)
+	case "junit":
+		return showStdoutResultsJUnit(w, preflightName, analyzeResults)
+	case "sarif":
+		return showStdoutResultsSARIF(w, preflightName, specPath, analyzeResults)
 	}
 
 	return errors.Errorf("unknown output format: %q", format)
 }
 
-func showStdoutResultsHuman(preflightName string, analyzeResults []*analyzerunner.AnalyzeResult) error {
+func showStdoutResultsHuman(w io.Writer, preflightName string, analyzeResults []*analyzerunner.AnalyzeResult) error {
 	var failed bool
 	for _, analyzeResult := range analyzeResults {
-		testResultfailed := outputResult(analyzeResult)
+		testResultfailed := outputResult(w, analyzeResult)
 		if testResultfailed {
 			failed = true
 		}
 	}
 	if failed {
-		fmt.Printf("--- FAIL   %s\n", preflightName)
-		fmt.Println("FAILED")
+		fmt.Fprintf(w, "--- FAIL   %s\n", preflightName)
+		fmt.Fprintln(w, "FAILED")
 	} else {
-		fmt.Printf("--- PASS   %s\n", preflightName)
-		fmt.Println("PASS")
+		fmt.Fprintf(w, "--- PASS   %s\n", preflightName)
+		fmt.Fprintln(w, "PASS")
 	}
 	return nil
 }
 
-func showStdoutResultsJSON(preflightName string, analyzeResults []*analyzerunner.AnalyzeResult) error {
-	type ResultOutput struct {
-		Title   string `json:"title"`
-		Message string `json:"message"`
-		URI     string `json:"uri,omitempty"`
-	}
-	type Output struct {
-		Pass []ResultOutput `json:"pass,omitempty"`
-		Warn []ResultOutput `json:"warn,omitempty"`
-		Fail []ResultOutput `json:"fail,omitempty"`
-	}
+type resultOutput struct {
+	Title   string `json:"title" yaml:"title"`
+	Message string `json:"message" yaml:"message"`
+	URI     string `json:"uri,omitempty" yaml:"uri,omitempty"`
+}
+
+type resultsOutput struct {
+	Pass []resultOutput `json:"pass,omitempty" yaml:"pass,omitempty"`
+	Warn []resultOutput `json:"warn,omitempty" yaml:"warn,omitempty"`
+	Fail []resultOutput `json:"fail,omitempty" yaml:"fail,omitempty"`
+}
 
-	output := Output{
-		Pass: []ResultOutput{},
-		Warn: []ResultOutput{},
-		Fail: []ResultOutput{},
+func getResultsOutput(analyzeResults []*analyzerunner.AnalyzeResult) resultsOutput {
+	output := resultsOutput{
+		Pass: []resultOutput{},
+		Warn: []resultOutput{},
+		Fail: []resultOutput{},
 	}
 
 	for _, analyzeResult := range analyzeResults {
-		resultOutput := ResultOutput{
+		result := resultOutput{
 			Title:   analyzeResult.Title,
 			Message: analyzeResult.Message,
 			URI:     analyzeResult.URI,
 		}
 
 		if analyzeResult.IsPass {
-			output.Pass = append(output.Pass, resultOutput)
+			output.Pass = append(output.Pass, result)
 		} else if analyzeResult.IsWarn {
-			output.Warn = append(output.Warn, resultOutput)
+			output.Warn = append(output.Warn, result)
 		} else if analyzeResult.IsFail {
-			output.Fail = append(output.Fail, resultOutput)
+			output.Fail = append(output.Fail, result)
 		}
 	}
 
-	b, err := json.MarshalIndent(output, "", "  ")
+	return output
+}
+
+func showStdoutResultsJSON(w io.Writer, preflightName string, analyzeResults []*analyzerunner.AnalyzeResult) error {
+	b, err := json.MarshalIndent(getResultsOutput(analyzeResults), "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal results")
+	}
+
+	fmt.Fprintf(w, "%s\n", b)
+
+	return nil
+}
+
+func showStdoutResultsYAML(w io.Writer, preflightName string, analyzeResults []*analyzerunner.AnalyzeResult) error {
+	b, err := yaml.Marshal(getResultsOutput(analyzeResults))
 	if err != nil {
 		return errors.Wrap(err, "failed to marshal results")
 	}
 
-	fmt.Printf("%s\n", b)
+	fmt.Fprintf(w, "%s", b)
 
 	return nil
 }
 
-func outputResult(analyzeResult *analyzerunner.AnalyzeResult) bool {
+func outputResult(w io.Writer, analyzeResult *analyzerunner.AnalyzeResult) bool {
 	if analyzeResult.IsPass {
-		fmt.Printf("   --- PASS %s\n", analyzeResult.Title)
-		fmt.Printf("      --- %s\n", analyzeResult.Message)
+		fmt.Fprintf(w, "   --- PASS %s\n", analyzeResult.Title)
+		fmt.Fprintf(w, "      --- %s\n", analyzeResult.Message)
 	} else if analyzeResult.IsWarn {
-		fmt.Printf("   --- WARN: %s\n", analyzeResult.Title)
-		fmt.Printf("      --- %s\n", analyzeResult.Message)
+		fmt.Fprintf(w, "   --- WARN: %s\n", analyzeResult.Title)
+		fmt.Fprintf(w, "      --- %s\n", analyzeResult.Message)
 	} else if analyzeResult.IsFail {
-		fmt.Printf("   --- FAIL: %s\n", analyzeResult.Title)
-		fmt.Printf("      --- %s\n", analyzeResult.Message)
+		fmt.Fprintf(w, "   --- FAIL: %s\n", analyzeResult.Title)
+		fmt.Fprintf(w, "      --- %s\n", analyzeResult.Message)
 		return true
 	}
 	return false
@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	analyzerunner "github.com/replicatedhq/troubleshoot/pkg/analyze"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+func TestShowStdoutResults(t *testing.T) {
+	results := []*analyzerunner.AnalyzeResult{
+		{IsPass: true, Title: "Kubernetes Version", Message: "Kubernetes is at least 1.17."},
+		{IsWarn: true, Title: "Kernel Modules", Message: "The tcm_loop kernel module is not loaded on node-a.", URI: "https://docs.storageos.com/docs/prerequisites/systemconfiguration/"},
+		// results of several analyzers can share a title
+		{IsFail: true, Title: "Kernel Modules", Message: "The target_core_user kernel module is not installed on node-b & node-c."},
+		{IsFail: true, Title: "Kernel Modules", Message: "The configfs kernel module is not installed on node-c."},
+		// results that are neither are left out
+		{Title: "Unknown", Message: "no outcome matched"},
+	}
+
+	tests := []struct {
+		format string
+		golden string
+	}{
+		{format: "yaml", golden: "results.yaml"},
+		{format: "junit", golden: "results.junit.xml"},
+		{format: "sarif", golden: "results.sarif.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			if err := showStdoutResults(buf, tt.format, "storageos-preflight", filepath.Join("testdata", "preflight.yaml"), results); err != nil {
+				t.Fatalf("showStdoutResults() error = %v", err)
+			}
+
+			golden := filepath.Join("testdata", tt.golden)
+			if *update {
+				if err := ioutil.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != string(want) {
+				t.Errorf("showStdoutResults(%s) =\n%s\nwant\n%s", tt.format, got, want)
+			}
+		})
+	}
+
+	if err := showStdoutResults(&bytes.Buffer{}, "csv", "storageos-preflight", "", results); err == nil {
+		t.Error("showStdoutResults() of an unknown format succeeded")
+	}
+}
@@ -0,0 +1,128 @@
+// Package bundlearchive reads the files of a support bundle straight from its
+// tar.gz, without extracting it.
+package bundlearchive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// versionFilename is the first file of a support bundle archive.
+const versionFilename = "version.yaml"
+
+// topLevelFolderPrefix starts the name of the folder troubleshoot archives
+// bundles in.
+const topLevelFolderPrefix = "support-bundle"
+
+// ErrStop is returned by a WalkFunc to stop walking without an error.
+var ErrStop = errors.New("stop walking the archive")
+
+// File is a regular file in a support bundle archive. Name is relative to the
+// root of the bundle, with slash separators.
+type File struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
+
+// WalkFunc is called for each file in an archive. r reads the contents of the
+// file and is only valid until WalkFunc returns.
+type WalkFunc func(f File, r io.Reader) error
+
+// Walk calls fn for each regular file in the support bundle archive at
+// filename, in archive order. Bundles archived with a top-level folder, as
+// troubleshoot does, are read as if they were not.
+func Walk(filename string, fn WalkFunc) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return errors.Wrap(err, "open support bundle")
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return errors.Wrap(err, "support bundle is not a tar.gz")
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	prefix := ""
+	first := true
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "read support bundle")
+		}
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+			continue
+		}
+
+		name := path.Clean(strings.TrimPrefix(header.Name, "./"))
+		if first {
+			prefix = topLevelFolder(name)
+			first = false
+		}
+
+		err = fn(File{Name: strings.TrimPrefix(name, prefix), Size: header.Size, ModTime: header.ModTime}, tr)
+		if err == ErrStop {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// topLevelFolder returns the folder the files of a bundle are archived in,
+// with a trailing slash, or "" if they are at the root. name is the first
+// file in the archive.
+func topLevelFolder(name string) string {
+	i := strings.Index(name, "/")
+	if i < 0 {
+		return ""
+	}
+	dir := name[:i+1]
+	if name[i+1:] == versionFilename || strings.HasPrefix(dir, topLevelFolderPrefix) {
+		return dir
+	}
+	return ""
+}
+
+// ReadFile returns the contents of the file name in the archive at filename.
+// The cause of the error is os.ErrNotExist if there is no such file.
+func ReadFile(filename string, name string) ([]byte, error) {
+	name = path.Clean(strings.TrimPrefix(name, "/"))
+
+	var contents []byte
+	found := false
+	err := Walk(filename, func(f File, r io.Reader) error {
+		if f.Name != name {
+			return nil
+		}
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			return errors.Wrapf(err, "read %s", name)
+		}
+		contents = b
+		found = true
+		return ErrStop
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errors.Wrapf(os.ErrNotExist, "%s is not in the support bundle", name)
+	}
+	return contents, nil
+}
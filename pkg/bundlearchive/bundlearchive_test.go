@@ -0,0 +1,217 @@
+package bundlearchive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+// writeArchive writes a tar.gz of files, given as name and contents pairs.
+// Names ending in a slash are written as directories.
+func writeArchive(t *testing.T, filename string, files [][2]string) {
+	t.Helper()
+
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for _, file := range files {
+		header := &tar.Header{Name: file[0], Mode: 0644, Size: int64(len(file[1])), Typeflag: tar.TypeReg}
+		if file[0][len(file[0])-1] == '/' {
+			header = &tar.Header{Name: file[0], Mode: 0755, Typeflag: tar.TypeDir}
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(file[1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWalk(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "bundlearchive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	tests := []struct {
+		name  string
+		files [][2]string
+		want  []string
+	}{
+		{
+			name: "troubleshoot folder",
+			files: [][2]string{
+				{"support-bundle-2020-06-01T10_00_00/", ""},
+				{"support-bundle-2020-06-01T10_00_00/version.yaml", "v"},
+				{"support-bundle-2020-06-01T10_00_00/cluster-resources/nodes.json", "[]"},
+			},
+			want: []string{"version.yaml", "cluster-resources/nodes.json"},
+		},
+		{
+			name: "renamed folder",
+			files: [][2]string{
+				{"bundle/version.yaml", "v"},
+				{"bundle/storageos/logs/a.log", "l"},
+			},
+			want: []string{"version.yaml", "storageos/logs/a.log"},
+		},
+		{
+			name: "support-bundle folder without version.yaml first",
+			files: [][2]string{
+				{"support-bundle/cluster-resources/nodes.json", "[]"},
+				{"support-bundle/version.yaml", "v"},
+			},
+			want: []string{"cluster-resources/nodes.json", "version.yaml"},
+		},
+		{
+			name: "root",
+			files: [][2]string{
+				{"version.yaml", "v"},
+				{"cluster-resources/nodes.json", "[]"},
+			},
+			want: []string{"version.yaml", "cluster-resources/nodes.json"},
+		},
+		{
+			name: "root with dot slash",
+			files: [][2]string{
+				{"./", ""},
+				{"./version.yaml", "v"},
+				{"./cluster-resources/nodes.json", "[]"},
+			},
+			want: []string{"version.yaml", "cluster-resources/nodes.json"},
+		},
+		{
+			name: "root starting in a folder",
+			files: [][2]string{
+				{"cluster-resources/nodes.json", "[]"},
+				{"version.yaml", "v"},
+			},
+			want: []string{"cluster-resources/nodes.json", "version.yaml"},
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(tmp, string(rune('a'+i))+".tar.gz")
+			writeArchive(t, filename, tt.files)
+
+			got := []string{}
+			err := Walk(filename, func(f File, r io.Reader) error {
+				b, err := ioutil.ReadAll(r)
+				if err != nil {
+					return err
+				}
+				if int64(len(b)) != f.Size {
+					t.Errorf("%s: read %d bytes, want %d", f.Name, len(b), f.Size)
+				}
+				got = append(got, f.Name)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Walk() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Walk() files = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWalkStop(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "bundlearchive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	filename := filepath.Join(tmp, "bundle.tar.gz")
+	writeArchive(t, filename, [][2]string{{"version.yaml", "v"}, {"a", "a"}, {"b", "b"}})
+
+	n := 0
+	err = Walk(filename, func(f File, r io.Reader) error {
+		n++
+		return ErrStop
+	})
+	if err != nil || n != 1 {
+		t.Errorf("Walk() with ErrStop = %v after %d files, want nil after 1", err, n)
+	}
+
+	want := errors.New("failed")
+	if err := Walk(filename, func(f File, r io.Reader) error { return want }); err != want {
+		t.Errorf("Walk() error = %v, want %v", err, want)
+	}
+
+	if err := Walk(filepath.Join(tmp, "missing.tar.gz"), func(f File, r io.Reader) error { return nil }); err == nil {
+		t.Error("Walk() of a missing bundle succeeded")
+	}
+	notGzip := filepath.Join(tmp, "bundle.txt")
+	if err := ioutil.WriteFile(notGzip, []byte("not a bundle"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Walk(notGzip, func(f File, r io.Reader) error { return nil }); err == nil {
+		t.Error("Walk() of a file that is not a tar.gz succeeded")
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "bundlearchive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	filename := filepath.Join(tmp, "bundle.tar.gz")
+	writeArchive(t, filename, [][2]string{
+		{"support-bundle/version.yaml", "version"},
+		{"support-bundle/cluster-resources/nodes.json", "nodes"},
+	})
+
+	tests := []struct {
+		name     string
+		file     string
+		want     string
+		notExist bool
+	}{
+		{name: "file", file: "cluster-resources/nodes.json", want: "nodes"},
+		{name: "leading slash", file: "/version.yaml", want: "version"},
+		{name: "unclean", file: "cluster-resources/../version.yaml", want: "version"},
+		{name: "with top-level folder", file: "support-bundle/version.yaml", notExist: true},
+		{name: "directory", file: "cluster-resources", notExist: true},
+		{name: "missing", file: "storageos/logs/a.log", notExist: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadFile(filename, tt.file)
+			if tt.notExist {
+				if !os.IsNotExist(errors.Cause(err)) {
+					t.Errorf("ReadFile() error = %v, want not exist", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadFile() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("ReadFile() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package bundlearchive
+
+import (
+	"encoding/json"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// ClusterResourcesDir holds the objects collected by the clusterResources
+// collector.
+const ClusterResourcesDir = "cluster-resources"
+
+// ResourceKind returns the kind of the objects in a file of cluster-resources:
+// cluster-resources/<kind>.json or cluster-resources/<kind>/<namespace>.json.
+func ResourceKind(name string) (string, bool) {
+	if path.Ext(name) != ".json" || !strings.HasPrefix(name, ClusterResourcesDir+"/") {
+		return "", false
+	}
+
+	parts := strings.Split(strings.TrimPrefix(name, ClusterResourcesDir+"/"), "/")
+	switch len(parts) {
+	case 1:
+		return strings.TrimSuffix(parts[0], ".json"), true
+	case 2:
+		return parts[0], true
+	}
+	return "", false
+}
+
+// ObjectSummary is the columns of an object in a resource listing.
+type ObjectSummary struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	// Created is the creation time in RFC 3339, or "" if it is not set.
+	Created string `json:"created"`
+}
+
+// Summarize returns the summary of an object.
+func Summarize(obj map[string]interface{}) ObjectSummary {
+	u := unstructured.Unstructured{Object: obj}
+	summary := ObjectSummary{
+		Namespace: u.GetNamespace(),
+		Name:      u.GetName(),
+		Status:    ObjectStatus(obj),
+	}
+	if t := u.GetCreationTimestamp(); !t.IsZero() {
+		summary.Created = t.UTC().Format(time.RFC3339)
+	}
+	return summary
+}
+
+// SortSummaries sorts summaries by namespace, then name.
+func SortSummaries(summaries []ObjectSummary) {
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Namespace != summaries[j].Namespace {
+			return summaries[i].Namespace < summaries[j].Namespace
+		}
+		return summaries[i].Name < summaries[j].Name
+	})
+}
+
+// ObjectStatus returns the phase of an object, or its Ready condition.
+func ObjectStatus(obj map[string]interface{}) string {
+	if phase, found, _ := unstructured.NestedString(obj, "status", "phase"); found {
+		return phase
+	}
+
+	conditions, _, _ := unstructured.NestedSlice(obj, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if ok && condition["type"] == "Ready" {
+			if condition["status"] == "True" {
+				return "Ready"
+			}
+			return "NotReady"
+		}
+	}
+	return ""
+}
+
+// DecodeObjects decodes the objects in a file of cluster-resources, which
+// holds either a list of objects or a Kubernetes List.
+func DecodeObjects(b []byte) ([]map[string]interface{}, error) {
+	items := []map[string]interface{}{}
+	if err := json.Unmarshal(b, &items); err == nil {
+		return items, nil
+	}
+
+	list := struct {
+		Items []map[string]interface{} `json:"items"`
+	}{}
+	if err := json.Unmarshal(b, &list); err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
@@ -0,0 +1,81 @@
+package bundlearchive
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResourceKind(t *testing.T) {
+	tests := []struct {
+		name   string
+		want   string
+		wantOK bool
+	}{
+		{name: "cluster-resources/nodes.json", want: "nodes", wantOK: true},
+		{name: "cluster-resources/pods/storageos.json", want: "pods", wantOK: true},
+		{name: "cluster-resources/custom-resources/a/b.json", wantOK: false},
+		{name: "cluster-resources/pods/logs/a.log", wantOK: false},
+		{name: "cluster-resources/nodes.yaml", wantOK: false},
+		{name: "storageos/nodes.json", wantOK: false},
+		{name: "cluster-resources.json", wantOK: false},
+	}
+	for _, tt := range tests {
+		got, ok := ResourceKind(tt.name)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("ResourceKind(%q) = %q, %v, want %q, %v", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestDecodeObjects(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:  "list of objects",
+			input: `[{"metadata": {"name": "a"}}, {"metadata": {"name": "b"}}]`,
+			want:  []string{"a", "b"},
+		},
+		{
+			name:  "Kubernetes List",
+			input: `{"kind": "NodeList", "items": [{"metadata": {"name": "a"}}]}`,
+			want:  []string{"a"},
+		},
+		{
+			name:  "empty list",
+			input: `[]`,
+			want:  []string{},
+		},
+		{
+			name:  "List without items",
+			input: `{"kind": "PodList"}`,
+			want:  []string{},
+		},
+		{
+			name:    "not JSON",
+			input:   `pods "a" is forbidden`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects, err := DecodeObjects([]byte(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecodeObjects() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got := []string{}
+			for _, obj := range objects {
+				got = append(got, Summarize(obj).Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DecodeObjects() names = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
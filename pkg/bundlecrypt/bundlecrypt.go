@@ -0,0 +1,213 @@
+// Package bundlecrypt encrypts, decrypts, signs and verifies support bundles
+// with OpenPGP keys, so that bundles can be shared without exposing what was
+// collected.
+package bundlecrypt
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+
+	// openpgp falls back to RIPEMD160 for keys without hash preferences
+	_ "golang.org/x/crypto/ripemd160"
+)
+
+// Extension is appended to the name of an encrypted support bundle.
+const Extension = ".gpg"
+
+// PassphraseFunc returns the passphrase for an encrypted private key. It is
+// only called if the key is encrypted.
+type PassphraseFunc func() ([]byte, error)
+
+// EncryptFile encrypts inputPath to outputPath for every recipient in the
+// OpenPGP public key file at publicKeyPath. The key file may be armored or
+// binary.
+func EncryptFile(inputPath string, outputPath string, publicKeyPath string) error {
+	recipients, err := readKeyRing(publicKeyPath)
+	if err != nil {
+		return errors.Wrap(err, "read public key")
+	}
+
+	in, err := os.Open(inputPath)
+	if err != nil {
+		return errors.Wrap(err, "open input file")
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(outputPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.Wrap(err, "create output file")
+	}
+
+	hints := &openpgp.FileHints{
+		IsBinary: true,
+		FileName: filepath.Base(inputPath),
+	}
+	err = encrypt(out, in, recipients, hints)
+	if closeErr := out.Close(); err == nil && closeErr != nil {
+		err = errors.Wrap(closeErr, "close output file")
+	}
+	if err != nil {
+		// a partly encrypted bundle is of no use
+		os.Remove(outputPath)
+		return err
+	}
+	return nil
+}
+
+func encrypt(out io.Writer, in io.Reader, recipients openpgp.EntityList, hints *openpgp.FileHints) error {
+	w, err := openpgp.Encrypt(out, recipients, nil, hints, nil)
+	if err != nil {
+		return errors.Wrap(err, "start encryption")
+	}
+
+	if _, err := io.Copy(w, in); err != nil {
+		return errors.Wrap(err, "encrypt")
+	}
+
+	return errors.Wrap(w.Close(), "finish encryption")
+}
+
+// DecryptFile decrypts inputPath to outputPath with the OpenPGP private key
+// file at privateKeyPath.
+func DecryptFile(inputPath string, outputPath string, privateKeyPath string, passphrase PassphraseFunc) error {
+	keyRing, err := readKeyRing(privateKeyPath)
+	if err != nil {
+		return errors.Wrap(err, "read private key")
+	}
+
+	in, err := os.Open(inputPath)
+	if err != nil {
+		return errors.Wrap(err, "open input file")
+	}
+	defer in.Close()
+
+	r, err := unarmor(in)
+	if err != nil {
+		return err
+	}
+
+	prompted := false
+	prompt := func(keys []openpgp.Key, symmetric bool) ([]byte, error) {
+		// openpgp keeps calling the prompt until a key is decrypted, so only
+		// ask once
+		if prompted || passphrase == nil {
+			return nil, errors.New("private key is encrypted")
+		}
+		prompted = true
+
+		p, err := passphrase()
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range keys {
+			if err := k.PrivateKey.Decrypt(p); err != nil {
+				return nil, errors.Wrap(err, "decrypt private key")
+			}
+		}
+		return nil, nil
+	}
+
+	md, err := openpgp.ReadMessage(r, keyRing, prompt, nil)
+	if err != nil {
+		return errors.Wrap(err, "read encrypted message")
+	}
+
+	out, err := os.OpenFile(outputPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.Wrap(err, "create output file")
+	}
+
+	err = decrypt(out, md)
+	if closeErr := out.Close(); err == nil && closeErr != nil {
+		err = errors.Wrap(closeErr, "close output file")
+	}
+	if err != nil {
+		// a partly decrypted or tampered with bundle must not be used, and
+		// would stop the next attempt from creating outputPath
+		os.Remove(outputPath)
+		return err
+	}
+	return nil
+}
+
+func decrypt(out io.Writer, md *openpgp.MessageDetails) error {
+	if _, err := io.Copy(out, md.UnverifiedBody); err != nil {
+		return errors.Wrap(err, "decrypt")
+	}
+
+	// the integrity check only happens once the body has been read
+	if md.SignatureError != nil {
+		return errors.Wrap(md.SignatureError, "verify integrity")
+	}
+	return nil
+}
+
+// IsEncrypted returns true if the file at path is an OpenPGP message rather
+// than a plain gzipped archive.
+func IsEncrypted(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	header := make([]byte, len(armorHeader))
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return false, err
+	}
+	header = header[:n]
+
+	if bytes.HasPrefix(header, []byte(armorHeader)) {
+		return true, nil
+	}
+	if len(header) >= 2 && header[0] == 0x1f && header[1] == 0x8b {
+		return false, nil
+	}
+
+	// every OpenPGP packet header has the high bit set
+	return len(header) > 0 && header[0]&0x80 != 0, nil
+}
+
+const armorHeader = "-----BEGIN PGP"
+
+func readKeyRing(path string) (openpgp.EntityList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r, err := unarmor(f)
+	if err != nil {
+		return nil, err
+	}
+
+	return openpgp.ReadKeyRing(r)
+}
+
+// unarmor returns a reader for the binary contents of r, whether or not r is
+// armored.
+func unarmor(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(len(armorHeader))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if !bytes.Equal(header, []byte(armorHeader)) {
+		return br, nil
+	}
+
+	block, err := armor.Decode(br)
+	if err != nil {
+		return nil, errors.Wrap(err, "decode armor")
+	}
+	return block.Body, nil
+}
@@ -0,0 +1,170 @@
+package bundlecrypt
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/openpgp"
+)
+
+// writeTestKeys writes a new key pair to dir and returns the paths of the
+// public and private key files.
+func writeTestKeys(t *testing.T, dir string) (string, string) {
+	t.Helper()
+
+	entity, err := openpgp.NewEntity("support", "", "support@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	public := &bytes.Buffer{}
+	if err := entity.Serialize(public); err != nil {
+		t.Fatal(err)
+	}
+	private := &bytes.Buffer{}
+	if err := entity.SerializePrivate(private, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	publicPath := filepath.Join(dir, "public.gpg")
+	privatePath := filepath.Join(dir, "private.gpg")
+	if err := ioutil.WriteFile(publicPath, public.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(privatePath, private.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return publicPath, privatePath
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bundlecrypt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	publicKey, privateKey := writeTestKeys(t, dir)
+	data := bytes.Repeat([]byte("support bundle\n"), 1000)
+	plainPath := filepath.Join(dir, "bundle.tar.gz")
+	if err := ioutil.WriteFile(plainPath, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	encryptedPath := plainPath + Extension
+	if err := EncryptFile(plainPath, encryptedPath, publicKey); err != nil {
+		t.Fatalf("EncryptFile() error = %v", err)
+	}
+	if encrypted, err := IsEncrypted(encryptedPath); err != nil || !encrypted {
+		t.Errorf("IsEncrypted(encrypted) = %v, %v, want true", encrypted, err)
+	}
+	if encrypted, err := IsEncrypted(plainPath); err != nil || encrypted {
+		t.Errorf("IsEncrypted(plain) = %v, %v, want false", encrypted, err)
+	}
+
+	decryptedPath := filepath.Join(dir, "decrypted.tar.gz")
+	if err := DecryptFile(encryptedPath, decryptedPath, privateKey, nil); err != nil {
+		t.Fatalf("DecryptFile() error = %v", err)
+	}
+	got, err := ioutil.ReadFile(decryptedPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Error("decrypted bundle differs from the original")
+	}
+}
+
+func TestDecryptTampered(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bundlecrypt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	publicKey, privateKey := writeTestKeys(t, dir)
+	plainPath := filepath.Join(dir, "bundle.tar.gz")
+	if err := ioutil.WriteFile(plainPath, bytes.Repeat([]byte("support bundle\n"), 1000), 0600); err != nil {
+		t.Fatal(err)
+	}
+	encryptedPath := plainPath + Extension
+	if err := EncryptFile(plainPath, encryptedPath, publicKey); err != nil {
+		t.Fatalf("EncryptFile() error = %v", err)
+	}
+
+	encrypted, err := ioutil.ReadFile(encryptedPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// inside the encrypted data, before the integrity check
+	encrypted[len(encrypted)-40] ^= 0xff
+	if err := ioutil.WriteFile(encryptedPath, encrypted, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	decryptedPath := filepath.Join(dir, "decrypted.tar.gz")
+	for attempt := 0; attempt < 2; attempt++ {
+		err := DecryptFile(encryptedPath, decryptedPath, privateKey, nil)
+		if err == nil {
+			t.Fatal("DecryptFile() of a tampered bundle succeeded")
+		}
+		if _, err := os.Stat(decryptedPath); !os.IsNotExist(err) {
+			t.Fatalf("DecryptFile() left %s behind", decryptedPath)
+		}
+	}
+}
+
+func TestEncryptBadKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bundlecrypt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	plainPath := filepath.Join(dir, "bundle.tar.gz")
+	if err := ioutil.WriteFile(plainPath, []byte("support bundle"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := EncryptFile(plainPath, plainPath+Extension, filepath.Join(dir, "missing.asc")); err == nil {
+		t.Fatal("EncryptFile() with a missing key succeeded")
+	}
+	if _, err := os.Stat(plainPath + Extension); !os.IsNotExist(err) {
+		t.Error("EncryptFile() left an encrypted file behind")
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bundlecrypt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	publicKey, privateKey := writeTestKeys(t, dir)
+	path := filepath.Join(dir, "bundle.tar.gz")
+	if err := ioutil.WriteFile(path, []byte("support bundle"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	sigPath := path + SignatureExtension
+	if err := SignFile(path, sigPath, privateKey, nil); err != nil {
+		t.Fatalf("SignFile() error = %v", err)
+	}
+	signer, err := VerifyFile(path, sigPath, publicKey)
+	if err != nil {
+		t.Fatalf("VerifyFile() error = %v", err)
+	}
+	if name := SignerName(signer); name != "support <support@example.com>" {
+		t.Errorf("SignerName() = %q", name)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("changed bundle"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyFile(path, sigPath, publicKey); err == nil {
+		t.Error("VerifyFile() of a changed bundle succeeded")
+	}
+}
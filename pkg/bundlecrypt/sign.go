@@ -0,0 +1,117 @@
+package bundlecrypt
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/openpgp"
+)
+
+// SignatureExtension is appended to the name of a bundle to get the name of
+// its detached signature.
+const SignatureExtension = ".asc"
+
+// SignFile writes an armored detached signature for path to sigPath, using
+// the OpenPGP private key file at privateKeyPath.
+func SignFile(path string, sigPath string, privateKeyPath string, passphrase PassphraseFunc) error {
+	keyRing, err := readKeyRing(privateKeyPath)
+	if err != nil {
+		return errors.Wrap(err, "read private key")
+	}
+
+	signer, err := signingEntity(keyRing, passphrase)
+	if err != nil {
+		return err
+	}
+
+	in, err := os.Open(path)
+	if err != nil {
+		return errors.Wrap(err, "open file")
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(sigPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.Wrap(err, "create signature file")
+	}
+	defer out.Close()
+
+	if err := openpgp.ArmoredDetachSign(out, signer, in, nil); err != nil {
+		return errors.Wrap(err, "sign")
+	}
+
+	return out.Close()
+}
+
+// VerifyFile checks the detached signature at sigPath against path with the
+// OpenPGP public key file at publicKeyPath, and returns the signer.
+func VerifyFile(path string, sigPath string, publicKeyPath string) (*openpgp.Entity, error) {
+	keyRing, err := readKeyRing(publicKeyPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "read public key")
+	}
+
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "open file")
+	}
+	defer in.Close()
+
+	sig, err := os.Open(sigPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "open signature file")
+	}
+	defer sig.Close()
+
+	sigReader, err := unarmor(sig)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := openpgp.CheckDetachedSignature(keyRing, in, sigReader)
+	if err != nil {
+		return nil, errors.Wrap(err, "bad signature")
+	}
+
+	return signer, nil
+}
+
+// SignerName returns a printable name for the signer of a bundle.
+func SignerName(signer *openpgp.Entity) string {
+	for name := range signer.Identities {
+		return name
+	}
+	return signer.PrimaryKey.KeyIdString()
+}
+
+func signingEntity(keyRing openpgp.EntityList, passphrase PassphraseFunc) (*openpgp.Entity, error) {
+	for _, entity := range keyRing {
+		if entity.PrivateKey == nil {
+			continue
+		}
+
+		if entity.PrivateKey.Encrypted {
+			if passphrase == nil {
+				return nil, errors.New("private key is encrypted")
+			}
+			p, err := passphrase()
+			if err != nil {
+				return nil, err
+			}
+			if err := entity.PrivateKey.Decrypt(p); err != nil {
+				return nil, errors.Wrap(err, "decrypt private key")
+			}
+			for _, subkey := range entity.Subkeys {
+				if subkey.PrivateKey != nil && subkey.PrivateKey.Encrypted {
+					if err := subkey.PrivateKey.Decrypt(p); err != nil {
+						return nil, errors.Wrap(err, "decrypt private subkey")
+					}
+				}
+			}
+		}
+
+		return entity, nil
+	}
+
+	return nil, errors.New("no private key found")
+}
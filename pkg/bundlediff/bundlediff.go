@@ -0,0 +1,186 @@
+// Package bundlediff compares two extracted support bundles, to show what
+// changed in a cluster between them.
+package bundlediff
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	analyzer "github.com/replicatedhq/troubleshoot/pkg/analyze"
+)
+
+const clusterVersionFile = "cluster-info/cluster_version.json"
+
+// Bundle is an extracted support bundle and the results of analyzing it.
+type Bundle struct {
+	Dir     string
+	Results []*analyzer.AnalyzeResult
+}
+
+// Report is what changed from one bundle to the other. Empty fields mean no
+// change.
+type Report struct {
+	ClusterVersion    *Change         `json:"clusterVersion,omitempty"`
+	Nodes             []ResourceDiff  `json:"nodes,omitempty"`
+	Deployments       []ResourceDiff  `json:"deployments,omitempty"`
+	DaemonSets        []ResourceDiff  `json:"daemonSets,omitempty"`
+	StorageOSClusters []ResourceDiff  `json:"storageosClusters,omitempty"`
+	Regressions       []OutcomeChange `json:"regressions,omitempty"`
+	NewLogErrors      []LogSignature  `json:"newLogErrors,omitempty"`
+}
+
+// Empty returns true if the report has no changes.
+func (r *Report) Empty() bool {
+	return r.ClusterVersion == nil && len(r.Nodes) == 0 && len(r.Deployments) == 0 && len(r.DaemonSets) == 0 &&
+		len(r.StorageOSClusters) == 0 && len(r.Regressions) == 0 && len(r.NewLogErrors) == 0
+}
+
+// Change is a value that differs between the bundles.
+type Change struct {
+	Field  string `json:"field,omitempty"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+type DiffStatus string
+
+const (
+	StatusAdded   DiffStatus = "added"
+	StatusRemoved DiffStatus = "removed"
+	StatusChanged DiffStatus = "changed"
+)
+
+// ResourceDiff is an object that was added, removed or changed.
+type ResourceDiff struct {
+	Name    string     `json:"name"`
+	Status  DiffStatus `json:"status"`
+	Changes []Change   `json:"changes,omitempty"`
+}
+
+// OutcomeChange is an analyzer whose outcome got worse.
+type OutcomeChange struct {
+	Title   string `json:"title"`
+	Before  string `json:"before"`
+	After   string `json:"after"`
+	Message string `json:"message"`
+}
+
+// Diff compares bundle a, the earlier one, with b.
+func Diff(a, b Bundle) (*Report, error) {
+	report := &Report{}
+
+	versionA, err := clusterVersion(a.Dir)
+	if err != nil {
+		return nil, err
+	}
+	versionB, err := clusterVersion(b.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if versionA != versionB {
+		report.ClusterVersion = &Change{Before: versionA, After: versionB}
+	}
+
+	if report.Nodes, err = nodes.diff(a.Dir, b.Dir); err != nil {
+		return nil, err
+	}
+	if report.Deployments, err = deployments.diff(a.Dir, b.Dir); err != nil {
+		return nil, err
+	}
+	if report.DaemonSets, err = daemonSets.diff(a.Dir, b.Dir); err != nil {
+		return nil, err
+	}
+	if report.StorageOSClusters, err = storageosClusters.diff(a.Dir, b.Dir); err != nil {
+		return nil, err
+	}
+
+	report.Regressions = regressions(a.Results, b.Results)
+
+	report.NewLogErrors, err = newLogErrors(a.Dir, b.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// clusterVersion returns the Kubernetes version in a bundle, or "" if it was
+// not collected.
+func clusterVersion(dir string) (string, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, clusterVersionFile))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "read cluster version")
+	}
+
+	version := struct {
+		String string `json:"string"`
+	}{}
+	if err := json.Unmarshal(b, &version); err != nil {
+		return "", errors.Wrap(err, "parse cluster version")
+	}
+	return version.String, nil
+}
+
+var outcomeSeverity = map[string]int{
+	"":     0,
+	"pass": 1,
+	"warn": 2,
+	"fail": 3,
+}
+
+// regressions returns the analyzers whose worst outcome is worse in after
+// than in before, including those that only fail in after.
+func regressions(before, after []*analyzer.AnalyzeResult) []OutcomeChange {
+	worstBefore := worstOutcomes(before)
+	worstAfter := worstOutcomes(after)
+
+	changes := []OutcomeChange{}
+	for _, result := range after {
+		outcome := outcomeOf(result)
+		if worstAfter[result.Title] != outcome || outcome == "pass" {
+			continue
+		}
+		previous := worstBefore[result.Title]
+		if outcomeSeverity[outcome] <= outcomeSeverity[previous] {
+			continue
+		}
+		changes = append(changes, OutcomeChange{
+			Title:   result.Title,
+			Before:  previous,
+			After:   outcome,
+			Message: result.Message,
+		})
+		// report each analyzer once, with its first message
+		worstBefore[result.Title] = outcome
+	}
+	return changes
+}
+
+func worstOutcomes(results []*analyzer.AnalyzeResult) map[string]string {
+	worst := map[string]string{}
+	for _, result := range results {
+		outcome := outcomeOf(result)
+		if outcomeSeverity[outcome] > outcomeSeverity[worst[result.Title]] {
+			worst[result.Title] = outcome
+		}
+	}
+	return worst
+}
+
+func outcomeOf(result *analyzer.AnalyzeResult) string {
+	switch {
+	case result.IsFail:
+		return "fail"
+	case result.IsWarn:
+		return "warn"
+	case result.IsPass:
+		return "pass"
+	}
+	return ""
+}
@@ -0,0 +1,37 @@
+package bundlediff
+
+import (
+	"reflect"
+	"testing"
+
+	analyzer "github.com/replicatedhq/troubleshoot/pkg/analyze"
+)
+
+func TestRegressions(t *testing.T) {
+	before := []*analyzer.AnalyzeResult{
+		{Title: "Kubernetes Version", IsPass: true},
+		{Title: "Nodes", IsWarn: true, Message: "1 node is not ready"},
+		{Title: "Etcd", IsFail: true, Message: "etcd is unreachable"},
+		{Title: "CSI Driver", IsPass: true},
+	}
+	after := []*analyzer.AnalyzeResult{
+		{Title: "Kubernetes Version", IsWarn: true, Message: "Kubernetes is out of support"},
+		// an analyzer with several results is compared by its worst outcome
+		{Title: "Nodes", IsWarn: true, Message: "node-a is not ready"},
+		{Title: "Nodes", IsFail: true, Message: "no nodes are ready"},
+		{Title: "Nodes", IsFail: true, Message: "node-b is cordoned"},
+		{Title: "Etcd", IsWarn: true, Message: "etcd is slow"},
+		{Title: "CSI Driver", IsPass: true},
+		{Title: "Hugepages", IsFail: true, Message: "hugepages are not available"},
+	}
+
+	got := regressions(before, after)
+	want := []OutcomeChange{
+		{Title: "Kubernetes Version", Before: "pass", After: "warn", Message: "Kubernetes is out of support"},
+		{Title: "Nodes", Before: "warn", After: "fail", Message: "no nodes are ready"},
+		{Title: "Hugepages", Before: "", After: "fail", Message: "hugepages are not available"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("regressions() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,150 @@
+package bundlediff
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/croomes/kubectl-plugin/pkg/timeline"
+)
+
+// maxLogSignatures is how many new log errors a report lists, most frequent
+// first.
+const maxLogSignatures = 50
+
+// maxSignatureLength truncates long log lines, which rarely differ past it.
+const maxSignatureLength = 200
+
+// LogSignature is an error logged in a bundle, with the values that change
+// from one occurrence to the next replaced by placeholders.
+type LogSignature struct {
+	Signature string   `json:"signature"`
+	Count     int      `json:"count"`
+	Files     []string `json:"files"`
+}
+
+var (
+	errorLine = regexp.MustCompile(`(?i)(\blevel=(error|fatal|panic)\b|"level":"(error|fatal|panic)"|^[EF]\d{4} |\berror\b|\bpanic\b)`)
+	logMsg    = regexp.MustCompile(`\bmsg="((?:[^"\\]|\\.)*)"|"msg":"((?:[^"\\]|\\.)*)"`)
+
+	timestamp = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?`)
+	klog      = regexp.MustCompile(`^[IWEF]\d{4} \d{2}:\d{2}:\d{2}\.\d+\s+\d+ [^\]]+\] `)
+	uuid      = regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)
+	ip        = regexp.MustCompile(`\b\d{1,3}(\.\d{1,3}){3}(:\d+)?\b`)
+	hex       = regexp.MustCompile(`(?i)\b(0x)?[0-9a-f]{12,}\b`)
+	number    = regexp.MustCompile(`\b\d+\b`)
+)
+
+// newLogErrors returns the error signatures in the logs of bundle b that are
+// not in the logs of bundle a.
+func newLogErrors(dirA, dirB string) ([]LogSignature, error) {
+	before, err := logErrors(dirA)
+	if err != nil {
+		return nil, err
+	}
+	after, err := logErrors(dirB)
+	if err != nil {
+		return nil, err
+	}
+
+	signatures := []LogSignature{}
+	for signature, s := range after {
+		if _, ok := before[signature]; !ok {
+			signatures = append(signatures, *s)
+		}
+	}
+	sort.Slice(signatures, func(i, j int) bool {
+		if signatures[i].Count != signatures[j].Count {
+			return signatures[i].Count > signatures[j].Count
+		}
+		return signatures[i].Signature < signatures[j].Signature
+	})
+	if len(signatures) > maxLogSignatures {
+		signatures = signatures[:maxLogSignatures]
+	}
+	return signatures, nil
+}
+
+// logErrors returns the error signatures in the *.log files of a bundle. The
+// timeline is skipped, since it repeats every line of the other logs.
+func logErrors(dir string) (map[string]*LogSignature, error) {
+	signatures := map[string]*LogSignature{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || filepath.Ext(path) != ".log" {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if rel == timeline.Filename {
+			return nil
+		}
+		return scanLog(path, filepath.ToSlash(rel), signatures)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "read logs")
+	}
+	return signatures, nil
+}
+
+func scanLog(path, name string, signatures map[string]*LogSignature) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !errorLine.MatchString(line) {
+			continue
+		}
+		signature := normalize(line)
+		if signature == "" {
+			continue
+		}
+
+		s, ok := signatures[signature]
+		if !ok {
+			s = &LogSignature{Signature: signature}
+			signatures[signature] = s
+		}
+		s.Count++
+		if len(s.Files) == 0 || s.Files[len(s.Files)-1] != name {
+			s.Files = append(s.Files, name)
+		}
+	}
+	return scanner.Err()
+}
+
+// normalize returns the message of a log line with timestamps, IDs, addresses
+// and numbers replaced, so that repeats of an error have the same signature.
+func normalize(line string) string {
+	if m := logMsg.FindStringSubmatch(line); m != nil {
+		line = m[1] + m[2]
+	}
+	line = klog.ReplaceAllString(line, "")
+	line = timestamp.ReplaceAllString(line, "<time>")
+	line = uuid.ReplaceAllString(line, "<id>")
+	line = ip.ReplaceAllString(line, "<ip>")
+	line = hex.ReplaceAllString(line, "<hex>")
+	line = number.ReplaceAllString(line, "<n>")
+	line = strings.TrimSpace(line)
+
+	if len(line) > maxSignatureLength {
+		line = line[:maxSignatureLength]
+	}
+	return line
+}
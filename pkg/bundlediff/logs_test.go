@@ -0,0 +1,144 @@
+package bundlediff
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeBundle writes files, keyed by their path in the bundle, to a new
+// directory.
+func writeBundle(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "bundlediff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestNewLogErrorsSkipsTimeline(t *testing.T) {
+	before := writeBundle(t, map[string]string{
+		"storageos/logs/node-a.log": "level=info msg=\"started\"\n",
+	})
+	defer os.RemoveAll(before)
+
+	line := "time=\"2020-06-01T10:00:00Z\" level=error msg=\"etcd connection refused\"\n"
+	after := writeBundle(t, map[string]string{
+		"storageos/logs/node-a.log": line,
+		"timeline.log":              "2020-06-01T10:00:00.000000Z [storageos/logs/node-a] " + line,
+	})
+	defer os.RemoveAll(after)
+
+	got, err := newLogErrors(before, after)
+	if err != nil {
+		t.Fatalf("newLogErrors() error = %v", err)
+	}
+	want := []LogSignature{{
+		Signature: "etcd connection refused",
+		Count:     1,
+		Files:     []string{"storageos/logs/node-a.log"},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("newLogErrors() = %+v, want %+v", got, want)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{
+			name: "logfmt",
+			line: `time="2020-06-01T10:00:00Z" level=error msg="failed to attach volume 3f2504e0-4f89-11d3-9a0c-0305e82c3301 on 10.0.0.12:5705" module=csi`,
+			want: "failed to attach volume <id> on <ip>",
+		},
+		{
+			name: "logfmt with escaped quotes",
+			line: `level=error msg="volume \"pvc-1\" not found"`,
+			want: `volume \"pvc-<n>\" not found`,
+		},
+		{
+			name: "json",
+			line: `{"level":"error","msg":"lost lease after 30s, retrying in 5 seconds","time":"2020-06-01T10:00:00Z"}`,
+			want: "lost lease after 30s, retrying in <n> seconds",
+		},
+		{
+			name: "klog",
+			line: `E0601 10:00:00.123456       1 controller.go:42] sync failed for pod storageos/node-7: error 500`,
+			want: "sync failed for pod storageos/node-<n>: error <n>",
+		},
+		{
+			name: "timestamp",
+			line: `2020-06-01 10:00:00.123+00:00 ERROR panic in handler`,
+			want: "<time> ERROR panic in handler",
+		},
+		{
+			name: "hex",
+			line: `error: checksum mismatch 0xdeadbeefcafe1234 != deadbeefcafe`,
+			want: "error: checksum mismatch <hex> != <hex>",
+		},
+		{
+			name: "empty message",
+			line: `level=error msg=""`,
+			want: "",
+		},
+		{
+			name: "long line",
+			line: "error: " + strings.Repeat("x", 300),
+			want: "error: " + strings.Repeat("x", maxSignatureLength-len("error: ")),
+		},
+	}
+	for _, tt := range tests {
+		if got := normalize(tt.line); got != tt.want {
+			t.Errorf("%s: normalize(%q) = %q, want %q", tt.name, tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestNewLogErrors(t *testing.T) {
+	before := writeBundle(t, map[string]string{
+		"storageos/logs/node-a.log": "level=error msg=\"etcd connection refused after 3 attempts\"\n",
+	})
+	defer os.RemoveAll(before)
+
+	after := writeBundle(t, map[string]string{
+		// the same error, with different numbers, is not new
+		"storageos/logs/node-a.log": "level=error msg=\"etcd connection refused after 5 attempts\"\n" +
+			"level=info msg=\"volume attached\"\n" +
+			"level=error msg=\"volume pvc-1 is degraded\"\n",
+		"storageos/logs/node-b.log": "level=error msg=\"volume pvc-2 is degraded\"\n" +
+			"level=error msg=\"volume pvc-3 is degraded\"\n" +
+			"E0601 10:00:00.123456       1 node.go:12] lost connection to 10.0.0.3:5705\n",
+		// only *.log files are searched
+		"storageos/node-b.txt": "level=error msg=\"disk full\"\n",
+	})
+	defer os.RemoveAll(after)
+
+	got, err := newLogErrors(before, after)
+	if err != nil {
+		t.Fatalf("newLogErrors() error = %v", err)
+	}
+	want := []LogSignature{
+		{Signature: "volume pvc-<n> is degraded", Count: 3, Files: []string{"storageos/logs/node-a.log", "storageos/logs/node-b.log"}},
+		{Signature: "lost connection to <ip>", Count: 1, Files: []string{"storageos/logs/node-b.log"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("newLogErrors() = %+v, want %+v", got, want)
+	}
+}
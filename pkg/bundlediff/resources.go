@@ -0,0 +1,250 @@
+package bundlediff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/croomes/kubectl-plugin/pkg/bundlearchive"
+	"github.com/croomes/kubectl-plugin/pkg/discovery"
+	"github.com/croomes/kubectl-plugin/pkg/storageos"
+)
+
+// resourceDiffer compares the objects of a resource by the fields that
+// matter when troubleshooting.
+type resourceDiffer struct {
+	// read returns the objects in a bundle by name.
+	read func(dir string) (map[string]map[string]interface{}, error)
+	// fields returns the compared fields of an object by field name.
+	fields func(obj map[string]interface{}) map[string]string
+}
+
+var (
+	nodes = resourceDiffer{
+		read:   readFiles("cluster-resources/nodes.json"),
+		fields: nodeFields,
+	}
+	deployments = resourceDiffer{
+		read:   readFiles("cluster-resources/deployments/*.json"),
+		fields: deploymentFields,
+	}
+	daemonSets = resourceDiffer{
+		read:   readFiles("cluster-resources/daemonsets/*.json"),
+		fields: daemonSetFields,
+	}
+	storageosClusters = resourceDiffer{
+		read:   readStorageOSClusters,
+		fields: storageosClusterFields,
+	}
+)
+
+func (rd resourceDiffer) diff(dirA, dirB string) ([]ResourceDiff, error) {
+	before, err := rd.read(dirA)
+	if err != nil {
+		return nil, err
+	}
+	after, err := rd.read(dirB)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for name := range before {
+		names = append(names, name)
+	}
+	for name := range after {
+		if _, ok := before[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	diffs := []ResourceDiff{}
+	for _, name := range names {
+		objA, inA := before[name]
+		objB, inB := after[name]
+		switch {
+		case !inA:
+			diffs = append(diffs, ResourceDiff{Name: name, Status: StatusAdded})
+		case !inB:
+			diffs = append(diffs, ResourceDiff{Name: name, Status: StatusRemoved})
+		default:
+			if changes := diffFields(rd.fields(objA), rd.fields(objB)); len(changes) > 0 {
+				diffs = append(diffs, ResourceDiff{Name: name, Status: StatusChanged, Changes: changes})
+			}
+		}
+	}
+	return diffs, nil
+}
+
+func diffFields(before, after map[string]string) []Change {
+	fields := []string{}
+	for field := range before {
+		fields = append(fields, field)
+	}
+	for field := range after {
+		if _, ok := before[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+
+	changes := []Change{}
+	for _, field := range fields {
+		if before[field] != after[field] {
+			changes = append(changes, Change{Field: field, Before: before[field], After: after[field]})
+		}
+	}
+	return changes
+}
+
+// readFiles returns a reader of the objects in the files matching pattern.
+func readFiles(pattern string) func(dir string) (map[string]map[string]interface{}, error) {
+	return func(dir string) (map[string]map[string]interface{}, error) {
+		filenames, err := filepath.Glob(filepath.Join(dir, filepath.FromSlash(pattern)))
+		if err != nil {
+			return nil, errors.Wrapf(err, "find %s", pattern)
+		}
+
+		objects := map[string]map[string]interface{}{}
+		for _, filename := range filenames {
+			b, err := ioutil.ReadFile(filename)
+			if err != nil {
+				return nil, errors.Wrapf(err, "read %s", filename)
+			}
+			items, err := bundlearchive.DecodeObjects(b)
+			if err != nil {
+				return nil, errors.Wrapf(err, "parse %s", filename)
+			}
+			for _, item := range items {
+				objects[objectName(item)] = item
+			}
+		}
+		return objects, nil
+	}
+}
+
+func readStorageOSClusters(dir string) (map[string]map[string]interface{}, error) {
+	filename := filepath.Join(dir, filepath.FromSlash(path.Join(storageos.Dir, discovery.StorageOSClusterResource.Resource+".json")))
+	b, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return map[string]map[string]interface{}{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "read storageosclusters")
+	}
+
+	resources := []storageos.Resource{}
+	if err := json.Unmarshal(b, &resources); err != nil {
+		return nil, errors.Wrap(err, "parse storageosclusters")
+	}
+
+	objects := map[string]map[string]interface{}{}
+	for _, r := range resources {
+		objects[objectName(r.Object)] = r.Object
+	}
+	return objects, nil
+}
+
+func objectName(obj map[string]interface{}) string {
+	u := unstructured.Unstructured{Object: obj}
+	if u.GetNamespace() == "" {
+		return u.GetName()
+	}
+	return u.GetNamespace() + "/" + u.GetName()
+}
+
+func nodeFields(node map[string]interface{}) map[string]string {
+	fields := map[string]string{}
+	for _, field := range []string{"kubeletVersion", "kernelVersion", "osImage", "containerRuntimeVersion"} {
+		fields[field] = nestedValue(node, "status", "nodeInfo", field)
+	}
+	fields["unschedulable"] = nestedValue(node, "spec", "unschedulable")
+
+	conditions, _, _ := unstructured.NestedSlice(node, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if ok && condition["type"] == "Ready" {
+			fields["ready"] = fmt.Sprintf("%v", condition["status"])
+		}
+	}
+	return fields
+}
+
+func deploymentFields(deployment map[string]interface{}) map[string]string {
+	fields := containerImages(deployment)
+	fields["replicas"] = nestedValue(deployment, "spec", "replicas")
+	fields["readyReplicas"] = nestedValue(deployment, "status", "readyReplicas")
+	return fields
+}
+
+func daemonSetFields(ds map[string]interface{}) map[string]string {
+	fields := containerImages(ds)
+	fields["desired"] = nestedValue(ds, "status", "desiredNumberScheduled")
+	fields["ready"] = nestedValue(ds, "status", "numberReady")
+	return fields
+}
+
+func storageosClusterFields(cluster map[string]interface{}) map[string]string {
+	fields := map[string]string{}
+	if spec, ok := cluster["spec"].(map[string]interface{}); ok {
+		flatten("spec", spec, fields)
+	}
+	fields["status.phase"] = nestedValue(cluster, "status", "phase")
+	return fields
+}
+
+func containerImages(obj map[string]interface{}) map[string]string {
+	fields := map[string]string{}
+	containers, _, _ := unstructured.NestedSlice(obj, "spec", "template", "spec", "containers")
+	for _, c := range containers {
+		container, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		fields[fmt.Sprintf("image[%v]", container["name"])] = fmt.Sprintf("%v", container["image"])
+	}
+	return fields
+}
+
+// nestedValue returns the value at fields as text, or "" if it is not set.
+func nestedValue(obj map[string]interface{}, fields ...string) string {
+	value, found, err := unstructured.NestedFieldNoCopy(obj, fields...)
+	if !found || err != nil || value == nil {
+		return ""
+	}
+	return valueString(value)
+}
+
+// flatten adds the leaves of m to fields, named by their dotted path.
+func flatten(prefix string, m map[string]interface{}, fields map[string]string) {
+	for k, v := range m {
+		name := prefix + "." + k
+		if child, ok := v.(map[string]interface{}); ok {
+			flatten(name, child, fields)
+			continue
+		}
+		fields[name] = valueString(v)
+	}
+}
+
+func valueString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case map[string]interface{}, []interface{}:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(b)
+	}
+	return fmt.Sprintf("%v", v)
+}
@@ -0,0 +1,101 @@
+package bundlediff
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestDiffFields(t *testing.T) {
+	before := map[string]string{"replicas": "3", "image[api]": "storageos/api:v2.3.0", "readyReplicas": "3"}
+	after := map[string]string{"replicas": "3", "image[api]": "storageos/api:v2.4.0", "image[sidecar]": "csi/sidecar:v1"}
+
+	got := diffFields(before, after)
+	want := []Change{
+		{Field: "image[api]", Before: "storageos/api:v2.3.0", After: "storageos/api:v2.4.0"},
+		{Field: "image[sidecar]", Before: "", After: "csi/sidecar:v1"},
+		{Field: "readyReplicas", Before: "3", After: ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diffFields() = %+v, want %+v", got, want)
+	}
+
+	if got := diffFields(before, before); len(got) != 0 {
+		t.Errorf("diffFields() of the same fields = %+v, want none", got)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	before := writeBundle(t, map[string]string{
+		"cluster-info/cluster_version.json": `{"string": "v1.19.3"}`,
+		"cluster-resources/nodes.json": `[
+			{"metadata": {"name": "node-a"}, "status": {"nodeInfo": {"kubeletVersion": "v1.19.3"}, "conditions": [{"type": "Ready", "status": "True"}]}},
+			{"metadata": {"name": "node-b"}, "status": {"nodeInfo": {"kubeletVersion": "v1.19.3"}}}
+		]`,
+		"cluster-resources/deployments/storageos.json": `{"items": [
+			{"metadata": {"name": "api-manager", "namespace": "storageos"}, "spec": {"replicas": 2, "template": {"spec": {"containers": [{"name": "api", "image": "storageos/api:v1"}]}}}, "status": {"readyReplicas": 2}}
+		]}`,
+		"storageos/storageosclusters.json": `[
+			{"object": {"metadata": {"name": "storageos", "namespace": "storageos"}, "spec": {"tlsEtcdSecretRefName": "etcd", "kvBackend": {"address": "etcd:2379"}}, "status": {"phase": "Running"}}}
+		]`,
+	})
+	defer os.RemoveAll(before)
+
+	after := writeBundle(t, map[string]string{
+		"cluster-info/cluster_version.json": `{"string": "v1.20.1"}`,
+		"cluster-resources/nodes.json": `[
+			{"metadata": {"name": "node-a"}, "status": {"nodeInfo": {"kubeletVersion": "v1.20.1"}, "conditions": [{"type": "Ready", "status": "False"}]}},
+			{"metadata": {"name": "node-c"}, "status": {"nodeInfo": {"kubeletVersion": "v1.20.1"}}}
+		]`,
+		"cluster-resources/deployments/storageos.json": `{"items": [
+			{"metadata": {"name": "api-manager", "namespace": "storageos"}, "spec": {"replicas": 2, "template": {"spec": {"containers": [{"name": "api", "image": "storageos/api:v1"}]}}}, "status": {"readyReplicas": 2}}
+		]}`,
+		"storageos/storageosclusters.json": `[
+			{"object": {"metadata": {"name": "storageos", "namespace": "storageos"}, "spec": {"tlsEtcdSecretRefName": "etcd", "kvBackend": {"address": "etcd-0:2379"}}, "status": {"phase": "Pending"}}}
+		]`,
+	})
+	defer os.RemoveAll(after)
+
+	report, err := Diff(Bundle{Dir: before}, Bundle{Dir: after})
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+
+	if want := (&Change{Before: "v1.19.3", After: "v1.20.1"}); !reflect.DeepEqual(report.ClusterVersion, want) {
+		t.Errorf("ClusterVersion = %+v, want %+v", report.ClusterVersion, want)
+	}
+	wantNodes := []ResourceDiff{
+		{Name: "node-a", Status: StatusChanged, Changes: []Change{
+			{Field: "kubeletVersion", Before: "v1.19.3", After: "v1.20.1"},
+			{Field: "ready", Before: "True", After: "False"},
+		}},
+		{Name: "node-b", Status: StatusRemoved},
+		{Name: "node-c", Status: StatusAdded},
+	}
+	if !reflect.DeepEqual(report.Nodes, wantNodes) {
+		t.Errorf("Nodes = %+v, want %+v", report.Nodes, wantNodes)
+	}
+	if len(report.Deployments) != 0 {
+		t.Errorf("Deployments = %+v, want no changes", report.Deployments)
+	}
+	wantClusters := []ResourceDiff{
+		{Name: "storageos/storageos", Status: StatusChanged, Changes: []Change{
+			{Field: "spec.kvBackend.address", Before: "etcd:2379", After: "etcd-0:2379"},
+			{Field: "status.phase", Before: "Running", After: "Pending"},
+		}},
+	}
+	if !reflect.DeepEqual(report.StorageOSClusters, wantClusters) {
+		t.Errorf("StorageOSClusters = %+v, want %+v", report.StorageOSClusters, wantClusters)
+	}
+	if report.Empty() {
+		t.Error("Empty() = true for a report with changes")
+	}
+
+	same, err := Diff(Bundle{Dir: before}, Bundle{Dir: before})
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	if !same.Empty() {
+		t.Errorf("Diff() of the same bundle = %+v, want no changes", same)
+	}
+}
@@ -0,0 +1,344 @@
+// Package bundleui serves a web UI to browse an extracted support bundle:
+// its analyzer results, files, logs and cluster resources. The UI has no
+// external assets, so that it works without internet access.
+package bundleui
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	analyzer "github.com/replicatedhq/troubleshoot/pkg/analyze"
+
+	"github.com/croomes/kubectl-plugin/pkg/bundlearchive"
+	"github.com/croomes/kubectl-plugin/pkg/timeline"
+)
+
+//go:generate go run generate.go
+
+// maxSearchMatches is how many lines a search returns.
+const maxSearchMatches = 1000
+
+// Server serves the UI for the support bundle extracted to Dir.
+type Server struct {
+	Dir string
+	// Title is shown at the top of the UI, usually the bundle filename.
+	Title   string
+	Results []*analyzer.AnalyzeResult
+	// Addr is the address the UI is served on. Only requests for its host,
+	// localhost or an IP address are served, so that other sites cannot read
+	// the bundle by rebinding their DNS names to it.
+	Addr string
+}
+
+type result struct {
+	Outcome string `json:"outcome"`
+	Title   string `json:"title"`
+	Message string `json:"message"`
+}
+
+type file struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+type match struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+	Text string `json:"text"`
+}
+
+type kindCount struct {
+	Kind  string `json:"kind"`
+	Count int    `json:"count"`
+}
+
+type object struct {
+	bundlearchive.ObjectSummary
+	Object map[string]interface{} `json:"object"`
+}
+
+// Handler returns the handler of the UI and its API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.index)
+	mux.HandleFunc("/api/summary", s.summary)
+	mux.HandleFunc("/api/files", s.files)
+	mux.HandleFunc("/api/file", s.file)
+	mux.HandleFunc("/api/search", s.search)
+	mux.HandleFunc("/api/resources", s.resources)
+	return s.checkHost(mux)
+}
+
+// checkHost refuses requests whose Host header is not an allowed host.
+func (s *Server) checkHost(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.allowedHost(r.Host) {
+			writeError(w, errors.Errorf("host %q is not allowed", r.Host), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) allowedHost(hostport string) bool {
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.Trim(host, "[]"), ".")
+	if host == "" {
+		return false
+	}
+	if strings.EqualFold(host, "localhost") || net.ParseIP(host) != nil {
+		return true
+	}
+	addrHost := s.Addr
+	if h, _, err := net.SplitHostPort(s.Addr); err == nil {
+		addrHost = h
+	}
+	return strings.EqualFold(host, addrHost)
+}
+
+func (s *Server) index(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(indexHTML))
+}
+
+func (s *Server) summary(w http.ResponseWriter, r *http.Request) {
+	results := []result{}
+	for _, res := range s.Results {
+		outcome := ""
+		switch {
+		case res.IsFail:
+			outcome = "fail"
+		case res.IsWarn:
+			outcome = "warn"
+		case res.IsPass:
+			outcome = "pass"
+		}
+		results = append(results, result{Outcome: outcome, Title: res.Title, Message: res.Message})
+	}
+
+	writeJSON(w, struct {
+		Title   string   `json:"title"`
+		Results []result `json:"results"`
+	}{s.Title, results})
+}
+
+func (s *Server) files(w http.ResponseWriter, r *http.Request) {
+	files := []file{}
+	err := s.walk(func(name string, info os.FileInfo) error {
+		files = append(files, file{Name: name, Size: info.Size()})
+		return nil
+	})
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
+	writeJSON(w, files)
+}
+
+func (s *Server) file(w http.ResponseWriter, r *http.Request) {
+	filename, err := s.resolve(r.URL.Query().Get("path"))
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// only regular files are served, not links out of the bundle
+	info, err := os.Lstat(filename)
+	if os.IsNotExist(err) || (err == nil && !info.Mode().IsRegular()) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	// files are always shown as text, so that a file in the bundle cannot
+	// run scripts in the UI
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	io.Copy(w, f)
+}
+
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	re, err := regexp.Compile(query.Get("q"))
+	if err != nil {
+		writeError(w, errors.Wrap(err, "invalid regex"), http.StatusBadRequest)
+		return
+	}
+	include := query.Get("include")
+	if include == "" {
+		include = "*.log"
+	}
+	if _, err := path.Match(include, ""); err != nil {
+		writeError(w, errors.Wrap(err, "invalid include pattern"), http.StatusBadRequest)
+		return
+	}
+
+	matches := []match{}
+	truncated := false
+	err = s.walk(func(name string, info os.FileInfo) error {
+		if matched, _ := path.Match(include, path.Base(name)); !matched {
+			return nil
+		}
+		// the timeline repeats every line of the other logs
+		if name == timeline.Filename && include != timeline.Filename {
+			return nil
+		}
+
+		f, err := os.Open(filepath.Join(s.Dir, filepath.FromSlash(name)))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for line := 1; scanner.Scan(); line++ {
+			if !re.Match(scanner.Bytes()) {
+				continue
+			}
+			if len(matches) == maxSearchMatches {
+				truncated = true
+				return errStopWalk
+			}
+			matches = append(matches, match{File: name, Line: line, Text: scanner.Text()})
+		}
+		return scanner.Err()
+	})
+	if err != nil && err != errStopWalk {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, struct {
+		Matches   []match `json:"matches"`
+		Truncated bool    `json:"truncated"`
+	}{matches, truncated})
+}
+
+func (s *Server) resources(w http.ResponseWriter, r *http.Request) {
+	kind := r.URL.Query().Get("kind")
+
+	counts := map[string]int{}
+	objects := []object{}
+	err := s.walk(func(name string, info os.FileInfo) error {
+		fileKind, ok := bundlearchive.ResourceKind(name)
+		if !ok || (kind != "" && fileKind != kind) {
+			return nil
+		}
+
+		b, err := ioutil.ReadFile(filepath.Join(s.Dir, filepath.FromSlash(name)))
+		if err != nil {
+			return err
+		}
+		items, err := bundlearchive.DecodeObjects(b)
+		if err != nil {
+			// some files in cluster-resources are not lists of objects
+			return nil
+		}
+		counts[fileKind] += len(items)
+		for _, item := range items {
+			objects = append(objects, object{ObjectSummary: bundlearchive.Summarize(item), Object: item})
+		}
+		return nil
+	})
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	if kind != "" {
+		sort.Slice(objects, func(i, j int) bool {
+			if objects[i].Namespace != objects[j].Namespace {
+				return objects[i].Namespace < objects[j].Namespace
+			}
+			return objects[i].Name < objects[j].Name
+		})
+		writeJSON(w, objects)
+		return
+	}
+
+	kinds := []kindCount{}
+	for k, count := range counts {
+		kinds = append(kinds, kindCount{Kind: k, Count: count})
+	}
+	sort.Slice(kinds, func(i, j int) bool {
+		return kinds[i].Kind < kinds[j].Kind
+	})
+	writeJSON(w, kinds)
+}
+
+var errStopWalk = errors.New("stop walking the bundle")
+
+// walk calls fn for each regular file in the bundle, with its name relative
+// to Dir and slash separated.
+func (s *Server) walk(fn func(name string, info os.FileInfo) error) error {
+	return filepath.Walk(s.Dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(s.Dir, p)
+		if err != nil {
+			return err
+		}
+		return fn(filepath.ToSlash(rel), info)
+	})
+}
+
+// resolve returns the path of a file in the bundle, refusing names outside
+// of it.
+func (s *Server) resolve(name string) (string, error) {
+	name = path.Clean("/" + name)
+	if name == "/" {
+		return "", errors.New("no file given")
+	}
+	return filepath.Join(s.Dir, filepath.FromSlash(strings.TrimPrefix(name, "/"))), nil
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
+
+func writeError(w http.ResponseWriter, err error, code int) {
+	http.Error(w, err.Error(), code)
+}
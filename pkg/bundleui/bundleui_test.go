@@ -0,0 +1,130 @@
+package bundleui
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestServer(t *testing.T) (*Server, func()) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "bundleui")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"storageos/logs/node-a.log": "level=info msg=\"started\"\nlevel=error msg=\"etcd connection refused\"\n",
+		"timeline.log":              "2020-06-01T10:00:00.000000Z [storageos/logs/node-a] level=error msg=\"etcd connection refused\"\n",
+		"version.yaml":              "apiVersion: troubleshoot.sh/v1beta2\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &Server{Dir: dir}, func() { os.RemoveAll(dir) }
+}
+
+func TestSearch(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	tests := []struct {
+		include string
+		want    []match
+	}{
+		{
+			want: []match{{File: "storageos/logs/node-a.log", Line: 2, Text: "level=error msg=\"etcd connection refused\""}},
+		},
+		{
+			include: "timeline.log",
+			want:    []match{{File: "timeline.log", Line: 1, Text: "2020-06-01T10:00:00.000000Z [storageos/logs/node-a] level=error msg=\"etcd connection refused\""}},
+		},
+	}
+	for _, tt := range tests {
+		query := url.Values{"q": {"etcd"}, "include": {tt.include}}
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://localhost:8800/api/search?"+query.Encode(), nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("search %q: status %d: %s", tt.include, rec.Code, rec.Body)
+		}
+
+		got := struct {
+			Matches []match `json:"matches"`
+		}{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if len(got.Matches) != len(tt.want) {
+			t.Fatalf("search %q = %+v, want %+v", tt.include, got.Matches, tt.want)
+		}
+		for i := range tt.want {
+			if got.Matches[i] != tt.want[i] {
+				t.Errorf("search %q match %d = %+v, want %+v", tt.include, i, got.Matches[i], tt.want[i])
+			}
+		}
+	}
+}
+
+func TestFile(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://localhost:8800/api/file?path=version.yaml", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "apiVersion: troubleshoot.sh/v1beta2\n" {
+		t.Errorf("file version.yaml: status %d, body %q", rec.Code, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Errorf("file version.yaml: Content-Type %q", ct)
+	}
+
+	// paths are resolved inside the bundle, where these don't exist
+	for _, name := range []string{"../etc/passwd", "/etc/passwd", "storageos/../../etc/passwd"} {
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://localhost:8800/api/file?"+url.Values{"path": {name}}.Encode(), nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("file %q: status %d, want %d", name, rec.Code, http.StatusNotFound)
+		}
+	}
+}
+
+func TestHost(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+	s.Addr = "bundles.example.com:8800"
+
+	tests := []struct {
+		host string
+		want int
+	}{
+		{host: "localhost:8800", want: http.StatusOK},
+		{host: "127.0.0.1:8800", want: http.StatusOK},
+		{host: "[::1]:8800", want: http.StatusOK},
+		{host: "192.168.1.10:8800", want: http.StatusOK},
+		{host: "bundles.example.com:8800", want: http.StatusOK},
+		{host: "localhost", want: http.StatusOK},
+		{host: "attacker.example.com:8800", want: http.StatusForbidden},
+		{host: "localhost.attacker.example.com", want: http.StatusForbidden},
+		{host: "", want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/summary", nil)
+		req.Host = tt.host
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("host %q: status %d, want %d", tt.host, rec.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+//go:build ignore
+// +build ignore
+
+// generate writes the web UI assets into assets_generated.go as string
+// constants, so that they are compiled into the binary.
+package main
+
+import (
+	"bytes"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"strings"
+	"text/template"
+)
+
+var assets = []struct {
+	Name string
+	Path string
+}{
+	{"indexHTML", "assets/index.html"},
+}
+
+var tmpl = template.Must(template.New("assets").Parse(`// Code generated by generate.go. DO NOT EDIT.
+
+package bundleui
+{{range .}}
+// {{.Name}} is the content of {{.Path}}.
+const {{.Name}} = ` + "`{{.Content}}`" + `
+{{end}}`))
+
+func main() {
+	type asset struct {
+		Name    string
+		Path    string
+		Content string
+	}
+
+	data := []asset{}
+	for _, a := range assets {
+		b, err := ioutil.ReadFile(a.Path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if strings.Contains(string(b), "`") {
+			log.Fatalf("%s contains a backtick, which cannot be embedded in a raw string", a.Path)
+		}
+		data = append(data, asset{Name: a.Name, Path: a.Path, Content: string(b)})
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		log.Fatal(err)
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile("assets_generated.go", src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
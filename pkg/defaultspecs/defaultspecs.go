@@ -0,0 +1,35 @@
+// Package defaultspecs holds the example specs compiled into the binary, so
+// that the plugin works in clusters without internet access.
+package defaultspecs
+
+import (
+	"fmt"
+
+	"github.com/croomes/kubectl-plugin/pkg/specloader"
+)
+
+//go:generate go run generate.go
+
+// Sources of the default spec, selected with --spec-source.
+const (
+	SourceEmbedded = "embedded"
+	SourceRemote   = "remote"
+)
+
+// URLs of the latest example specs, used with --spec-source=remote.
+const (
+	SupportBundleURL = "https://raw.githubusercontent.com/croomes/kubectl-plugin/master/examples/bundle.yaml"
+	PreflightURL     = "https://raw.githubusercontent.com/croomes/kubectl-plugin/master/examples/preflight.yaml"
+)
+
+// Source returns the spec source to load the default spec from: the embedded
+// spec, or remoteURL for the remote source.
+func Source(specSource string, remoteURL string) (string, error) {
+	switch specSource {
+	case SourceEmbedded:
+		return specloader.Embedded, nil
+	case SourceRemote:
+		return remoteURL, nil
+	}
+	return "", fmt.Errorf("invalid --spec-source %q, must be one of %s, %s", specSource, SourceEmbedded, SourceRemote)
+}
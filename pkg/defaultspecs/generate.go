@@ -0,0 +1,65 @@
+//go:build ignore
+// +build ignore
+
+// generate writes the example specs into specs_generated.go as string
+// constants, so that they are compiled into the binary.
+package main
+
+import (
+	"bytes"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"strings"
+	"text/template"
+)
+
+var specs = []struct {
+	Name string
+	Path string
+}{
+	{"SupportBundle", "../../examples/bundle.yaml"},
+	{"Preflight", "../../examples/preflight.yaml"},
+}
+
+var tmpl = template.Must(template.New("specs").Parse(`// Code generated by generate.go. DO NOT EDIT.
+
+package defaultspecs
+{{range .}}
+// {{.Name}} is the content of {{.Path}}.
+const {{.Name}} = ` + "`{{.Content}}`" + `
+{{end}}`))
+
+func main() {
+	type spec struct {
+		Name    string
+		Path    string
+		Content string
+	}
+
+	data := []spec{}
+	for _, s := range specs {
+		b, err := ioutil.ReadFile(s.Path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if strings.Contains(string(b), "`") {
+			log.Fatalf("%s contains a backtick, which cannot be embedded in a raw string", s.Path)
+		}
+		data = append(data, spec{Name: s.Name, Path: strings.TrimPrefix(s.Path, "../../"), Content: string(b)})
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		log.Fatal(err)
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile("specs_generated.go", src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,200 @@
+// Package discovery finds where StorageOS is installed in a cluster, so that
+// specs can be rendered to collect from and check the right namespaces.
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/croomes/kubectl-plugin/pkg/specloader"
+)
+
+// Filename is the name of the discovery summary in a support bundle.
+const Filename = "storageos-discovery.json"
+
+const (
+	daemonSetSelector = "app=storageos"
+	componentLabel    = "app.kubernetes.io/component"
+	daemonSetSuffix   = "-daemonset"
+)
+
+// StorageOSClusterResource is the StorageOSCluster custom resource.
+var StorageOSClusterResource = schema.GroupVersionResource{
+	Group:    "storageos.com",
+	Version:  "v1",
+	Resource: "storageosclusters",
+}
+
+// operatorSelectors match the cluster operator deployment of each operator
+// release.
+var operatorSelectors = []string{
+	"name=storageos-cluster-operator",
+	"control-plane=storageos-operator",
+}
+
+// Installation is where StorageOS was found in the cluster. Fields that could
+// not be found are empty.
+type Installation struct {
+	Clusters []Cluster `json:"clusters"`
+
+	// Namespace is the namespace of the StorageOS components, and Name the
+	// prefix of their names, e.g. storageos in storageos-api-manager.
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+	DaemonSet string `json:"daemonSet,omitempty"`
+
+	OperatorNamespace  string `json:"operatorNamespace,omitempty"`
+	OperatorDeployment string `json:"operatorDeployment,omitempty"`
+
+	// Errors are the lookups that failed, usually for lack of permissions.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// Cluster is a StorageOSCluster resource.
+type Cluster struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Phase     string `json:"phase,omitempty"`
+	// ComponentNamespace is the namespace the cluster asks for its
+	// components to be installed in, if it sets one.
+	ComponentNamespace string `json:"componentNamespace,omitempty"`
+}
+
+// Found returns true if any part of a StorageOS installation was found.
+func (i *Installation) Found() bool {
+	return len(i.Clusters) > 0 || i.Namespace != "" || i.OperatorNamespace != ""
+}
+
+// Running returns true if the StorageOS DaemonSet was found. An installation
+// that was not discovered, e.g. with --discover=false, is not running, so
+// that checks for an installation, such as that its ports are free, still run.
+func (i *Installation) Running() bool {
+	return i != nil && i.DaemonSet != ""
+}
+
+// Values returns the spec template values for the installation. Only values
+// that were found are set, so the spec's defaults apply to the rest.
+func (i *Installation) Values() specloader.Values {
+	values := specloader.Values{}
+	if i.Namespace != "" {
+		values["namespace"] = i.Namespace
+	}
+	if i.Name != "" {
+		values["name"] = i.Name
+	}
+	if i.OperatorNamespace != "" {
+		values["operatorNamespace"] = i.OperatorNamespace
+	}
+	return values
+}
+
+// Discover looks up the StorageOSCluster resources, the StorageOS daemonset
+// and the cluster operator. Lookups that fail are recorded in the
+// Installation's Errors rather than returned.
+func Discover(ctx context.Context, config *rest.Config) (*Installation, error) {
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create kubernetes client")
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create dynamic client")
+	}
+
+	installation := &Installation{
+		Clusters: []Cluster{},
+	}
+
+	installation.discoverClusters(ctx, dynamicClient)
+	installation.discoverDaemonSet(ctx, client)
+	installation.discoverOperator(ctx, client)
+
+	// before the daemonset is running, the cluster resource is the only place
+	// that says where it will be
+	if installation.Namespace == "" {
+		for _, cluster := range installation.Clusters {
+			if cluster.ComponentNamespace != "" {
+				installation.Namespace = cluster.ComponentNamespace
+				break
+			}
+		}
+	}
+
+	return installation, nil
+}
+
+func (i *Installation) errorf(format string, args ...interface{}) {
+	i.Errors = append(i.Errors, fmt.Sprintf(format, args...))
+}
+
+func (i *Installation) discoverClusters(ctx context.Context, client dynamic.Interface) {
+	list, err := client.Resource(StorageOSClusterResource).Namespace(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		i.errorf("list storageosclusters: %v", err)
+		return
+	}
+
+	for _, item := range list.Items {
+		cluster := Cluster{
+			Name:      item.GetName(),
+			Namespace: item.GetNamespace(),
+		}
+		cluster.Phase, _, _ = unstructured.NestedString(item.Object, "status", "phase")
+		cluster.ComponentNamespace, _, _ = unstructured.NestedString(item.Object, "spec", "namespace")
+		i.Clusters = append(i.Clusters, cluster)
+	}
+}
+
+func (i *Installation) discoverDaemonSet(ctx context.Context, client kubernetes.Interface) {
+	list, err := client.AppsV1().DaemonSets(metav1.NamespaceAll).List(ctx, metav1.ListOptions{LabelSelector: daemonSetSelector})
+	if err != nil {
+		i.errorf("list daemonsets: %v", err)
+		return
+	}
+	if len(list.Items) == 0 {
+		return
+	}
+
+	// prefer the node daemonset over any other StorageOS daemonset, such as
+	// the CSI helper in older releases
+	ds := list.Items[0]
+	for _, item := range list.Items {
+		if strings.HasSuffix(item.Labels[componentLabel], daemonSetSuffix) || strings.HasSuffix(item.Name, daemonSetSuffix) {
+			ds = item
+			break
+		}
+	}
+
+	i.Namespace = ds.Namespace
+	i.DaemonSet = ds.Name
+	if component := ds.Labels[componentLabel]; strings.HasSuffix(component, daemonSetSuffix) {
+		i.Name = strings.TrimSuffix(component, daemonSetSuffix)
+	} else if strings.HasSuffix(ds.Name, daemonSetSuffix) {
+		i.Name = strings.TrimSuffix(ds.Name, daemonSetSuffix)
+	}
+}
+
+func (i *Installation) discoverOperator(ctx context.Context, client kubernetes.Interface) {
+	for _, selector := range operatorSelectors {
+		list, err := client.AppsV1().Deployments(metav1.NamespaceAll).List(ctx, metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			i.errorf("list deployments: %v", err)
+			return
+		}
+		if len(list.Items) > 0 {
+			i.OperatorNamespace = list.Items[0].Namespace
+			i.OperatorDeployment = list.Items[0].Name
+			return
+		}
+	}
+}
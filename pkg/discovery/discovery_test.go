@@ -0,0 +1,21 @@
+package discovery
+
+import "testing"
+
+func TestRunning(t *testing.T) {
+	tests := []struct {
+		name         string
+		installation *Installation
+		want         bool
+	}{
+		{name: "not discovered", installation: nil, want: false},
+		{name: "not installed", installation: &Installation{}, want: false},
+		{name: "cluster without daemonset", installation: &Installation{Clusters: []Cluster{{Name: "storageos"}}}, want: false},
+		{name: "daemonset found", installation: &Installation{Namespace: "storageos", DaemonSet: "storageos-node"}, want: true},
+	}
+	for _, tt := range tests {
+		if got := tt.installation.Running(); got != tt.want {
+			t.Errorf("%s: Running() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
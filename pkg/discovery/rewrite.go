@@ -0,0 +1,61 @@
+package discovery
+
+import (
+	"strings"
+
+	troubleshootv1beta2 "github.com/replicatedhq/troubleshoot/pkg/apis/troubleshoot/v1beta2"
+)
+
+const (
+	defaultName     = "storageos"
+	componentPrefix = componentLabel + "=" + defaultName + "-"
+)
+
+// RewriteCollectors points the collectors of StorageOS pods at the
+// installation, for specs that were not rendered with its values or the
+// user's, which already say where to collect from. A collector targets the
+// StorageOS components if its selector includes app=storageos, and the
+// operator if it includes one of the operator selectors.
+func (i *Installation) RewriteCollectors(collectors []*troubleshootv1beta2.Collect) {
+	for _, c := range collectors {
+		switch {
+		case c.Logs != nil:
+			c.Logs.Namespace = i.rewriteNamespace(c.Logs.Namespace, c.Logs.Selector)
+			i.rewriteSelector(c.Logs.Selector)
+		case c.Exec != nil:
+			c.Exec.Namespace = i.rewriteNamespace(c.Exec.Namespace, c.Exec.Selector)
+			i.rewriteSelector(c.Exec.Selector)
+		case c.Copy != nil:
+			c.Copy.Namespace = i.rewriteNamespace(c.Copy.Namespace, c.Copy.Selector)
+			i.rewriteSelector(c.Copy.Selector)
+		}
+	}
+}
+
+func (i *Installation) rewriteNamespace(namespace string, selector []string) string {
+	for _, s := range selector {
+		if s == daemonSetSelector && i.Namespace != "" {
+			return i.Namespace
+		}
+		for _, operatorSelector := range operatorSelectors {
+			if s == operatorSelector && i.OperatorNamespace != "" {
+				return i.OperatorNamespace
+			}
+		}
+	}
+	return namespace
+}
+
+// rewriteSelector renames the default component labels, e.g.
+// app.kubernetes.io/component=storageos-api-manager, to the installation's
+// name.
+func (i *Installation) rewriteSelector(selector []string) {
+	if i.Name == "" || i.Name == defaultName {
+		return
+	}
+	for idx, s := range selector {
+		if strings.HasPrefix(s, componentPrefix) {
+			selector[idx] = componentLabel + "=" + i.Name + "-" + strings.TrimPrefix(s, componentPrefix)
+		}
+	}
+}
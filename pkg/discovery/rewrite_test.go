@@ -0,0 +1,83 @@
+package discovery
+
+import (
+	"reflect"
+	"testing"
+
+	troubleshootv1beta2 "github.com/replicatedhq/troubleshoot/pkg/apis/troubleshoot/v1beta2"
+)
+
+func TestRewriteCollectors(t *testing.T) {
+	collectors := func() []*troubleshootv1beta2.Collect {
+		return []*troubleshootv1beta2.Collect{
+			{Logs: &troubleshootv1beta2.Logs{Namespace: "kube-system", Selector: []string{"app=storageos", "app.kubernetes.io/component=storageos-daemonset"}}},
+			{Exec: &troubleshootv1beta2.Exec{Namespace: "kube-system", Selector: []string{"app=storageos", "app.kubernetes.io/component=storageos-api-manager"}}},
+			{Copy: &troubleshootv1beta2.Copy{Namespace: "storageos-operator", Selector: []string{"name=storageos-cluster-operator"}}},
+			{Logs: &troubleshootv1beta2.Logs{Namespace: "storageos", Selector: []string{"control-plane=storageos-operator"}}},
+			// not a StorageOS collector
+			{Logs: &troubleshootv1beta2.Logs{Namespace: "kube-system", Selector: []string{"k8s-app=kube-dns"}}},
+			{ClusterInfo: &troubleshootv1beta2.ClusterInfo{}},
+		}
+	}
+
+	type target struct {
+		namespace string
+		selector  []string
+	}
+	tests := []struct {
+		name         string
+		installation *Installation
+		want         []target
+	}{
+		{
+			name:         "nothing found",
+			installation: &Installation{},
+			want: []target{
+				{"kube-system", []string{"app=storageos", "app.kubernetes.io/component=storageos-daemonset"}},
+				{"kube-system", []string{"app=storageos", "app.kubernetes.io/component=storageos-api-manager"}},
+				{"storageos-operator", []string{"name=storageos-cluster-operator"}},
+				{"storageos", []string{"control-plane=storageos-operator"}},
+				{"kube-system", []string{"k8s-app=kube-dns"}},
+			},
+		},
+		{
+			name:         "default name",
+			installation: &Installation{Namespace: "storageos", Name: "storageos", OperatorNamespace: "operators"},
+			want: []target{
+				{"storageos", []string{"app=storageos", "app.kubernetes.io/component=storageos-daemonset"}},
+				{"storageos", []string{"app=storageos", "app.kubernetes.io/component=storageos-api-manager"}},
+				{"operators", []string{"name=storageos-cluster-operator"}},
+				{"operators", []string{"control-plane=storageos-operator"}},
+				{"kube-system", []string{"k8s-app=kube-dns"}},
+			},
+		},
+		{
+			name:         "renamed installation",
+			installation: &Installation{Namespace: "ondat", Name: "ondat"},
+			want: []target{
+				{"ondat", []string{"app=storageos", "app.kubernetes.io/component=ondat-daemonset"}},
+				{"ondat", []string{"app=storageos", "app.kubernetes.io/component=ondat-api-manager"}},
+				{"storageos-operator", []string{"name=storageos-cluster-operator"}},
+				{"storageos", []string{"control-plane=storageos-operator"}},
+				{"kube-system", []string{"k8s-app=kube-dns"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := collectors()
+			tt.installation.RewriteCollectors(c)
+
+			got := []target{
+				{c[0].Logs.Namespace, c[0].Logs.Selector},
+				{c[1].Exec.Namespace, c[1].Exec.Selector},
+				{c[2].Copy.Namespace, c[2].Copy.Selector},
+				{c[3].Logs.Namespace, c[3].Logs.Selector},
+				{c[4].Logs.Namespace, c[4].Logs.Selector},
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RewriteCollectors() =\n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+// Package exitcode defines the exit codes of the preflight and bundle
+// commands, so that scripts and CI pipelines can gate on their results.
+package exitcode
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	analyzerunner "github.com/replicatedhq/troubleshoot/pkg/analyze"
+)
+
+const (
+	// OK means every check passed, or none reached the --fail-on threshold.
+	OK = 0
+	// Error is any error that is not described by another code, such as a
+	// spec that cannot be loaded.
+	Error = 1
+	// Warn means at least one check warned and none failed, with
+	// --fail-on=warn.
+	Warn = 2
+	// Fail means at least one check failed.
+	Fail = 3
+	// CollectionError means collection failed or was interrupted, so there
+	// are no results to gate on.
+	CollectionError = 4
+)
+
+const (
+	// FailOnWarn exits non-zero when any check warns or fails.
+	FailOnWarn = "warn"
+	// FailOnFail exits non-zero only when a check fails.
+	FailOnFail = "fail"
+)
+
+// ExitError is an error that sets the exit code of the command.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+// New returns an error that exits with code.
+func New(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &ExitError{Code: code, Err: err}
+}
+
+// Code returns the exit code for an error returned by a command: OK for nil,
+// the code of an ExitError, even when wrapped, and Error for anything else.
+func Code(err error) int {
+	if err == nil {
+		return OK
+	}
+	if exitErr, ok := errors.Cause(err).(*ExitError); ok {
+		return exitErr.Code
+	}
+	return Error
+}
+
+// ValidateFailOn returns an error if failOn is not a known threshold.
+func ValidateFailOn(failOn string) error {
+	switch failOn {
+	case FailOnWarn, FailOnFail:
+		return nil
+	}
+	return fmt.Errorf("invalid --fail-on %q, must be one of %s, %s", failOn, FailOnWarn, FailOnFail)
+}
+
+// FromResults returns an ExitError if any of the results reach the failOn
+// threshold, or nil if the command should exit OK.
+func FromResults(analyzeResults []*analyzerunner.AnalyzeResult, failOn string) error {
+	var warnings, failures int
+	for _, analyzeResult := range analyzeResults {
+		if analyzeResult.IsFail {
+			failures++
+		} else if analyzeResult.IsWarn {
+			warnings++
+		}
+	}
+
+	if failures > 0 {
+		return New(Fail, fmt.Errorf("%d of %d checks failed", failures, len(analyzeResults)))
+	}
+	if warnings > 0 && failOn == FailOnWarn {
+		return New(Warn, fmt.Errorf("%d of %d checks warned", warnings, len(analyzeResults)))
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package exitcode
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	analyzerunner "github.com/replicatedhq/troubleshoot/pkg/analyze"
+)
+
+func TestFromResults(t *testing.T) {
+	pass := &analyzerunner.AnalyzeResult{IsPass: true}
+	warn := &analyzerunner.AnalyzeResult{IsWarn: true}
+	fail := &analyzerunner.AnalyzeResult{IsFail: true}
+
+	tests := []struct {
+		name    string
+		results []*analyzerunner.AnalyzeResult
+		failOn  string
+		want    int
+		wantMsg string
+	}{
+		{name: "no results", failOn: FailOnFail, want: OK},
+		{name: "all pass", results: []*analyzerunner.AnalyzeResult{pass, pass}, failOn: FailOnWarn, want: OK},
+		{name: "warn below threshold", results: []*analyzerunner.AnalyzeResult{pass, warn}, failOn: FailOnFail, want: OK},
+		{name: "warn at threshold", results: []*analyzerunner.AnalyzeResult{pass, warn}, failOn: FailOnWarn, want: Warn, wantMsg: "1 of 2 checks warned"},
+		{name: "fail", results: []*analyzerunner.AnalyzeResult{fail, pass, fail}, failOn: FailOnFail, want: Fail, wantMsg: "2 of 3 checks failed"},
+		{name: "fail outranks warn", results: []*analyzerunner.AnalyzeResult{warn, fail}, failOn: FailOnWarn, want: Fail, wantMsg: "1 of 2 checks failed"},
+		// a result that both fails and warns only counts as a failure
+		{name: "fail and warn", results: []*analyzerunner.AnalyzeResult{{IsFail: true, IsWarn: true}}, failOn: FailOnWarn, want: Fail, wantMsg: "1 of 1 checks failed"},
+	}
+	for _, tt := range tests {
+		err := FromResults(tt.results, tt.failOn)
+		if got := Code(err); got != tt.want {
+			t.Errorf("%s: Code(FromResults()) = %d, want %d", tt.name, got, tt.want)
+		}
+		if err != nil && err.Error() != tt.wantMsg {
+			t.Errorf("%s: FromResults() error = %q, want %q", tt.name, err, tt.wantMsg)
+		}
+	}
+}
+
+func TestCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "nil", err: nil, want: OK},
+		{name: "other error", err: errors.New("spec not found"), want: Error},
+		{name: "exit error", err: New(CollectionError, errors.New("interrupted")), want: CollectionError},
+		{name: "wrapped exit error", err: errors.Wrap(New(Warn, errors.New("1 of 1 checks warned")), "analyze"), want: Warn},
+	}
+	for _, tt := range tests {
+		if got := Code(tt.err); got != tt.want {
+			t.Errorf("%s: Code() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	if err := New(Fail, nil); err != nil {
+		t.Errorf("New(Fail, nil) = %v, want nil", err)
+	}
+}
+
+func TestValidateFailOn(t *testing.T) {
+	for _, failOn := range []string{FailOnWarn, FailOnFail} {
+		if err := ValidateFailOn(failOn); err != nil {
+			t.Errorf("ValidateFailOn(%q) error = %v", failOn, err)
+		}
+	}
+	for _, failOn := range []string{"", "pass", "WARN"} {
+		if err := ValidateFailOn(failOn); err == nil {
+			t.Errorf("ValidateFailOn(%q) succeeded, want an error", failOn)
+		}
+	}
+}
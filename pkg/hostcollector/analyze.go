@@ -0,0 +1,191 @@
+package hostcollector
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	analyzer "github.com/replicatedhq/troubleshoot/pkg/analyze"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// MinAvailableDisk is the free space StorageOS recommends on the filesystem
+// of DataDir.
+var MinAvailableDisk = resource.MustParse("20Gi")
+
+// AnalyzeFile analyzes the node reports written to Filename in a bundle.
+// getFile returns the contents of a file in the bundle. A bundle without host
+// reports has no results.
+func AnalyzeFile(getFile func(string) ([]byte, error), storageosRunning bool) ([]*analyzer.AnalyzeResult, error) {
+	b, err := getFile(Filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "read host reports")
+	}
+
+	reports := []*NodeReport{}
+	if err := json.Unmarshal(b, &reports); err != nil {
+		return nil, errors.Wrap(err, "parse host reports")
+	}
+	return Analyze(reports, storageosRunning), nil
+}
+
+// Analyze reports nodes without the kernel modules StorageOS needs, low on
+// disk space under DataDir, or out of hugepages. Ports in the StorageOS range
+// that are in use are reported unless storageosRunning, when StorageOS itself
+// is expected to be using them.
+func Analyze(reports []*NodeReport, storageosRunning bool) []*analyzer.AnalyzeResult {
+	results := []*analyzer.AnalyzeResult{}
+
+	checked := []*NodeReport{}
+	failed := []string{}
+	for _, report := range reports {
+		if report.Error != "" {
+			failed = append(failed, fmt.Sprintf("%s (%s)", report.Node, report.Error))
+			continue
+		}
+		checked = append(checked, report)
+	}
+	if len(failed) > 0 {
+		results = append(results, &analyzer.AnalyzeResult{
+			IsWarn:  true,
+			Title:   "Host Checks",
+			Message: fmt.Sprintf("The host checks did not finish on %d nodes: %s.", len(failed), strings.Join(failed, ", ")),
+		})
+	}
+	if len(checked) == 0 {
+		return results
+	}
+
+	results = append(results, analyzeModules(checked)...)
+	if !storageosRunning {
+		results = append(results, analyzePorts(checked))
+	}
+	results = append(results, analyzeDisk(checked))
+	results = append(results, analyzeHugePages(checked))
+	return results
+}
+
+func analyzeModules(reports []*NodeReport) []*analyzer.AnalyzeResult {
+	title := "Kernel Modules"
+	results := []*analyzer.AnalyzeResult{}
+	for _, module := range Modules {
+		missing := []string{}
+		for _, report := range reports {
+			if report.Modules[module] == ModuleMissing {
+				missing = append(missing, report.Node)
+			}
+		}
+		if len(missing) > 0 {
+			results = append(results, &analyzer.AnalyzeResult{
+				IsFail:  true,
+				Title:   title,
+				Message: fmt.Sprintf("The %s kernel module is not installed on %s.", module, strings.Join(missing, ", ")),
+			})
+		}
+	}
+
+	if len(results) == 0 {
+		results = append(results, &analyzer.AnalyzeResult{
+			IsPass:  true,
+			Title:   title,
+			Message: fmt.Sprintf("The %s kernel modules are available on all %d nodes.", strings.Join(Modules, ", "), len(reports)),
+		})
+	}
+	return results
+}
+
+func analyzePorts(reports []*NodeReport) *analyzer.AnalyzeResult {
+	inUse := []string{}
+	for _, report := range reports {
+		if len(report.PortsInUse) == 0 {
+			continue
+		}
+		ports := []string{}
+		for _, port := range report.PortsInUse {
+			ports = append(ports, strconv.Itoa(port))
+		}
+		inUse = append(inUse, fmt.Sprintf("%s (%s)", report.Node, strings.Join(ports, ", ")))
+	}
+
+	title := "StorageOS Ports"
+	if len(inUse) > 0 {
+		return &analyzer.AnalyzeResult{
+			IsFail:  true,
+			Title:   title,
+			Message: fmt.Sprintf("StorageOS needs ports %d-%d, which are in use on %s.", FirstPort, LastPort, strings.Join(inUse, ", ")),
+		}
+	}
+	return &analyzer.AnalyzeResult{
+		IsPass:  true,
+		Title:   title,
+		Message: fmt.Sprintf("Ports %d-%d are free on all %d nodes.", FirstPort, LastPort, len(reports)),
+	}
+}
+
+func analyzeDisk(reports []*NodeReport) *analyzer.AnalyzeResult {
+	low := []string{}
+	for _, report := range reports {
+		if report.Disk == nil {
+			continue
+		}
+		available := resource.NewQuantity(report.Disk.AvailableBytes, resource.BinarySI)
+		if available.Cmp(MinAvailableDisk) < 0 {
+			low = append(low, fmt.Sprintf("%s (%s free on %s)", report.Node, available.String(), report.Disk.Path))
+		}
+	}
+
+	title := "Disk Space"
+	if len(low) > 0 {
+		return &analyzer.AnalyzeResult{
+			IsWarn:  true,
+			Title:   title,
+			Message: fmt.Sprintf("StorageOS recommends at least %s free for %s, which %d nodes do not have: %s.", MinAvailableDisk.String(), DataDir, len(low), strings.Join(low, ", ")),
+		}
+	}
+	return &analyzer.AnalyzeResult{
+		IsPass:  true,
+		Title:   title,
+		Message: fmt.Sprintf("All nodes have at least %s free for %s.", MinAvailableDisk.String(), DataDir),
+	}
+}
+
+func analyzeHugePages(reports []*NodeReport) *analyzer.AnalyzeResult {
+	exhausted := []string{}
+	configured := 0
+	for _, report := range reports {
+		if report.HugePages == nil || report.HugePages.Total == 0 {
+			continue
+		}
+		configured++
+		if report.HugePages.Free == 0 {
+			exhausted = append(exhausted, report.Node)
+		}
+	}
+
+	title := "Hugepages"
+	if len(exhausted) > 0 {
+		return &analyzer.AnalyzeResult{
+			IsWarn:  true,
+			Title:   title,
+			Message: fmt.Sprintf("Hugepages are configured but none are free on %s.", strings.Join(exhausted, ", ")),
+		}
+	}
+	if configured == 0 {
+		return &analyzer.AnalyzeResult{
+			IsPass:  true,
+			Title:   title,
+			Message: "No nodes have hugepages configured.",
+		}
+	}
+	return &analyzer.AnalyzeResult{
+		IsPass:  true,
+		Title:   title,
+		Message: fmt.Sprintf("Hugepages are free on all %d nodes that have them configured.", configured),
+	}
+}
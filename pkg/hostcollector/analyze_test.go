@@ -0,0 +1,133 @@
+package hostcollector
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	analyzer "github.com/replicatedhq/troubleshoot/pkg/analyze"
+)
+
+// healthyReport is a node with everything StorageOS needs.
+func healthyReport(node string) *NodeReport {
+	return &NodeReport{
+		Node: node,
+		Modules: map[string]ModuleStatus{
+			"target_core_user": ModuleLoaded,
+			"tcm_loop":         ModuleLoaded,
+			"configfs":         ModuleAvailable,
+		},
+		Disk: &Disk{Path: DataDir, TotalBytes: 100 << 30, AvailableBytes: 50 << 30},
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	modulesPass := &analyzer.AnalyzeResult{IsPass: true, Title: "Kernel Modules", Message: "The target_core_user, tcm_loop, configfs kernel modules are available on all 2 nodes."}
+	portsPass := &analyzer.AnalyzeResult{IsPass: true, Title: "StorageOS Ports", Message: "Ports 5701-5711 are free on all 2 nodes."}
+	diskPass := &analyzer.AnalyzeResult{IsPass: true, Title: "Disk Space", Message: "All nodes have at least 20Gi free for /var/lib/storageos."}
+	hugePagesPass := &analyzer.AnalyzeResult{IsPass: true, Title: "Hugepages", Message: "No nodes have hugepages configured."}
+
+	tests := []struct {
+		name    string
+		reports func() []*NodeReport
+		running bool
+		want    []*analyzer.AnalyzeResult
+	}{
+		{
+			name: "healthy",
+			reports: func() []*NodeReport {
+				return []*NodeReport{healthyReport("node-a"), healthyReport("node-b")}
+			},
+			want: []*analyzer.AnalyzeResult{modulesPass, portsPass, diskPass, hugePagesPass},
+		},
+		{
+			name: "ports are not checked while StorageOS runs",
+			reports: func() []*NodeReport {
+				a := healthyReport("node-a")
+				a.PortsInUse = []int{5701}
+				return []*NodeReport{a, healthyReport("node-b")}
+			},
+			running: true,
+			want:    []*analyzer.AnalyzeResult{modulesPass, diskPass, hugePagesPass},
+		},
+		{
+			name: "problems",
+			reports: func() []*NodeReport {
+				a := healthyReport("node-a")
+				a.Modules["tcm_loop"] = ModuleMissing
+				a.PortsInUse = []int{5701, 5705}
+				a.HugePages = &HugePages{Total: 512, Free: 0, SizeBytes: 2 << 20}
+				b := healthyReport("node-b")
+				b.Modules["tcm_loop"] = ModuleMissing
+				b.Modules["configfs"] = ModuleMissing
+				b.Disk.AvailableBytes = 10 << 30
+				b.HugePages = &HugePages{Total: 512, Free: 12, SizeBytes: 2 << 20}
+				return []*NodeReport{a, b, {Node: "node-c", Error: "pod is Pending: ImagePullBackOff"}}
+			},
+			want: []*analyzer.AnalyzeResult{
+				{IsWarn: true, Title: "Host Checks", Message: "The host checks did not finish on 1 nodes: node-c (pod is Pending: ImagePullBackOff)."},
+				{IsFail: true, Title: "Kernel Modules", Message: "The tcm_loop kernel module is not installed on node-a, node-b."},
+				{IsFail: true, Title: "Kernel Modules", Message: "The configfs kernel module is not installed on node-b."},
+				{IsFail: true, Title: "StorageOS Ports", Message: "StorageOS needs ports 5701-5711, which are in use on node-a (5701, 5705)."},
+				{IsWarn: true, Title: "Disk Space", Message: "StorageOS recommends at least 20Gi free for /var/lib/storageos, which 1 nodes do not have: node-b (10Gi free on /var/lib/storageos)."},
+				{IsWarn: true, Title: "Hugepages", Message: "Hugepages are configured but none are free on node-a."},
+			},
+		},
+		{
+			name: "no node finished",
+			reports: func() []*NodeReport {
+				return []*NodeReport{{Node: "node-a", Error: "checks did not finish"}}
+			},
+			want: []*analyzer.AnalyzeResult{
+				{IsWarn: true, Title: "Host Checks", Message: "The host checks did not finish on 1 nodes: node-a (checks did not finish)."},
+			},
+		},
+		{
+			name: "hugepages free",
+			reports: func() []*NodeReport {
+				a := healthyReport("node-a")
+				a.HugePages = &HugePages{Total: 512, Free: 12, SizeBytes: 2 << 20}
+				return []*NodeReport{a, healthyReport("node-b")}
+			},
+			running: true,
+			want: []*analyzer.AnalyzeResult{modulesPass, diskPass,
+				{IsPass: true, Title: "Hugepages", Message: "Hugepages are free on all 1 nodes that have them configured."},
+			},
+		},
+	}
+	for _, tt := range tests {
+		got := Analyze(tt.reports(), tt.running)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Analyze() =", tt.name)
+			for _, r := range got {
+				t.Errorf("  %+v", *r)
+			}
+		}
+	}
+}
+
+func TestAnalyzeFile(t *testing.T) {
+	results, err := AnalyzeFile(func(string) ([]byte, error) {
+		return nil, os.ErrNotExist
+	}, false)
+	if err != nil || results != nil {
+		t.Errorf("AnalyzeFile() of a bundle without host reports = %v, %v", results, err)
+	}
+
+	if _, err := AnalyzeFile(func(string) ([]byte, error) {
+		return nil, errors.New("permission denied")
+	}, false); err == nil {
+		t.Error("AnalyzeFile() succeeded when the reports could not be read")
+	}
+
+	results, err = AnalyzeFile(func(name string) ([]byte, error) {
+		if name != Filename {
+			t.Errorf("AnalyzeFile() read %s, want %s", name, Filename)
+		}
+		return []byte(`[{"node": "node-a", "error": "checks did not finish"}]`), nil
+	}, false)
+	if err != nil || len(results) != 1 || !results[0].IsWarn {
+		t.Errorf("AnalyzeFile() = %v, %v, want a warning", results, err)
+	}
+}
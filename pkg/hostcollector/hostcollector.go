@@ -0,0 +1,239 @@
+// Package hostcollector checks the hosts of a cluster for what StorageOS
+// needs from them, by running a short-lived privileged DaemonSet that
+// reports on its node.
+package hostcollector
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// DefaultImage is the image the checks run in if no other is given. It needs
+// a shell with awk, df and grep.
+const DefaultImage = "busybox:1.32"
+
+// Filename is the name of the node reports in a support bundle.
+const Filename = "host/nodes.json"
+
+// CollectorName is the name of the host collector in the bundle manifest.
+const CollectorName = "storageos-host"
+
+// DefaultTimeout is how long to wait for the nodes to report if no timeout is
+// given.
+const DefaultTimeout = 2 * time.Minute
+
+const (
+	appLabel     = "app"
+	appName      = "storageos-host-collector"
+	runLabel     = "storageos.com/host-collector-run"
+	pollInterval = 2 * time.Second
+	// createTimeout and cleanupTimeout bound the creation and deletion of
+	// the DaemonSet, which are not cancelled with the collection context.
+	createTimeout  = 30 * time.Second
+	cleanupTimeout = 30 * time.Second
+)
+
+// Options configure the DaemonSet.
+type Options struct {
+	// Namespace the DaemonSet is created in.
+	Namespace  string
+	Image      string
+	PullPolicy corev1.PullPolicy
+	// Timeout is how long to wait for every node to report, DefaultTimeout if
+	// zero. Nodes that have not reported by then are returned with an error.
+	Timeout time.Duration
+}
+
+// Collect runs the host checks on every schedulable node and returns a
+// report for each. The DaemonSet is deleted before Collect returns, including
+// when ctx is cancelled.
+func Collect(ctx context.Context, config *rest.Config, opts Options) ([]*NodeReport, error) {
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create kubernetes client")
+	}
+
+	if opts.Namespace == "" {
+		opts.Namespace = metav1.NamespaceDefault
+	}
+	if opts.Image == "" {
+		opts.Image = DefaultImage
+	}
+	if opts.PullPolicy == "" {
+		opts.PullPolicy = corev1.PullIfNotPresent
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	ds, err := createDaemonSet(client, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "create host collector daemonset")
+	}
+	defer deleteDaemonSet(client, ds)
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	return waitForReports(ctx, client, ds)
+}
+
+// createDaemonSet creates the DaemonSet with a context of its own, so that a
+// DaemonSet the server created is always returned to be deleted, even if
+// collection is interrupted meanwhile.
+func createDaemonSet(client kubernetes.Interface, opts Options) (*appsv1.DaemonSet, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), createTimeout)
+	defer cancel()
+
+	return client.AppsV1().DaemonSets(opts.Namespace).Create(ctx, daemonSet(opts), metav1.CreateOptions{})
+}
+
+// deleteDaemonSet deletes the DaemonSet and its pods with a context of its
+// own, so that it runs after collection was interrupted.
+func deleteDaemonSet(client kubernetes.Interface, ds *appsv1.DaemonSet) {
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+
+	propagation := metav1.DeletePropagationForeground
+	client.AppsV1().DaemonSets(ds.Namespace).Delete(ctx, ds.Name, metav1.DeleteOptions{
+		PropagationPolicy: &propagation,
+	})
+}
+
+// waitForReports polls the DaemonSet's pods until each has reported, or ctx
+// is done. Nodes without a report by then get one with the reason.
+func waitForReports(ctx context.Context, client kubernetes.Interface, ds *appsv1.DaemonSet) ([]*NodeReport, error) {
+	selector := metav1.FormatLabelSelector(ds.Spec.Selector)
+	reports := map[string]*NodeReport{}
+	pending := map[string]string{}
+
+	for {
+		pods, err := client.CoreV1().Pods(ds.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+		if err != nil && ctx.Err() == nil {
+			return nil, errors.Wrap(err, "list host collector pods")
+		}
+		if pods != nil {
+			for _, pod := range pods.Items {
+				node := pod.Spec.NodeName
+				if node == "" || reports[node] != nil {
+					continue
+				}
+				report, reason := podReport(ctx, client, &pod)
+				if report != nil {
+					reports[node] = report
+					delete(pending, node)
+				} else {
+					pending[node] = reason
+				}
+			}
+		}
+
+		current, err := client.AppsV1().DaemonSets(ds.Namespace).Get(ctx, ds.Name, metav1.GetOptions{})
+		done := err == nil && current.Status.DesiredNumberScheduled > 0 && len(reports) >= int(current.Status.DesiredNumberScheduled)
+		if done {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			for node, reason := range pending {
+				reports[node] = &NodeReport{Node: node, Error: reason}
+			}
+			return sortedReports(reports), nil
+		case <-time.After(pollInterval):
+		}
+	}
+
+	return sortedReports(reports), nil
+}
+
+// podReport returns the report of a pod that has finished its checks, or
+// why it has not.
+func podReport(ctx context.Context, client kubernetes.Interface, pod *corev1.Pod) (*NodeReport, string) {
+	if pod.Status.Phase != corev1.PodRunning {
+		reason := "pod is " + string(pod.Status.Phase)
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.State.Waiting != nil && status.State.Waiting.Reason != "" {
+				reason += ": " + status.State.Waiting.Reason
+			}
+		}
+		return nil, reason
+	}
+
+	logs, err := client.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{}).DoRaw(ctx)
+	if err != nil {
+		return nil, "failed to get pod logs: " + err.Error()
+	}
+
+	report, complete := parseReport(pod.Spec.NodeName, logs)
+	if !complete {
+		return nil, "checks did not finish"
+	}
+	return report, ""
+}
+
+func daemonSet(opts Options) *appsv1.DaemonSet {
+	// pods of concurrent runs are told apart by their run
+	labels := map[string]string{
+		appLabel: appName,
+		runLabel: strconv.FormatInt(time.Now().UnixNano(), 36),
+	}
+	privileged := true
+
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: appName + "-",
+			Namespace:    opts.Namespace,
+			Labels:       labels,
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					// the host network namespace shows which ports are in use
+					HostNetwork: true,
+					// run on every node StorageOS may run on, tainted or not
+					Tolerations: []corev1.Toleration{
+						{Operator: corev1.TolerationOpExists},
+					},
+					Containers: []corev1.Container{
+						{
+							Name:            "host-collector",
+							Image:           opts.Image,
+							ImagePullPolicy: opts.PullPolicy,
+							Command:         []string{"/bin/sh", "-c", script},
+							SecurityContext: &corev1.SecurityContext{
+								Privileged: &privileged,
+							},
+							VolumeMounts: []corev1.VolumeMount{
+								{Name: "host", MountPath: hostRoot, ReadOnly: true},
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: "host",
+							VolumeSource: corev1.VolumeSource{
+								HostPath: &corev1.HostPathVolumeSource{Path: "/"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
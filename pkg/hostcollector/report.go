@@ -0,0 +1,141 @@
+package hostcollector
+
+import (
+	"bufio"
+	"bytes"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// hostRoot is where the host's root filesystem is mounted in the pod.
+const hostRoot = "/host"
+
+// Modules are the kernel modules StorageOS needs.
+var Modules = []string{"target_core_user", "tcm_loop", "configfs"}
+
+// FirstPort and LastPort are the range of ports StorageOS listens on.
+const (
+	FirstPort = 5701
+	LastPort  = 5711
+)
+
+// DataDir is where StorageOS stores its data on the host.
+const DataDir = "/var/lib/storageos"
+
+// ModuleStatus is whether a kernel module is loaded, can be loaded, or is
+// not installed.
+type ModuleStatus string
+
+const (
+	ModuleLoaded    ModuleStatus = "loaded"
+	ModuleAvailable ModuleStatus = "available"
+	ModuleMissing   ModuleStatus = "missing"
+)
+
+// NodeReport is what the checks found on a node.
+type NodeReport struct {
+	Node    string                  `json:"node"`
+	Modules map[string]ModuleStatus `json:"modules,omitempty"`
+	// PortsInUse are the StorageOS ports something is listening on.
+	PortsInUse []int      `json:"portsInUse,omitempty"`
+	Disk       *Disk      `json:"disk,omitempty"`
+	HugePages  *HugePages `json:"hugePages,omitempty"`
+	// Error is why the node has no report.
+	Error string `json:"error,omitempty"`
+}
+
+// Disk is the filesystem StorageOS stores its data on: DataDir if it exists,
+// or its parent.
+type Disk struct {
+	Path           string `json:"path"`
+	TotalBytes     int64  `json:"totalBytes"`
+	AvailableBytes int64  `json:"availableBytes"`
+}
+
+type HugePages struct {
+	Total     int64 `json:"total"`
+	Free      int64 `json:"free"`
+	SizeBytes int64 `json:"sizeBytes"`
+}
+
+// script prints a line for each check, then "done", and sleeps so that the
+// DaemonSet does not restart it before its logs are read.
+var script = `
+for m in ` + strings.Join(Modules, " ") + `; do
+  if [ -d /sys/module/$m ]; then
+    s=` + string(ModuleLoaded) + `
+  elif grep -qE "/$m\.ko" ` + hostRoot + `/lib/modules/$(uname -r)/modules.dep ` + hostRoot + `/lib/modules/$(uname -r)/modules.builtin 2>/dev/null; then
+    s=` + string(ModuleAvailable) + `
+  else
+    s=` + string(ModuleMissing) + `
+  fi
+  echo "module $m $s"
+done
+for p in $(seq ` + strconv.Itoa(FirstPort) + ` ` + strconv.Itoa(LastPort) + `); do
+  h=$(printf '%04X' $p)
+  if awk '{print $2, $4}' /proc/net/tcp /proc/net/tcp6 2>/dev/null | grep -q ":$h 0A"; then
+    echo "port $p"
+  fi
+done
+d=` + DataDir + `
+[ -d ` + hostRoot + `$d ] || d=$(dirname $d)
+df -Pk ` + hostRoot + `$d | awk -v d=$d 'NR==2 {print "disk", d, $2, $4}'
+awk '/^HugePages_Total:/ {t=$2} /^HugePages_Free:/ {f=$2} /^Hugepagesize:/ {s=$2} END {print "hugepages", t, f, s}' /proc/meminfo
+echo done
+sleep 3600
+`
+
+// parseReport parses the output of script. complete is false until the
+// script has printed "done".
+func parseReport(node string, output []byte) (report *NodeReport, complete bool) {
+	report = &NodeReport{
+		Node:    node,
+		Modules: map[string]ModuleStatus{},
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch {
+		case fields[0] == "done":
+			complete = true
+		case fields[0] == "module" && len(fields) == 3:
+			report.Modules[fields[1]] = ModuleStatus(fields[2])
+		case fields[0] == "port" && len(fields) == 2:
+			if port, err := strconv.Atoi(fields[1]); err == nil {
+				report.PortsInUse = append(report.PortsInUse, port)
+			}
+		case fields[0] == "disk" && len(fields) == 4:
+			total, errTotal := strconv.ParseInt(fields[2], 10, 64)
+			available, errAvailable := strconv.ParseInt(fields[3], 10, 64)
+			if errTotal == nil && errAvailable == nil {
+				report.Disk = &Disk{Path: fields[1], TotalBytes: total * 1024, AvailableBytes: available * 1024}
+			}
+		case fields[0] == "hugepages" && len(fields) == 4:
+			total, errTotal := strconv.ParseInt(fields[1], 10, 64)
+			free, errFree := strconv.ParseInt(fields[2], 10, 64)
+			size, errSize := strconv.ParseInt(fields[3], 10, 64)
+			if errTotal == nil && errFree == nil && errSize == nil {
+				report.HugePages = &HugePages{Total: total, Free: free, SizeBytes: size * 1024}
+			}
+		}
+	}
+
+	return report, complete
+}
+
+func sortedReports(reports map[string]*NodeReport) []*NodeReport {
+	sorted := make([]*NodeReport, 0, len(reports))
+	for _, report := range reports {
+		sorted = append(sorted, report)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Node < sorted[j].Node
+	})
+	return sorted
+}
@@ -0,0 +1,76 @@
+package hostcollector
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseReport(t *testing.T) {
+	tests := []struct {
+		name         string
+		output       string
+		want         *NodeReport
+		wantComplete bool
+	}{
+		{
+			name: "complete",
+			output: `module target_core_user loaded
+module tcm_loop available
+module configfs missing
+port 5701
+port 5705
+disk /var/lib/storageos 104857600 52428800
+hugepages 512 128 2048
+done
+`,
+			want: &NodeReport{
+				Node: "node-a",
+				Modules: map[string]ModuleStatus{
+					"target_core_user": ModuleLoaded,
+					"tcm_loop":         ModuleAvailable,
+					"configfs":         ModuleMissing,
+				},
+				PortsInUse: []int{5701, 5705},
+				Disk:       &Disk{Path: "/var/lib/storageos", TotalBytes: 104857600 * 1024, AvailableBytes: 52428800 * 1024},
+				HugePages:  &HugePages{Total: 512, Free: 128, SizeBytes: 2048 * 1024},
+			},
+			wantComplete: true,
+		},
+		{
+			name:   "not finished",
+			output: "module target_core_user loaded\nport 5701\n",
+			want: &NodeReport{
+				Node:       "node-a",
+				Modules:    map[string]ModuleStatus{"target_core_user": ModuleLoaded},
+				PortsInUse: []int{5701},
+			},
+		},
+		{
+			// e.g. /proc/meminfo without hugepages, or df failing
+			name: "malformed lines are skipped",
+			output: `module target_core_user
+port http
+disk /var/lib 100
+disk /var/lib total 100
+hugepages   
+hugepages 0 0 kB
+
+done
+`,
+			want: &NodeReport{
+				Node:    "node-a",
+				Modules: map[string]ModuleStatus{},
+			},
+			wantComplete: true,
+		},
+	}
+	for _, tt := range tests {
+		report, complete := parseReport("node-a", []byte(tt.output))
+		if complete != tt.wantComplete {
+			t.Errorf("%s: parseReport() complete = %v, want %v", tt.name, complete, tt.wantComplete)
+		}
+		if !reflect.DeepEqual(report, tt.want) {
+			t.Errorf("%s: parseReport() = %+v, want %+v", tt.name, report, tt.want)
+		}
+	}
+}
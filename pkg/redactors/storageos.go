@@ -0,0 +1,38 @@
+package redactors
+
+// StorageOS is a troubleshoot Redactor spec that removes StorageOS secrets from
+// collected files. It is applied by default, in addition to the troubleshoot
+// default redactors and any redactors given on the command line.
+//
+// Regex redactors replace the capture group named "mask" and keep every other
+// unnamed group, so alternatives inside the key are non-capturing.
+const StorageOS = `apiVersion: troubleshoot.sh/v1beta2
+kind: Redactor
+metadata:
+  name: storageos
+spec:
+  redactors:
+  - name: storageos api credentials
+    removals:
+      regex:
+      - redactor: '(?i)("?api-?(?:username|password)"?\s*[:=]\s*"?)(?P<mask>[^"\s,}]+)'
+  - name: storageos etcd tls material
+    removals:
+      regex:
+      - redactor: '(?i)("?etcd-(?:client\.key|client\.crt|client-ca\.crt|ca\.crt)"?\s*[:=]\s*"?)(?P<mask>[^"\s,}]+)'
+      - redactor: '(?i)("?tls\.(?:key|crt)"?\s*[:=]\s*"?)(?P<mask>[^"\s,}]+)'
+      - redactor: '(?P<mask>LS0tLS1CRUdJT[A-Za-z0-9+/=]+)'
+  - name: storageos csi secret references
+    removals:
+      regex:
+      - redactor: '(?i)("?csi\.storage\.k8s\.io/[a-z-]+-secret-name"?\s*[:=]\s*"?)(?P<mask>[^"\s,}]+)'
+      - redactor: '(?i)("?csi[a-z]*(?:username|password)"?\s*[:=]\s*"?)(?P<mask>[^"\s,}]+)'
+  - name: storageos license keys
+    removals:
+      regex:
+      - redactor: '(?i)("?license-?key"?\s*[:=]\s*"?)(?P<mask>[^"\s,}]+)'
+  - name: storageos admin passwords
+    removals:
+      regex:
+      - redactor: '(?i)("?admin-?(?:username|password)"?\s*[:=]\s*"?)(?P<mask>[^"\s,}]+)'
+`
@@ -0,0 +1,65 @@
+//go:build ignore
+// +build ignore
+
+// generate writes the default signature database into
+// signatures_generated.go as a string constant, so that it is compiled into
+// the binary.
+package main
+
+import (
+	"bytes"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"strings"
+	"text/template"
+)
+
+var databases = []struct {
+	Name string
+	Path string
+}{
+	{"Default", "../../examples/signatures.yaml"},
+}
+
+var tmpl = template.Must(template.New("signatures").Parse(`// Code generated by generate.go. DO NOT EDIT.
+
+package signatures
+{{range .}}
+// {{.Name}} is the content of {{.Path}}.
+const {{.Name}} = ` + "`{{.Content}}`" + `
+{{end}}`))
+
+func main() {
+	type database struct {
+		Name    string
+		Path    string
+		Content string
+	}
+
+	data := []database{}
+	for _, d := range databases {
+		b, err := ioutil.ReadFile(d.Path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if strings.Contains(string(b), "`") {
+			log.Fatalf("%s contains a backtick, which cannot be embedded in a raw string", d.Path)
+		}
+		data = append(data, database{Name: d.Name, Path: strings.TrimPrefix(d.Path, "../../"), Content: string(b)})
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		log.Fatal(err)
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile("signatures_generated.go", src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
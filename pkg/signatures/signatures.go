@@ -0,0 +1,231 @@
+// Package signatures searches the logs in a support bundle for known issues,
+// described by a database of regular expressions.
+package signatures
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	analyzer "github.com/replicatedhq/troubleshoot/pkg/analyze"
+	"gopkg.in/yaml.v2"
+
+	"github.com/croomes/kubectl-plugin/pkg/timeline"
+)
+
+//go:generate go run generate.go
+
+// Severities of a signature.
+const (
+	SeverityFail = "fail"
+	SeverityWarn = "warn"
+)
+
+// maxListedFiles is how many of the logs a known issue was found in are named
+// in its result.
+const maxListedFiles = 5
+
+// Signature is a known issue, found by a regex in the logs of a component.
+type Signature struct {
+	ID    string `yaml:"id"`
+	Title string `yaml:"title"`
+	// Component is part of the path of the logs to search, e.g. daemonset.
+	// Every log is searched if it is empty.
+	Component   string `yaml:"component,omitempty"`
+	Regex       string `yaml:"regex"`
+	Severity    string `yaml:"severity"`
+	Explanation string `yaml:"explanation"`
+	URI         string `yaml:"uri,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// Database is a list of signatures.
+type Database struct {
+	Signatures []*Signature `yaml:"signatures"`
+}
+
+// Parse parses and validates a signature database.
+func Parse(b []byte) (*Database, error) {
+	db := &Database{}
+	if err := yaml.UnmarshalStrict(b, db); err != nil {
+		return nil, errors.Wrap(err, "parse signatures")
+	}
+
+	ids := map[string]bool{}
+	for i, s := range db.Signatures {
+		if s.ID == "" {
+			return nil, fmt.Errorf("signature %d has no id", i)
+		}
+		if ids[s.ID] {
+			return nil, fmt.Errorf("signature %s is defined more than once", s.ID)
+		}
+		ids[s.ID] = true
+
+		if s.Title == "" {
+			s.Title = s.ID
+		}
+		if s.Severity != SeverityFail && s.Severity != SeverityWarn {
+			return nil, fmt.Errorf("signature %s has severity %q, must be one of %s, %s", s.ID, s.Severity, SeverityFail, SeverityWarn)
+		}
+
+		re, err := regexp.Compile(s.Regex)
+		if err != nil {
+			return nil, errors.Wrapf(err, "signature %s has an invalid regex", s.ID)
+		}
+		s.re = re
+	}
+	return db, nil
+}
+
+// Load returns the signature database in filename, or the default database if
+// filename is "".
+func Load(filename string) (*Database, error) {
+	if filename == "" {
+		return Parse([]byte(Default))
+	}
+
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "read signatures")
+	}
+	return Parse(b)
+}
+
+// occurrences are where a signature matched.
+type occurrences struct {
+	count       int
+	first, last time.Time
+	files       []string
+}
+
+// Scanner searches logs for the signatures of a database.
+type Scanner struct {
+	db *Database
+	// reference is when the bundle was collected, for timestamps without a
+	// year.
+	reference time.Time
+	found     map[string]*occurrences
+	logs      int
+}
+
+// NewScanner returns a scanner of the logs of a bundle collected at
+// reference.
+func NewScanner(db *Database, reference time.Time) *Scanner {
+	return &Scanner{
+		db:        db,
+		reference: reference,
+		found:     map[string]*occurrences{},
+	}
+}
+
+// Add searches a file of the bundle. name is the path of the file in the
+// bundle. Files other than logs are ignored.
+func (s *Scanner) Add(name string, r io.Reader) error {
+	if path.Ext(name) != ".log" || name == timeline.Filename {
+		return nil
+	}
+
+	sigs := []*Signature{}
+	for _, sig := range s.db.Signatures {
+		if strings.Contains(name, sig.Component) {
+			sigs = append(sigs, sig)
+		}
+	}
+	s.logs++
+	if len(sigs) == 0 {
+		return nil
+	}
+
+	// over-long lines are searched up to timeline.MaxLineLength
+	scanner := timeline.NewLineScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		for _, sig := range sigs {
+			if !sig.re.MatchString(line) {
+				continue
+			}
+			s.record(sig, name, line)
+		}
+	}
+	return errors.Wrapf(scanner.Err(), "read %s", name)
+}
+
+func (s *Scanner) record(sig *Signature, name string, line string) {
+	o, ok := s.found[sig.ID]
+	if !ok {
+		o = &occurrences{}
+		s.found[sig.ID] = o
+	}
+
+	o.count++
+	if len(o.files) == 0 || o.files[len(o.files)-1] != name {
+		o.files = append(o.files, name)
+	}
+	if ts, ok := timeline.LineTime(line, s.reference); ok {
+		if o.first.IsZero() || ts.Before(o.first) {
+			o.first = ts
+		}
+		if ts.After(o.last) {
+			o.last = ts
+		}
+	}
+}
+
+// Results returns a result for each known issue found, in the order of the
+// database, or a passing result if none were.
+func (s *Scanner) Results() []*analyzer.AnalyzeResult {
+	results := []*analyzer.AnalyzeResult{}
+	for _, sig := range s.db.Signatures {
+		o, ok := s.found[sig.ID]
+		if !ok {
+			continue
+		}
+		results = append(results, &analyzer.AnalyzeResult{
+			IsFail:  sig.Severity == SeverityFail,
+			IsWarn:  sig.Severity == SeverityWarn,
+			Title:   sig.Title,
+			Message: fmt.Sprintf("%s %s", sig.Explanation, o.describe()),
+			URI:     sig.URI,
+		})
+	}
+
+	if len(results) == 0 {
+		results = append(results, &analyzer.AnalyzeResult{
+			IsPass:  true,
+			Title:   "Known Issues",
+			Message: fmt.Sprintf("None of the %d known issues were found in %d logs.", len(s.db.Signatures), s.logs),
+		})
+	}
+	return results
+}
+
+func (o *occurrences) describe() string {
+	times := "time"
+	if o.count > 1 {
+		times = "times"
+	}
+
+	files := append([]string{}, o.files...)
+	sort.Strings(files)
+	listed := strings.Join(files, ", ")
+	if len(files) > maxListedFiles {
+		listed = fmt.Sprintf("%s and %d more", strings.Join(files[:maxListedFiles], ", "), len(files)-maxListedFiles)
+	}
+
+	msg := fmt.Sprintf("Seen %d %s in %s", o.count, times, listed)
+	switch {
+	case o.first.IsZero():
+	case o.first.Equal(o.last):
+		msg += fmt.Sprintf(", at %s", o.first.Format(time.RFC3339))
+	default:
+		msg += fmt.Sprintf(", first at %s and last at %s", o.first.Format(time.RFC3339), o.last.Format(time.RFC3339))
+	}
+	return msg + "."
+}
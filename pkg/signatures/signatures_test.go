@@ -0,0 +1,203 @@
+package signatures
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	analyzer "github.com/replicatedhq/troubleshoot/pkg/analyze"
+
+	"github.com/croomes/kubectl-plugin/pkg/timeline"
+)
+
+const testDatabase = `signatures:
+  - id: etcd-unreachable
+    title: etcd Unreachable
+    component: daemonset
+    regex: 'etcd.*connection refused'
+    severity: fail
+    explanation: StorageOS nodes could not reach etcd.
+    uri: https://docs.storageos.com/docs/prerequisites/etcd/
+  - id: disk-full
+    regex: 'no space left on device'
+    severity: warn
+    explanation: A disk is full.
+`
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		db      string
+		wantErr string
+	}{
+		{name: "valid", db: testDatabase},
+		{name: "empty", db: ""},
+		{name: "unknown field", db: "signatures:\n  - id: a\n    severity: fail\n    regexp: a\n", wantErr: "parse signatures"},
+		{name: "no id", db: "signatures:\n  - severity: fail\n    regex: a\n", wantErr: "signature 0 has no id"},
+		{name: "duplicate id", db: "signatures:\n  - id: a\n    severity: fail\n  - id: a\n    severity: warn\n", wantErr: "signature a is defined more than once"},
+		{name: "unknown severity", db: "signatures:\n  - id: a\n    severity: error\n", wantErr: `signature a has severity "error", must be one of fail, warn`},
+		{name: "invalid regex", db: "signatures:\n  - id: a\n    severity: fail\n    regex: '(a'\n", wantErr: "signature a has an invalid regex"},
+	}
+	for _, tt := range tests {
+		_, err := Parse([]byte(tt.db))
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s: Parse() error = %v", tt.name, err)
+		case tt.wantErr != "" && (err == nil || !strings.HasPrefix(err.Error(), tt.wantErr)):
+			t.Errorf("%s: Parse() error = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+
+	db, err := Parse([]byte(testDatabase))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title := db.Signatures[1].Title; title != "disk-full" {
+		t.Errorf("Title of a signature without one = %q, want its id", title)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	db, err := Load("")
+	if err != nil {
+		t.Fatalf("Load() of the default signatures error = %v", err)
+	}
+	if len(db.Signatures) == 0 {
+		t.Error("Load() of the default signatures returned none")
+	}
+
+	dir, err := ioutil.TempDir("", "signatures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "signatures.yaml")
+	if err := ioutil.WriteFile(filename, []byte(testDatabase), 0644); err != nil {
+		t.Fatal(err)
+	}
+	db, err = Load(filename)
+	if err != nil {
+		t.Fatalf("Load(%s) error = %v", filename, err)
+	}
+	if len(db.Signatures) != 2 {
+		t.Errorf("Load(%s) returned %d signatures, want 2", filename, len(db.Signatures))
+	}
+
+	if _, err := Load(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("Load() of a missing file succeeded")
+	}
+}
+
+func TestScanner(t *testing.T) {
+	db, err := Parse([]byte(testDatabase))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	refused := `time="2020-06-01T10:00:00Z" level=error msg="etcd connection refused"` + "\n"
+	files := map[string]string{
+		"storageos/logs/storageos-daemonset-a.log": refused +
+			`time="2020-06-01T10:05:00Z" level=error msg="etcd connection refused"` + "\n",
+		"storageos/logs/storageos-daemonset-b.log": "E0601 09:58:00.000000       1 kv.go:12] etcd connection refused\n",
+		// only the logs of the signature's component are searched
+		"storageos/logs/storageos-api-manager.log": refused,
+		// the timeline repeats the other logs, and other files aren't logs
+		"timeline.log":             "2020-06-01T10:00:00.000000Z [storageos/logs/storageos-daemonset-a] " + refused,
+		"storageos/daemonset.json": refused,
+	}
+
+	s := NewScanner(db, time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC))
+	for name, content := range files {
+		if err := s.Add(name, strings.NewReader(content)); err != nil {
+			t.Fatalf("Add(%s) error = %v", name, err)
+		}
+	}
+
+	results := s.Results()
+	want := []*analyzer.AnalyzeResult{{
+		IsFail: true,
+		Title:  "etcd Unreachable",
+		Message: "StorageOS nodes could not reach etcd. Seen 3 times in storageos/logs/storageos-daemonset-a.log, storageos/logs/storageos-daemonset-b.log, " +
+			"first at 2020-06-01T09:58:00Z and last at 2020-06-01T10:05:00Z.",
+		URI: "https://docs.storageos.com/docs/prerequisites/etcd/",
+	}}
+	if len(results) != len(want) {
+		t.Fatalf("Results() = %d results, want %d", len(results), len(want))
+	}
+	if *results[0] != *want[0] {
+		t.Errorf("Results() = %+v, want %+v", results[0], want[0])
+	}
+}
+
+func TestScannerNothingFound(t *testing.T) {
+	db, err := Parse([]byte(testDatabase))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewScanner(db, time.Time{})
+	for _, name := range []string{"storageos/logs/storageos-daemonset-a.log", "storageos/logs/storageos-scheduler.log"} {
+		if err := s.Add(name, strings.NewReader("level=info msg=\"started\"\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results := s.Results()
+	if len(results) != 1 || !results[0].IsPass {
+		t.Fatalf("Results() = %+v, want a single pass", results)
+	}
+	if want := "None of the 2 known issues were found in 2 logs."; results[0].Message != want {
+		t.Errorf("Results() message = %q, want %q", results[0].Message, want)
+	}
+}
+
+func TestScannerLongLines(t *testing.T) {
+	db, err := Parse([]byte(testDatabase))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a line longer than the scan buffer is searched up to the limit, and the
+	// lines after it are still searched
+	log := "write failed: no space left on device " + strings.Repeat("x", 2*timeline.MaxLineLength) + "\n" +
+		`time="2020-06-01T10:00:00Z" level=error msg="etcd connection refused"` + "\n"
+	s := NewScanner(db, time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC))
+	if err := s.Add("storageos/logs/storageos-daemonset-a.log", strings.NewReader(log)); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	results := s.Results()
+	if len(results) != 2 || !results[0].IsFail || !results[1].IsWarn {
+		t.Errorf("Results() = %+v, want etcd unreachable and disk full", results)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	at := time.Date(2020, 6, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		o    occurrences
+		want string
+	}{
+		{
+			o:    occurrences{count: 1, files: []string{"a.log"}},
+			want: "Seen 1 time in a.log.",
+		},
+		{
+			o:    occurrences{count: 2, first: at, last: at, files: []string{"b.log", "a.log"}},
+			want: "Seen 2 times in a.log, b.log, at 2020-06-01T10:00:00Z.",
+		},
+		{
+			o:    occurrences{count: 7, files: []string{"g.log", "f.log", "e.log", "d.log", "c.log", "b.log", "a.log"}},
+			want: "Seen 7 times in a.log, b.log, c.log, d.log, e.log and 2 more.",
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.o.describe(); got != tt.want {
+			t.Errorf("describe() = %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+// Package speclint checks troubleshoot specs for mistakes that the
+// troubleshoot decoder either accepts silently or reports without saying
+// where they are.
+package speclint
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	troubleshootv1beta2 "github.com/replicatedhq/troubleshoot/pkg/apis/troubleshoot/v1beta2"
+	troubleshootclientsetscheme "github.com/replicatedhq/troubleshoot/pkg/client/troubleshootclientset/scheme"
+	"github.com/replicatedhq/troubleshoot/pkg/docrewrite"
+	"gopkg.in/yaml.v3"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// Severity of a Diagnostic. Specs with errors fail to load or silently skip
+// checks; warnings are likely mistakes.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic is a problem found in a spec, at a line of the linted content.
+type Diagnostic struct {
+	Line     int
+	Severity Severity
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d: %s: %s", d.Line, d.Severity, d.Message)
+}
+
+// HasErrors returns true if any of the diagnostics is an error.
+func HasErrors(diagnostics []Diagnostic) bool {
+	for _, d := range diagnostics {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	knownAPIVersions = []string{"troubleshoot.sh/v1beta2", "troubleshoot.replicated.com/v1beta1"}
+	knownKinds       = []string{"Analyzer", "Collector", "Preflight", "Redactor", "SupportBundle"}
+
+	knownCollectors = fieldNames(troubleshootv1beta2.Collect{})
+	knownAnalyzers  = fieldNames(troubleshootv1beta2.Analyze{})
+	knownOutcomes   = fieldNames(troubleshootv1beta2.Outcome{})
+
+	yamlErrorLine = regexp.MustCompile(`line (\d+)`)
+)
+
+type linter struct {
+	diagnostics []Diagnostic
+}
+
+// Lint checks every document in content, and returns the problems found
+// ordered by line.
+func Lint(content []byte) []Diagnostic {
+	l := &linter{}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		doc := yaml.Node{}
+		err := decoder.Decode(&doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// the decoder cannot continue past a syntax error
+			l.errorf(syntaxErrorLine(err), "%v", err)
+			break
+		}
+		if len(doc.Content) == 0 {
+			continue
+		}
+		l.lintDocument(doc.Content[0])
+	}
+
+	sort.SliceStable(l.diagnostics, func(i, j int) bool {
+		return l.diagnostics[i].Line < l.diagnostics[j].Line
+	})
+	return l.diagnostics
+}
+
+func (l *linter) errorf(line int, format string, args ...interface{}) {
+	l.diagnostics = append(l.diagnostics, Diagnostic{Line: line, Severity: SeverityError, Message: fmt.Sprintf(format, args...)})
+}
+
+func (l *linter) warnf(line int, format string, args ...interface{}) {
+	l.diagnostics = append(l.diagnostics, Diagnostic{Line: line, Severity: SeverityWarning, Message: fmt.Sprintf(format, args...)})
+}
+
+func (l *linter) lintDocument(root *yaml.Node) {
+	if root.Kind != yaml.MappingNode {
+		l.errorf(root.Line, "document is not a mapping")
+		return
+	}
+
+	apiVersion := mappingValue(root, "apiVersion")
+	if apiVersion == nil {
+		l.errorf(root.Line, "missing apiVersion")
+	} else if !contains(knownAPIVersions, apiVersion.Value) {
+		l.errorf(apiVersion.Line, "unknown apiVersion %q, must be one of %s", apiVersion.Value, strings.Join(knownAPIVersions, ", "))
+	}
+
+	kindNode := mappingValue(root, "kind")
+	if kindNode == nil {
+		l.errorf(root.Line, "missing kind")
+		return
+	}
+	kind := kindNode.Value
+	if !contains(knownKinds, kind) {
+		l.errorf(kindNode.Line, "unknown kind %q, must be one of %s", kind, strings.Join(knownKinds, ", "))
+		return
+	}
+
+	if apiVersion != nil && contains(knownAPIVersions, apiVersion.Value) {
+		if err := decode(root); err != nil {
+			l.errorf(root.Line, "failed to decode %s: %v", kind, err)
+		}
+	}
+
+	spec := mappingValue(root, "spec")
+	if spec == nil {
+		l.errorf(root.Line, "%s has no spec", kind)
+		return
+	}
+
+	switch kind {
+	case "SupportBundle", "Preflight":
+		l.lintCollectors(mappingValue(spec, "collectors"))
+		l.lintAnalyzers(mappingValue(spec, "analyzers"))
+	case "Collector":
+		l.lintCollectors(mappingValue(spec, "collectors"))
+	case "Analyzer":
+		l.lintAnalyzers(mappingValue(spec, "analyzers"))
+	case "Redactor":
+		l.lintRedactors(mappingValue(spec, "redactors"))
+	}
+}
+
+func (l *linter) lintCollectors(collectors *yaml.Node) {
+	if collectors == nil || collectors.Kind != yaml.SequenceNode {
+		return
+	}
+
+	for _, item := range collectors.Content {
+		typ, _, ok := l.singleKey(item, "collector")
+		if ok && !contains(knownCollectors, typ) {
+			l.errorf(item.Line, "unknown collector type %q", typ)
+		}
+	}
+}
+
+func (l *linter) lintAnalyzers(analyzers *yaml.Node) {
+	if analyzers == nil || analyzers.Kind != yaml.SequenceNode {
+		return
+	}
+
+	seen := map[string]int{}
+	for _, item := range analyzers.Content {
+		typ, body, ok := l.singleKey(item, "analyzer")
+		if !ok {
+			continue
+		}
+		if !contains(knownAnalyzers, typ) {
+			l.errorf(item.Line, "unknown analyzer type %q", typ)
+			continue
+		}
+
+		l.lintOutcomes(typ, item.Line, body)
+
+		key := typ + "/" + identity(body)
+		if first, ok := seen[key]; ok {
+			l.warnf(item.Line, "duplicate %s analyzer, first defined on line %d", typ, first)
+		} else {
+			seen[key] = item.Line
+		}
+	}
+}
+
+func (l *linter) lintOutcomes(typ string, line int, body *yaml.Node) {
+	outcomes := mappingValue(body, "outcomes")
+	if outcomes == nil || outcomes.Kind != yaml.SequenceNode || len(outcomes.Content) == 0 {
+		l.errorf(line, "%s analyzer has no outcomes", typ)
+		return
+	}
+
+	for _, item := range outcomes.Content {
+		outcome, single, ok := l.singleKey(item, "outcome")
+		if !ok {
+			continue
+		}
+		if !contains(knownOutcomes, outcome) {
+			l.errorf(item.Line, "unknown outcome %q, must be one of %s", outcome, strings.Join(knownOutcomes, ", "))
+			continue
+		}
+
+		if mappingValue(single, "message") == nil {
+			l.warnf(item.Line, "%s outcome has no message", outcome)
+		}
+
+		when := mappingValue(single, "when")
+		if when == nil {
+			continue
+		}
+		if err := checkWhen(typ, when.Value); err != nil {
+			l.errorf(when.Line, "invalid when %q for %s analyzer: %v", when.Value, typ, err)
+		}
+	}
+}
+
+func (l *linter) lintRedactors(redactors *yaml.Node) {
+	if redactors == nil || redactors.Kind != yaml.SequenceNode {
+		return
+	}
+
+	for _, item := range redactors.Content {
+		regexes := mappingValue(mappingValue(item, "removals"), "regex")
+		if regexes == nil || regexes.Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, regex := range regexes.Content {
+			for _, field := range []string{"selector", "redactor"} {
+				expr := mappingValue(regex, field)
+				if expr == nil {
+					continue
+				}
+				if _, err := regexp.Compile(expr.Value); err != nil {
+					l.errorf(expr.Line, "invalid regex %s: %v", field, err)
+				}
+			}
+		}
+	}
+}
+
+// singleKey returns the key and value of a list item that must be a mapping
+// with exactly one key, such as a collector, analyzer or outcome.
+func (l *linter) singleKey(item *yaml.Node, what string) (string, *yaml.Node, bool) {
+	if item.Kind != yaml.MappingNode || len(item.Content) != 2 {
+		l.errorf(item.Line, "%s must be a mapping with a single type", what)
+		return "", nil, false
+	}
+	return item.Content[0].Value, item.Content[1], true
+}
+
+// mappingValue returns the value of key in a mapping node, or nil if node is
+// nil or not a mapping, so that lookups can be chained.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// identity returns a canonical form of an analyzer without its outcomes, so
+// that analyzers of the same thing can be found whatever their key order.
+func identity(body *yaml.Node) string {
+	var v map[string]interface{}
+	if err := body.Decode(&v); err != nil {
+		return ""
+	}
+	delete(v, "outcomes")
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// decode decodes the document the way the commands do, to catch fields of
+// the wrong type.
+func decode(root *yaml.Node) error {
+	b, err := yaml.Marshal(root)
+	if err != nil {
+		return err
+	}
+
+	b, err = docrewrite.ConvertToV1Beta2(b)
+	if err != nil {
+		return err
+	}
+
+	troubleshootclientsetscheme.AddToScheme(scheme.Scheme)
+	_, _, err = scheme.Codecs.UniversalDeserializer().Decode(b, nil, nil)
+	return err
+}
+
+func syntaxErrorLine(err error) int {
+	if m := yamlErrorLine.FindStringSubmatch(err.Error()); m != nil {
+		if line, err := strconv.Atoi(m[1]); err == nil {
+			return line
+		}
+	}
+	return 0
+}
+
+// fieldNames returns the json names of the fields of a struct.
+func fieldNames(v interface{}) []string {
+	names := []string{}
+	t := reflect.TypeOf(v)
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,196 @@
+package speclint
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/croomes/kubectl-plugin/pkg/defaultspecs"
+	"github.com/croomes/kubectl-plugin/pkg/specloader"
+)
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+		want []Diagnostic
+	}{
+		{
+			name: "valid",
+			spec: `apiVersion: troubleshoot.sh/v1beta2
+kind: Preflight
+metadata:
+  name: valid
+spec:
+  analyzers:
+    - clusterVersion:
+        outcomes:
+          - fail:
+              when: "< 1.15.0"
+              message: Kubernetes 1.15 or later is required
+          - pass:
+              message: Kubernetes version is supported
+`,
+		},
+		{
+			name: "syntax error",
+			spec: "apiVersion: troubleshoot.sh/v1beta2\nkind: Preflight\nspec:\n\t- analyzers\n",
+			want: []Diagnostic{{Line: 4, Severity: SeverityError, Message: "yaml: line 4: found character that cannot start any token"}},
+		},
+		{
+			name: "unknown apiVersion and kind",
+			spec: "apiVersion: troubleshoot.sh/v1\nkind: Preflights\nspec: {}\n",
+			want: []Diagnostic{
+				{Line: 1, Severity: SeverityError, Message: `unknown apiVersion "troubleshoot.sh/v1", must be one of troubleshoot.sh/v1beta2, troubleshoot.replicated.com/v1beta1`},
+				{Line: 2, Severity: SeverityError, Message: `unknown kind "Preflights", must be one of Analyzer, Collector, Preflight, Redactor, SupportBundle`},
+			},
+		},
+		{
+			name: "missing spec",
+			spec: "apiVersion: troubleshoot.sh/v1beta2\nkind: SupportBundle\n",
+			want: []Diagnostic{{Line: 1, Severity: SeverityError, Message: "SupportBundle has no spec"}},
+		},
+		{
+			name: "unknown collector",
+			spec: `apiVersion: troubleshoot.sh/v1beta2
+kind: SupportBundle
+spec:
+  collectors:
+    - clusterInfo: {}
+    - podLog: {}
+    - logs: {}
+      secret: {}
+`,
+			want: []Diagnostic{
+				{Line: 6, Severity: SeverityError, Message: `unknown collector type "podLog"`},
+				{Line: 7, Severity: SeverityError, Message: "collector must be a mapping with a single type"},
+			},
+		},
+		{
+			name: "analyzer outcomes",
+			spec: `apiVersion: troubleshoot.sh/v1beta2
+kind: Analyzer
+spec:
+  analyzers:
+    - clusterVersion: {}
+    - nodeResources:
+        outcomes:
+          - fail:
+              when: "min(memory) < 2Gi"
+              message: not enough memory
+          - maybe:
+              message: unknown
+          - pass: {}
+    - deploymentStatus:
+        name: api
+        outcomes:
+          - fail:
+              when: "<1"
+              message: down
+`,
+			want: []Diagnostic{
+				{Line: 5, Severity: SeverityError, Message: "clusterVersion analyzer has no outcomes"},
+				{Line: 9, Severity: SeverityError, Message: `invalid when "min(memory) < 2Gi" for nodeResources analyzer: unknown property "memory"`},
+				{Line: 11, Severity: SeverityError, Message: `unknown outcome "maybe", must be one of fail, warn, pass`},
+				{Line: 13, Severity: SeverityWarning, Message: "pass outcome has no message"},
+				{Line: 18, Severity: SeverityError, Message: `invalid when "<1" for deploymentStatus analyzer: expected an operator and a number separated by a space, e.g. "< 1"`},
+			},
+		},
+		{
+			name: "duplicate analyzer",
+			spec: `apiVersion: troubleshoot.sh/v1beta2
+kind: Preflight
+spec:
+  analyzers:
+    - deploymentStatus:
+        name: api
+        namespace: storageos
+        outcomes:
+          - pass:
+              message: up
+    - deploymentStatus:
+        namespace: storageos
+        name: api
+        outcomes:
+          - fail:
+              message: down
+    - deploymentStatus:
+        name: scheduler
+        namespace: storageos
+        outcomes:
+          - pass:
+              message: up
+`,
+			want: []Diagnostic{
+				{Line: 11, Severity: SeverityWarning, Message: "duplicate deploymentStatus analyzer, first defined on line 5"},
+			},
+		},
+		{
+			name: "invalid redactor regex",
+			spec: `apiVersion: troubleshoot.sh/v1beta2
+kind: Redactor
+spec:
+  redactors:
+    - name: tokens
+      removals:
+        regex:
+          - redactor: '(token=)(?P<mask>[a-z+)'
+          - selector: 'password'
+            redactor: '(?P<mask>.*)'
+`,
+			want: []Diagnostic{
+				{Line: 8, Severity: SeverityError, Message: "invalid regex redactor: error parsing regexp: missing closing ]: `[a-z+)`"},
+			},
+		},
+		{
+			name: "every document is linted",
+			spec: `apiVersion: troubleshoot.sh/v1beta2
+kind: Collector
+spec:
+  collectors:
+    - clusterInfo: {}
+---
+- not a mapping
+`,
+			want: []Diagnostic{{Line: 7, Severity: SeverityError, Message: "document is not a mapping"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Lint([]byte(tt.spec))
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lint() =\n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasErrors(t *testing.T) {
+	warning := Diagnostic{Line: 1, Severity: SeverityWarning, Message: "pass outcome has no message"}
+	err := Diagnostic{Line: 2, Severity: SeverityError, Message: "missing kind"}
+
+	if HasErrors(nil) || HasErrors([]Diagnostic{warning}) {
+		t.Error("HasErrors() = true for warnings only")
+	}
+	if !HasErrors([]Diagnostic{warning, err}) {
+		t.Error("HasErrors() = false with an error")
+	}
+}
+
+func TestLintDefaultSpecs(t *testing.T) {
+	bundle, err := specloader.Render([]byte(defaultspecs.SupportBundle), specloader.Values{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, spec := range map[string]string{
+		"support bundle": string(bundle),
+		"preflight":      defaultspecs.Preflight,
+	} {
+		if diagnostics := Lint([]byte(spec)); len(diagnostics) > 0 {
+			t.Errorf("Lint(%s) = %v", name, diagnostics)
+		}
+	}
+}
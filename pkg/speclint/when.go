@@ -0,0 +1,140 @@
+package speclint
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/blang/semver"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// whenCheckers validate the when expressions of the analyzers that parse
+// them, the same way troubleshoot's analyzers do. Expressions that an
+// analyzer accepts but can never match are errors too. Other analyzers match
+// when against their output as text, so any value is valid.
+var whenCheckers = map[string]func(string) error{
+	"clusterVersion":    checkVersionRange,
+	"deploymentStatus":  checkReplicaCount,
+	"statefulsetStatus": checkReplicaCount,
+	"distribution":      checkDistribution,
+	"containerRuntime":  checkContainerRuntime,
+	"nodeResources":     checkNodeResources,
+}
+
+var (
+	comparisonOperators = []string{"=", "==", "===", "<", "<=", ">", ">="}
+	equalityOperators   = []string{"=", "==", "==="}
+
+	// distributions are the names the distribution analyzer knows, lower
+	// case and without dashes
+	distributions = []string{"microk8s", "dockerdesktop", "eks", "gke", "digitalocean", "openshift", "kurl", "aks", "ibm", "ibmcloud", "minikube"}
+
+	nodeResourcesFunction = regexp.MustCompile(`(.*)\((.*)\)`)
+	nodeResourcesProperty = regexp.MustCompile(`^(cpuCapacity|cpuAllocatable|memoryCapacity|memoryAllocatable|podCapacity|podAllocatable|ephemeralStorageCapacity|ephemeralStorageAllocatable)$`)
+)
+
+// checkWhen checks the when of an outcome of an analyzer. An empty when
+// always matches.
+func checkWhen(analyzerType string, when string) error {
+	check, ok := whenCheckers[analyzerType]
+	if !ok || when == "" {
+		return nil
+	}
+	return check(when)
+}
+
+// checkVersionRange checks a semver range such as ">= 1.15.0".
+func checkVersionRange(when string) error {
+	_, err := semver.ParseRange(when)
+	return err
+}
+
+// checkReplicaCount checks an operator and a number separated by a space,
+// such as "< 1".
+func checkReplicaCount(when string) error {
+	parts := strings.Split(strings.TrimSpace(when), " ")
+	if len(parts) != 2 {
+		return fmt.Errorf("expected an operator and a number separated by a space, e.g. \"< 1\"")
+	}
+	if !contains(comparisonOperators, parts[0]) {
+		return fmt.Errorf("unknown operator %q", parts[0])
+	}
+	if _, err := strconv.Atoi(parts[1]); err != nil {
+		return fmt.Errorf("%q is not a number", parts[1])
+	}
+	return nil
+}
+
+// checkDistribution checks a distribution, optionally after an equality
+// operator and a space, such as "eks" or "!= eks".
+func checkDistribution(when string) error {
+	parts := strings.Split(strings.TrimSpace(when), " ")
+	if len(parts) == 1 {
+		parts = []string{"=", parts[0]}
+	}
+	if len(parts) != 2 {
+		return fmt.Errorf("expected an operator and a distribution separated by a space, e.g. \"== eks\"")
+	}
+	if !contains(equalityOperators, parts[0]) && parts[0] != "!=" && parts[0] != "!==" {
+		return fmt.Errorf("unknown operator %q, which never matches", parts[0])
+	}
+	if !contains(distributions, strings.ReplaceAll(strings.ToLower(parts[1]), "-", "")) {
+		return fmt.Errorf("unknown distribution %q", parts[1])
+	}
+	return nil
+}
+
+// checkContainerRuntime checks an equality operator and a runtime separated
+// by a space, such as "== containerd". The analyzer has no inequality.
+func checkContainerRuntime(when string) error {
+	parts := strings.Split(strings.TrimSpace(when), " ")
+	if len(parts) != 2 {
+		return fmt.Errorf("expected an operator and a runtime separated by a space, e.g. \"== containerd\"")
+	}
+	if !contains(equalityOperators, parts[0]) {
+		return fmt.Errorf("unknown operator %q, which never matches", parts[0])
+	}
+	return nil
+}
+
+// checkNodeResources checks a function, an operator and a value separated
+// by spaces, such as "min(memoryCapacity) < 2Gi". Without a function, or
+// with a word such as count instead of one, the number of nodes is
+// compared, as in "< 3".
+func checkNodeResources(when string) error {
+	parts := strings.Fields(when)
+	if len(parts) == 2 {
+		parts = append([]string{"count"}, parts...)
+	}
+	if len(parts) != 3 {
+		return fmt.Errorf("expected a function, an operator and a value separated by spaces, e.g. \"count() < 3\"")
+	}
+
+	function, property := "count", ""
+	if m := nodeResourcesFunction.FindStringSubmatch(parts[0]); m != nil {
+		function, property = m[1], m[2]
+	}
+
+	if !contains(comparisonOperators, parts[1]) {
+		return fmt.Errorf("unknown operator %q", parts[1])
+	}
+
+	switch function {
+	case "count":
+		if _, err := strconv.Atoi(parts[2]); err != nil {
+			return fmt.Errorf("%q is not a number of nodes", parts[2])
+		}
+	case "min", "max", "sum":
+		if !nodeResourcesProperty.MatchString(property) {
+			return fmt.Errorf("unknown property %q", property)
+		}
+		if _, err := resource.ParseQuantity(parts[2]); err != nil {
+			return fmt.Errorf("%q is not a quantity", parts[2])
+		}
+	default:
+		return fmt.Errorf("unknown function %q", parts[0])
+	}
+	return nil
+}
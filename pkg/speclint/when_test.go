@@ -0,0 +1,51 @@
+package speclint
+
+import "testing"
+
+func TestCheckWhen(t *testing.T) {
+	tests := []struct {
+		analyzer string
+		when     string
+		wantErr  bool
+	}{
+		{analyzer: "clusterVersion", when: ">= 1.15.0"},
+		{analyzer: "clusterVersion", when: ">= 1.15", wantErr: true},
+		{analyzer: "deploymentStatus", when: "< 1"},
+		{analyzer: "statefulsetStatus", when: ">= 3"},
+		{analyzer: "deploymentStatus", when: "<1", wantErr: true},
+		{analyzer: "deploymentStatus", when: "!= 1", wantErr: true},
+		{analyzer: "deploymentStatus", when: "< one", wantErr: true},
+		{analyzer: "distribution", when: "== eks"},
+		{analyzer: "distribution", when: "eks"},
+		{analyzer: "distribution", when: "!= docker-desktop"},
+		{analyzer: "distribution", when: "< eks", wantErr: true},
+		{analyzer: "distribution", when: "== rancher", wantErr: true},
+		{analyzer: "distribution", when: "==  eks", wantErr: true},
+		{analyzer: "containerRuntime", when: "== containerd"},
+		{analyzer: "containerRuntime", when: "!= docker", wantErr: true},
+		{analyzer: "containerRuntime", when: "containerd", wantErr: true},
+		{analyzer: "nodeResources", when: "count() < 3"},
+		{analyzer: "nodeResources", when: "min(memoryCapacity) < 2Gi"},
+		{analyzer: "nodeResources", when: "max(cpu) < 2", wantErr: true},
+		{analyzer: "nodeResources", when: "sum(cpuAllocatable) != 2", wantErr: true},
+		{analyzer: "nodeResources", when: "count() < three", wantErr: true},
+		{analyzer: "nodeResources", when: "count()<3", wantErr: true},
+		{analyzer: "nodeResources", when: "< 3"},
+		{analyzer: "nodeResources", when: "count < 3"},
+		{analyzer: "nodeResources", when: " count()  <  3 "},
+		{analyzer: "nodeResources", when: "count() < 2Gi", wantErr: true},
+		{analyzer: "nodeResources", when: "min(podCapacity) >= 10"},
+		{analyzer: "nodeResources", when: "avg(cpuCapacity) < 2", wantErr: true},
+		{analyzer: "nodeResources", when: "< 3 nodes", wantErr: true},
+		// an empty when always matches
+		{analyzer: "nodeResources", when: ""},
+		{analyzer: "deploymentStatus", when: ""},
+		// other analyzers match when against their output
+		{analyzer: "textAnalyze", when: "anything goes"},
+	}
+	for _, tt := range tests {
+		if err := checkWhen(tt.analyzer, tt.when); (err != nil) != tt.wantErr {
+			t.Errorf("checkWhen(%q, %q) error = %v, wantErr %v", tt.analyzer, tt.when, err, tt.wantErr)
+		}
+	}
+}
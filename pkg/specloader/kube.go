@@ -0,0 +1,49 @@
+package specloader
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/troubleshoot/pkg/k8sutil"
+	"github.com/replicatedhq/troubleshoot/pkg/specs"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+func loadFromSecret(namespace string, name string, key string) ([]byte, error) {
+	spec, err := specs.LoadFromSecret(namespace, name, key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get spec from secret")
+	}
+	return spec, nil
+}
+
+func loadFromConfigMap(namespace string, name string, key string) ([]byte, error) {
+	config, err := k8sutil.GetRESTConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to convert kube flags to rest config")
+	}
+
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create kubernetes client")
+	}
+	return configMapSpec(client, namespace, name, key)
+}
+
+// configMapSpec returns the spec under key in a ConfigMap, from its data or
+// binary data.
+func configMapSpec(client kubernetes.Interface, namespace string, name string, key string) ([]byte, error) {
+	configMap, err := client.CoreV1().ConfigMaps(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get configmap")
+	}
+
+	if spec, ok := configMap.Data[key]; ok {
+		return []byte(spec), nil
+	}
+	if spec, ok := configMap.BinaryData[key]; ok {
+		return spec, nil
+	}
+	return nil, errors.Errorf("spec not found in configmap %s under key %s", name, key)
+}
@@ -0,0 +1,67 @@
+package specloader
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestConfigMapSpec(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "storageos", Name: "specs"},
+			Data:       map[string]string{SupportBundleKey: "kind: SupportBundle\n"},
+			BinaryData: map[string][]byte{PreflightKey: []byte("kind: Preflight\n")},
+		},
+	)
+
+	tests := []struct {
+		namespace string
+		name      string
+		key       string
+		want      string
+		wantErr   bool
+	}{
+		{namespace: "storageos", name: "specs", key: SupportBundleKey, want: "kind: SupportBundle\n"},
+		{namespace: "storageos", name: "specs", key: PreflightKey, want: "kind: Preflight\n"},
+		{namespace: "storageos", name: "specs", key: "other", wantErr: true},
+		{namespace: "default", name: "specs", key: SupportBundleKey, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := configMapSpec(client, tt.namespace, tt.name, tt.key)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("configMapSpec(%s/%s, %s) error = %v, wantErr %v", tt.namespace, tt.name, tt.key, err, tt.wantErr)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("configMapSpec(%s/%s, %s) = %q, want %q", tt.namespace, tt.name, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestSplitObjectSource(t *testing.T) {
+	tests := []struct {
+		source        string
+		wantNamespace string
+		wantName      string
+		wantErr       bool
+	}{
+		{source: "configmap/storageos/specs", wantNamespace: "storageos", wantName: "specs"},
+		{source: "secret/storageos/specs", wantNamespace: "storageos", wantName: "specs"},
+		{source: "configmap/specs", wantErr: true},
+		{source: "configmap//specs", wantErr: true},
+		{source: "configmap/storageos/specs/extra", wantErr: true},
+	}
+	for _, tt := range tests {
+		namespace, name, err := splitObjectSource(tt.source)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("splitObjectSource(%q) error = %v, wantErr %v", tt.source, err, tt.wantErr)
+			continue
+		}
+		if namespace != tt.wantNamespace || name != tt.wantName {
+			t.Errorf("splitObjectSource(%q) = %q, %q, want %q, %q", tt.source, namespace, name, tt.wantNamespace, tt.wantName)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package specloader
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	ociManifestMediaType    = "application/vnd.oci.image.manifest.v1+json"
+	dockerManifestMediaType = "application/vnd.docker.distribution.manifest.v2+json"
+)
+
+type ociReference struct {
+	Registry   string
+	Repository string
+	// Reference is a tag or a digest.
+	Reference string
+}
+
+type ociManifest struct {
+	MediaType string          `json:"mediaType"`
+	Layers    []ociDescriptor `json:"layers"`
+}
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// parseOCIReference parses registry/repository:tag or
+// registry/repository@digest. The tag defaults to latest.
+func parseOCIReference(ref string) (*ociReference, error) {
+	slash := strings.Index(ref, "/")
+	if slash <= 0 || slash == len(ref)-1 {
+		return nil, fmt.Errorf("invalid oci reference %q, expected oci://registry/repository:tag", ref)
+	}
+
+	r := &ociReference{
+		Registry:   ref[:slash],
+		Repository: ref[slash+1:],
+		Reference:  "latest",
+	}
+
+	if at := strings.Index(r.Repository, "@"); at >= 0 {
+		r.Reference = r.Repository[at+1:]
+		r.Repository = r.Repository[:at]
+	} else if colon := strings.LastIndex(r.Repository, ":"); colon >= 0 {
+		r.Reference = r.Repository[colon+1:]
+		r.Repository = r.Repository[:colon]
+	}
+
+	if r.Repository == "" || r.Reference == "" {
+		return nil, fmt.Errorf("invalid oci reference %q, expected oci://registry/repository:tag", ref)
+	}
+	return r, nil
+}
+
+// baseURL returns the registry API root. Registries on localhost are assumed
+// to be plain HTTP, as they are when run for development.
+func (r *ociReference) baseURL() string {
+	host := r.Registry
+	if i := strings.LastIndex(host, ":"); i >= 0 {
+		host = host[:i]
+	}
+	if host == "localhost" || host == "127.0.0.1" {
+		return "http://" + r.Registry + "/v2/"
+	}
+	return "https://" + r.Registry + "/v2/"
+}
+
+// loadFromOCI pulls an OCI artifact and returns its YAML layers, joined as a
+// multi-document spec. An artifact with a single layer is returned whatever
+// its media type.
+func (l *Loader) loadFromOCI(ref string) ([]byte, error) {
+	r, err := parseOCIReference(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	manifestURL := r.baseURL() + r.Repository + "/manifests/" + r.Reference
+	manifestHeader := http.Header{}
+	manifestHeader.Set("Accept", strings.Join([]string{ociManifestMediaType, dockerManifestMediaType}, ", "))
+
+	auth, err := l.registryAuth(manifestURL, manifestHeader)
+	if err != nil {
+		return nil, err
+	}
+	for k, values := range auth {
+		manifestHeader[k] = values
+	}
+
+	b, err := l.get(manifestURL, manifestHeader)
+	if err != nil {
+		return nil, errors.Wrap(err, "get manifest")
+	}
+
+	manifest := ociManifest{}
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		return nil, errors.Wrap(err, "parse manifest")
+	}
+
+	layers := []ociDescriptor{}
+	for _, layer := range manifest.Layers {
+		if strings.Contains(layer.MediaType, "yaml") {
+			layers = append(layers, layer)
+		}
+	}
+	if len(layers) == 0 && len(manifest.Layers) == 1 {
+		layers = manifest.Layers
+	}
+	if len(layers) == 0 {
+		return nil, fmt.Errorf("no yaml layers in %s manifest", ref)
+	}
+
+	docs := []string{}
+	for _, layer := range layers {
+		blob, err := l.get(r.baseURL()+r.Repository+"/blobs/"+layer.Digest, auth)
+		if err != nil {
+			return nil, errors.Wrapf(err, "get layer %s", layer.Digest)
+		}
+		if err := verifyDigest(blob, layer.Digest); err != nil {
+			return nil, err
+		}
+		docs = append(docs, strings.TrimSuffix(string(blob), "\n"))
+	}
+
+	return []byte(strings.Join(docs, "\n---\n")), nil
+}
+
+// registryAuth returns the Authorization header for requests to the
+// repository, or none if the registry allows anonymous access. Registries
+// that answer 401 with a Bearer challenge are sent to their token service,
+// anonymously or with the Loader's registry credentials. Registries with a
+// Basic challenge, such as registry:2 with htpasswd, are sent the credentials
+// with every request.
+func (l *Loader) registryAuth(uri string, header http.Header) (http.Header, error) {
+	resp, err := l.do("GET", uri, header)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		return http.Header{}, nil
+	}
+
+	challenge := resp.Header.Get("WWW-Authenticate")
+	parts := strings.SplitN(challenge, " ", 2)
+	switch strings.ToLower(parts[0]) {
+	case "basic":
+		return l.basicAuth()
+	case "bearer":
+	default:
+		return nil, fmt.Errorf("unsupported registry authentication %q", challenge)
+	}
+	params := map[string]string{}
+	if len(parts) == 2 {
+		params = parseChallenge(parts[1])
+	}
+
+	tokenURL, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return nil, fmt.Errorf("invalid registry token realm %q", params["realm"])
+	}
+	query := tokenURL.Query()
+	for _, k := range []string{"service", "scope"} {
+		if params[k] != "" {
+			query.Set(k, params[k])
+		}
+	}
+	tokenURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequest("GET", tokenURL.String(), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "make token request")
+	}
+	req.Header.Set("User-Agent", UserAgent())
+	if l.RegistryUsername != "" {
+		req.SetBasicAuth(l.RegistryUsername, l.RegistryPassword)
+	}
+
+	tokenResp, err := l.HTTPClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "get registry token")
+	}
+	defer tokenResp.Body.Close()
+
+	if tokenResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d from registry token service", tokenResp.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(tokenResp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "read token response")
+	}
+
+	token := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, errors.Wrap(err, "parse token response")
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+
+	auth := http.Header{}
+	auth.Set("Authorization", "Bearer "+token.Token)
+	return auth, nil
+}
+
+// basicAuth returns the Authorization header with the Loader's registry
+// credentials.
+func (l *Loader) basicAuth() (http.Header, error) {
+	if l.RegistryUsername == "" {
+		return nil, errors.New("registry requires a username and password")
+	}
+	credentials := base64.StdEncoding.EncodeToString([]byte(l.RegistryUsername + ":" + l.RegistryPassword))
+
+	auth := http.Header{}
+	auth.Set("Authorization", "Basic "+credentials)
+	return auth, nil
+}
+
+// parseChallenge parses the comma separated key="value" parameters of a
+// WWW-Authenticate challenge.
+func parseChallenge(s string) map[string]string {
+	params := map[string]string{}
+	for s != "" {
+		eq := strings.Index(s, "=")
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = strings.TrimSpace(s[eq+1:])
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			end := strings.Index(s[1:], `"`)
+			if end < 0 {
+				value, s = s[1:], ""
+			} else {
+				value, s = s[1:end+1], s[end+2:]
+			}
+		} else {
+			end := strings.Index(s, ",")
+			if end < 0 {
+				end = len(s)
+			}
+			value, s = s[:end], s[end:]
+		}
+		params[key] = value
+
+		s = strings.TrimPrefix(strings.TrimSpace(s), ",")
+	}
+	return params
+}
+
+func verifyDigest(b []byte, digest string) error {
+	if !strings.HasPrefix(digest, "sha256:") {
+		return fmt.Errorf("unsupported digest algorithm in %s", digest)
+	}
+	sum := sha256.Sum256(b)
+	if hex.EncodeToString(sum[:]) != strings.TrimPrefix(digest, "sha256:") {
+		return fmt.Errorf("layer does not match digest %s", digest)
+	}
+	return nil
+}
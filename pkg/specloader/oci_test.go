@@ -0,0 +1,256 @@
+package specloader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testLayer is the media type and content of a layer.
+type testLayer [2]string
+
+// testRegistry serves the artifact specs:v1 with layers. auth is "",
+// "bearer" or "basic". Bearer tokens are only given to user:pass if
+// tokenNeedsCredentials is set.
+type testRegistry struct {
+	auth                  string
+	tokenNeedsCredentials bool
+	layers                []ociDescriptor
+	blobs                 map[string]string
+}
+
+func newTestRegistry(auth string, layers []testLayer) *testRegistry {
+	r := &testRegistry{auth: auth, blobs: map[string]string{}}
+	for _, layer := range layers {
+		sum := sha256.Sum256([]byte(layer[1]))
+		digest := "sha256:" + hex.EncodeToString(sum[:])
+		r.layers = append(r.layers, ociDescriptor{MediaType: layer[0], Digest: digest, Size: int64(len(layer[1]))})
+		r.blobs[digest] = layer[1]
+	}
+	return r
+}
+
+func (r *testRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path == "/token" {
+		if user, pass, ok := req.BasicAuth(); r.tokenNeedsCredentials && (!ok || user != "user" || pass != "pass") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if req.URL.Query().Get("scope") != "repository:specs:pull" || req.URL.Query().Get("service") != "registry.test" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "t0k"})
+		return
+	}
+
+	switch r.auth {
+	case "bearer":
+		if req.Header.Get("Authorization") != "Bearer t0k" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="http://`+req.Host+`/token",service="registry.test",scope="repository:specs:pull"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	case "basic":
+		if user, pass, ok := req.BasicAuth(); !ok || user != "user" || pass != "pass" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="Registry Realm"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}
+
+	switch {
+	case req.URL.Path == "/v2/specs/manifests/v1":
+		if !strings.Contains(req.Header.Get("Accept"), ociManifestMediaType) {
+			w.WriteHeader(http.StatusNotAcceptable)
+			return
+		}
+		w.Header().Set("Content-Type", ociManifestMediaType)
+		json.NewEncoder(w).Encode(ociManifest{MediaType: ociManifestMediaType, Layers: r.layers})
+	case strings.HasPrefix(req.URL.Path, "/v2/specs/blobs/"):
+		blob, ok := r.blobs[strings.TrimPrefix(req.URL.Path, "/v2/specs/blobs/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(blob))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestLoadFromOCI(t *testing.T) {
+	specAndValues := []testLayer{
+		{"application/vnd.storageos.spec.v1+yaml", "kind: SupportBundle\n"},
+		{"text/plain", "README\n"},
+		{"application/vnd.storageos.values.v1+yaml", "namespace: storageos\n"},
+	}
+
+	tests := []struct {
+		name                  string
+		auth                  string
+		tokenNeedsCredentials bool
+		username              string
+		layers                []testLayer
+		corrupt               bool
+		tag                   string
+		want                  string
+		wantErr               bool
+	}{
+		{
+			name:   "anonymous",
+			layers: specAndValues,
+			want:   "kind: SupportBundle\n---\nnamespace: storageos",
+		},
+		{
+			name:   "single layer of any type",
+			layers: []testLayer{{"text/plain", "kind: Preflight\n"}},
+			want:   "kind: Preflight",
+		},
+		{
+			name:    "no yaml layers",
+			layers:  []testLayer{{"text/plain", "README\n"}, {"text/plain", "LICENSE\n"}},
+			wantErr: true,
+		},
+		{
+			name:    "layer does not match its digest",
+			layers:  specAndValues,
+			corrupt: true,
+			wantErr: true,
+		},
+		{
+			name:   "anonymous token",
+			auth:   "bearer",
+			layers: specAndValues,
+			want:   "kind: SupportBundle\n---\nnamespace: storageos",
+		},
+		{
+			name:                  "token for credentials",
+			auth:                  "bearer",
+			tokenNeedsCredentials: true,
+			username:              "user",
+			layers:                specAndValues,
+			want:                  "kind: SupportBundle\n---\nnamespace: storageos",
+		},
+		{
+			name:                  "token without credentials",
+			auth:                  "bearer",
+			tokenNeedsCredentials: true,
+			layers:                specAndValues,
+			wantErr:               true,
+		},
+		{
+			name:     "basic",
+			auth:     "basic",
+			username: "user",
+			layers:   specAndValues,
+			want:     "kind: SupportBundle\n---\nnamespace: storageos",
+		},
+		{
+			name:    "basic without credentials",
+			auth:    "basic",
+			layers:  specAndValues,
+			wantErr: true,
+		},
+		{
+			name:    "missing tag",
+			layers:  specAndValues,
+			tag:     "v2",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := newTestRegistry(tt.auth, tt.layers)
+			registry.tokenNeedsCredentials = tt.tokenNeedsCredentials
+			if tt.corrupt {
+				for digest := range registry.blobs {
+					registry.blobs[digest] = "corrupt"
+				}
+			}
+			srv := httptest.NewServer(registry)
+			defer srv.Close()
+
+			tag := tt.tag
+			if tag == "" {
+				tag = "v1"
+			}
+			l := New(SupportBundleKey, false)
+			if tt.username != "" {
+				l.RegistryUsername = tt.username
+				l.RegistryPassword = "pass"
+			}
+			got, err := l.Load(ociPrefix + strings.TrimPrefix(srv.URL, "http://") + "/specs:" + tag)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Load() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseOCIReference(t *testing.T) {
+	tests := []struct {
+		ref     string
+		want    *ociReference
+		wantErr bool
+	}{
+		{ref: "ghcr.io/storageos/specs:v1", want: &ociReference{Registry: "ghcr.io", Repository: "storageos/specs", Reference: "v1"}},
+		{ref: "ghcr.io/storageos/specs", want: &ociReference{Registry: "ghcr.io", Repository: "storageos/specs", Reference: "latest"}},
+		{ref: "localhost:5000/specs@sha256:abc", want: &ociReference{Registry: "localhost:5000", Repository: "specs", Reference: "sha256:abc"}},
+		{ref: "specs", wantErr: true},
+		{ref: "ghcr.io/", wantErr: true},
+		{ref: "ghcr.io/specs:", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseOCIReference(tt.ref)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseOCIReference(%q) error = %v, wantErr %v", tt.ref, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseOCIReference(%q) = %+v, want %+v", tt.ref, got, tt.want)
+		}
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	tests := []struct {
+		challenge string
+		want      map[string]string
+	}{
+		{
+			challenge: `realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:storageos/specs:pull"`,
+			want:      map[string]string{"realm": "https://auth.docker.io/token", "service": "registry.docker.io", "scope": "repository:storageos/specs:pull"},
+		},
+		{
+			// scopes can hold commas, and keys are case-insensitive
+			challenge: `Realm="https://ghcr.io/token", scope="repository:a:pull,push" , service=ghcr.io`,
+			want:      map[string]string{"realm": "https://ghcr.io/token", "scope": "repository:a:pull,push", "service": "ghcr.io"},
+		},
+		{
+			challenge: `realm="https://ghcr.io/token`,
+			want:      map[string]string{"realm": "https://ghcr.io/token"},
+		},
+		{
+			challenge: "",
+			want:      map[string]string{},
+		},
+		{
+			challenge: "garbage",
+			want:      map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		if got := parseChallenge(tt.challenge); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseChallenge(%q) = %v, want %v", tt.challenge, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+// Package specloader loads troubleshoot specs from the sources accepted on the
+// command line: local files, stdin, URLs, Kubernetes Secrets and ConfigMaps,
+// and OCI registries.
+package specloader
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/troubleshoot/cmd/util"
+
+	"github.com/croomes/kubectl-plugin/pkg/version"
+)
+
+const (
+	// Stdin is the source that reads the spec from standard input.
+	Stdin = "-"
+	// Embedded is the source of the Loader's Default spec.
+	Embedded = "embedded"
+
+	secretPrefix    = "secret/"
+	configMapPrefix = "configmap/"
+	ociPrefix       = "oci://"
+)
+
+// Keys of the spec within a Secret or ConfigMap source.
+const (
+	SupportBundleKey = "support-bundle-spec"
+	PreflightKey     = "preflight-spec"
+)
+
+// Loader loads specs. A source is "-" for standard input, "embedded" for the
+// default spec compiled into the binary, or one of:
+//
+//	secret/namespace/name      the Key of a Secret
+//	configmap/namespace/name   the Key of a ConfigMap
+//	oci://registry/repo:tag    the YAML layers of an OCI artifact
+//	https://...                a URL
+//	path/to/spec.yaml          a local file
+type Loader struct {
+	// Key is the key of the spec in Secret and ConfigMap sources.
+	Key string
+
+	HTTPClient *http.Client
+
+	// Header is added to every HTTP request, after the User-Agent.
+	Header http.Header
+
+	// InsecureFallback, if set, is called when a server's certificate cannot
+	// be verified. If it returns true, the request is retried, and later
+	// requests are made, without verifying certificates.
+	InsecureFallback func() bool
+
+	// Stdin is read for the "-" source. It defaults to os.Stdin.
+	Stdin io.Reader
+
+	// Default is returned for the "embedded" source.
+	Default string
+
+	// Values, if not nil, are used to render specs as Go templates once they
+	// are loaded. Only the embedded spec and specs with a TemplateMarker line
+	// are rendered, unless RenderAll is set, so that specs written for other
+	// tools that contain "{{" are left alone. See Render.
+	Values Values
+
+	// RenderAll renders every spec with Values, as when values were given on
+	// the command line.
+	RenderAll bool
+
+	// Rendered is set by Load to whether the spec it returned was rendered
+	// with Values.
+	Rendered bool
+
+	// RegistryUsername and RegistryPassword are sent to the token service of
+	// OCI registries that require authentication, or to the registry itself
+	// if it asks for basic authentication. Without them, an anonymous token
+	// is requested.
+	RegistryUsername string
+	RegistryPassword string
+}
+
+// New returns a Loader for specs stored under key in Secrets and ConfigMaps.
+// If insecure is set, TLS certificates are not verified.
+func New(key string, insecure bool) *Loader {
+	client := http.DefaultClient
+	if insecure {
+		client = insecureClient()
+	}
+
+	return &Loader{
+		Key:        key,
+		HTTPClient: client,
+		Header:     http.Header{},
+		Stdin:      os.Stdin,
+	}
+}
+
+// UserAgent is sent with every request the Loader makes.
+func UserAgent() string {
+	return "kubectl-storageos/" + version.Version()
+}
+
+// Load returns the contents of the spec at source.
+func (l *Loader) Load(source string) ([]byte, error) {
+	b, err := l.load(source)
+	if err != nil {
+		return nil, errors.Wrapf(err, "load spec %s", source)
+	}
+
+	l.Rendered = l.Values != nil && (source == Embedded || l.RenderAll || IsTemplate(b))
+	if l.Rendered {
+		b, err = Render(b, l.Values)
+		if err != nil {
+			return nil, errors.Wrapf(err, "load spec %s", source)
+		}
+	}
+	return b, nil
+}
+
+// ReadRegistryPassword reads RegistryPassword from the first line of Stdin,
+// which can then no longer be used as a spec source.
+func (l *Loader) ReadRegistryPassword() error {
+	line, err := bufio.NewReader(l.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return errors.Wrap(err, "read registry password")
+	}
+	l.RegistryPassword = strings.TrimRight(line, "\r\n")
+	l.Stdin = usedStdin{}
+	return nil
+}
+
+// usedStdin is the Stdin of a Loader that has read the registry password
+// from it.
+type usedStdin struct{}
+
+func (usedStdin) Read([]byte) (int, error) {
+	return 0, errors.New("standard input was read for the registry password")
+}
+
+func (l *Loader) load(source string) ([]byte, error) {
+	switch {
+	case source == Stdin:
+		return ioutil.ReadAll(l.Stdin)
+	case source == Embedded:
+		if l.Default == "" {
+			return nil, errors.New("no embedded spec")
+		}
+		return []byte(l.Default), nil
+	case strings.HasPrefix(source, secretPrefix):
+		namespace, name, err := splitObjectSource(source)
+		if err != nil {
+			return nil, err
+		}
+		return loadFromSecret(namespace, name, l.Key)
+	case strings.HasPrefix(source, configMapPrefix):
+		namespace, name, err := splitObjectSource(source)
+		if err != nil {
+			return nil, err
+		}
+		return loadFromConfigMap(namespace, name, l.Key)
+	case strings.HasPrefix(source, ociPrefix):
+		return l.loadFromOCI(strings.TrimPrefix(source, ociPrefix))
+	}
+
+	_, statErr := os.Stat(source)
+	if statErr == nil {
+		return ioutil.ReadFile(source)
+	}
+	if !util.IsURL(source) {
+		return nil, fmt.Errorf("not a URL and was not found (err %s)", statErr)
+	}
+
+	return l.get(source, nil)
+}
+
+// splitObjectSource splits a secret/namespace/name or configmap/namespace/name
+// source.
+func splitObjectSource(source string) (string, string, error) {
+	parts := strings.Split(source, "/")
+	if len(parts) != 3 || parts[1] == "" || parts[2] == "" {
+		return "", "", errors.Errorf("path %s must have 3 components", source)
+	}
+	return parts[1], parts[2], nil
+}
+
+// get fetches uri and returns the response body, failing on any status other
+// than 200.
+func (l *Loader) get(uri string, header http.Header) ([]byte, error) {
+	resp, err := l.do("GET", uri, header)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, uri)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "read response body")
+	}
+	return body, nil
+}
+
+// do makes a request with the Loader's headers, falling back to an insecure
+// connection if the server's certificate cannot be verified and
+// InsecureFallback allows it.
+func (l *Loader) do(method string, uri string, header http.Header) (*http.Response, error) {
+	for {
+		req, err := http.NewRequest(method, uri, nil)
+		if err != nil {
+			return nil, errors.Wrap(err, "make request")
+		}
+		req.Header.Set("User-Agent", UserAgent())
+		for k, values := range l.Header {
+			for _, value := range values {
+				req.Header.Add(k, value)
+			}
+		}
+		for k, values := range header {
+			for _, value := range values {
+				req.Header.Add(k, value)
+			}
+		}
+
+		resp, err := l.HTTPClient.Do(req)
+		if err != nil {
+			if strings.Contains(err.Error(), "x509") && l.HTTPClient == http.DefaultClient && l.InsecureFallback != nil && l.InsecureFallback() {
+				l.HTTPClient = insecureClient()
+				continue
+			}
+			return nil, errors.Wrap(err, "execute request")
+		}
+		return resp, nil
+	}
+}
+
+func insecureClient() *http.Client {
+	return &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}}
+}
@@ -0,0 +1,50 @@
+package specloader
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadRegistryPassword(t *testing.T) {
+	tests := []struct {
+		stdin string
+		want  string
+	}{
+		{stdin: "s3cret\n", want: "s3cret"},
+		{stdin: "s3cret\r\nspec: ignored\n", want: "s3cret"},
+		{stdin: "s3cret", want: "s3cret"},
+		{stdin: "", want: ""},
+	}
+	for _, tt := range tests {
+		l := New(SupportBundleKey, false)
+		l.Stdin = strings.NewReader(tt.stdin)
+		if err := l.ReadRegistryPassword(); err != nil {
+			t.Fatalf("ReadRegistryPassword(%q) error = %v", tt.stdin, err)
+		}
+		if l.RegistryPassword != tt.want {
+			t.Errorf("ReadRegistryPassword(%q) = %q, want %q", tt.stdin, l.RegistryPassword, tt.want)
+		}
+
+		// standard input can't also be the spec source
+		if _, err := l.Load(Stdin); err == nil {
+			t.Errorf("Load(%q) after ReadRegistryPassword() succeeded", Stdin)
+		}
+	}
+}
+
+func TestLoadStdin(t *testing.T) {
+	spec := "apiVersion: troubleshoot.sh/v1beta2\nkind: SupportBundle\n"
+
+	l := New(SupportBundleKey, false)
+	l.Stdin = strings.NewReader(spec)
+	got, err := l.Load(Stdin)
+	if err != nil {
+		t.Fatalf("Load(%q) error = %v", Stdin, err)
+	}
+	if string(got) != spec {
+		t.Errorf("Load(%q) = %q, want %q", Stdin, got, spec)
+	}
+	if l.Rendered {
+		t.Errorf("Load(%q) rendered a spec without values", Stdin)
+	}
+}
@@ -0,0 +1,119 @@
+package specloader
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// TemplateMarker is a line that marks a spec as a template, so that it is
+// rendered even without --values or --set.
+const TemplateMarker = "# kubectl-storageos: template"
+
+// Values are the values a spec is rendered with, available to the template
+// as .Values.
+type Values map[string]interface{}
+
+var templateFuncs = template.FuncMap{
+	// default returns value, or def if value is unset or empty, e.g.
+	// {{ .Values.namespace | default "kube-system" }}
+	"default": func(def interface{}, value interface{}) interface{} {
+		if value == nil || value == "" {
+			return def
+		}
+		return value
+	},
+}
+
+// LoadValues reads the values files in order, then sets each key=value pair,
+// so that later values override earlier ones. Dotted keys set nested values,
+// e.g. storageos.namespace=storageos.
+func LoadValues(files []string, sets []string) (Values, error) {
+	values := Values{}
+
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, errors.Wrap(err, "read values file")
+		}
+
+		fileValues := map[string]interface{}{}
+		if err := yaml.Unmarshal(b, &fileValues); err != nil {
+			return nil, errors.Wrapf(err, "parse values file %s", file)
+		}
+		MergeValues(values, fileValues)
+	}
+
+	for _, set := range sets {
+		parts := strings.SplitN(set, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, errors.Errorf("invalid --set %q, expected key=value", set)
+		}
+
+		keys := strings.Split(parts[0], ".")
+		m := map[string]interface{}(values)
+		for _, key := range keys[:len(keys)-1] {
+			child, ok := m[key].(map[string]interface{})
+			if !ok {
+				child = map[string]interface{}{}
+				m[key] = child
+			}
+			m = child
+		}
+		m[keys[len(keys)-1]] = parts[1]
+	}
+
+	return values, nil
+}
+
+// MergeValues deep merges src into dst, so that src takes precedence.
+func MergeValues(dst map[string]interface{}, src map[string]interface{}) {
+	for k, v := range src {
+		srcMap, srcIsMap := v.(map[string]interface{})
+		dstMap, dstIsMap := dst[k].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			MergeValues(dstMap, srcMap)
+			continue
+		}
+		dst[k] = v
+	}
+}
+
+// IsTemplate returns true if spec has a TemplateMarker line.
+func IsTemplate(spec []byte) bool {
+	for _, line := range strings.Split(string(spec), "\n") {
+		if strings.TrimSpace(line) == TemplateMarker {
+			return true
+		}
+	}
+	return false
+}
+
+// Render executes spec as a Go template with values. Specs without template
+// actions are returned unchanged.
+func Render(spec []byte, values Values) ([]byte, error) {
+	if !bytes.Contains(spec, []byte("{{")) {
+		return spec, nil
+	}
+
+	tmpl, err := template.New("spec").Funcs(templateFuncs).Parse(string(spec))
+	if err != nil {
+		return nil, errors.Wrap(err, "parse spec template")
+	}
+
+	data := struct {
+		Values Values
+	}{
+		Values: values,
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, errors.Wrap(err, "render spec template")
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,181 @@
+package specloader
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadValues(t *testing.T) {
+	dir, err := ioutil.TempDir("", "specloader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	valuesFile := filepath.Join(dir, "values.yaml")
+	if err := ioutil.WriteFile(valuesFile, []byte("namespace: storageos\nstorageos:\n  name: ondat\n  replicas: 2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := LoadValues([]string{valuesFile}, []string{"storageos.name=storageos", "operatorNamespace=operators"})
+	if err != nil {
+		t.Fatalf("LoadValues() error = %v", err)
+	}
+	want := Values{
+		"namespace":         "storageos",
+		"operatorNamespace": "operators",
+		"storageos": map[string]interface{}{
+			"name":     "storageos",
+			"replicas": 2,
+		},
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("LoadValues() = %v, want %v", values, want)
+	}
+
+	for _, set := range []string{"namespace", "=storageos"} {
+		if _, err := LoadValues(nil, []string{set}); err == nil {
+			t.Errorf("LoadValues(--set %s) succeeded, want an error", set)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		values  Values
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "no template actions",
+			spec: "namespace: kube-system\n",
+			want: "namespace: kube-system\n",
+		},
+		{
+			name:   "value",
+			spec:   "namespace: {{ .Values.namespace }}\n",
+			values: Values{"namespace": "storageos"},
+			want:   "namespace: storageos\n",
+		},
+		{
+			name: "default",
+			spec: `namespace: {{ .Values.namespace | default "kube-system" }}` + "\n",
+			want: "namespace: kube-system\n",
+		},
+		{
+			name:    "invalid template",
+			spec:    "namespace: {{ .Values.namespace\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render([]byte(tt.spec), tt.values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Render() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadRendersOnlyTemplates(t *testing.T) {
+	// a spec written for another tool, which would not parse as a Go template
+	kots := "args:\n  - '{{repl ConfigOption \"namespace\" }}'\n"
+	marked := TemplateMarker + "\nnamespace: {{ .Values.namespace }}\n"
+
+	tests := []struct {
+		name      string
+		source    string
+		content   string
+		renderAll bool
+		want      string
+		wantErr   bool
+	}{
+		{
+			name:    "spec with {{ is unchanged",
+			source:  Stdin,
+			content: kots,
+			want:    kots,
+		},
+		{
+			name:    "marked spec is rendered",
+			source:  Stdin,
+			content: marked,
+			want:    TemplateMarker + "\nnamespace: storageos\n",
+		},
+		{
+			name:    "embedded spec is rendered",
+			source:  Embedded,
+			content: "namespace: {{ .Values.namespace }}\n",
+			want:    "namespace: storageos\n",
+		},
+		{
+			name:      "every spec is rendered with RenderAll",
+			source:    Stdin,
+			content:   "namespace: {{ .Values.namespace }}\n",
+			renderAll: true,
+			want:      "namespace: storageos\n",
+		},
+		{
+			name:      "spec for another tool fails with RenderAll",
+			source:    Stdin,
+			content:   kots,
+			renderAll: true,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(SupportBundleKey, false)
+			l.Stdin = strings.NewReader(tt.content)
+			l.Default = tt.content
+			l.Values = Values{"namespace": "storageos"}
+			l.RenderAll = tt.renderAll
+
+			got, err := l.Load(tt.source)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Load() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "specloader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	spec := "exec:\n  args: ['helm', 'get', 'values', '{{ .Release.Name }}']\n"
+	path := filepath.Join(dir, "spec.yaml")
+	if err := ioutil.WriteFile(path, []byte(spec), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	l := New(SupportBundleKey, false)
+	l.Values = Values{}
+	got, err := l.Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if string(got) != spec {
+		t.Errorf("Load() = %q, want the spec unchanged", got)
+	}
+
+	if _, err := l.Load(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("Load() of a missing file succeeded")
+	}
+}
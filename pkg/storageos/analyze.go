@@ -0,0 +1,253 @@
+package storageos
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	analyzer "github.com/replicatedhq/troubleshoot/pkg/analyze"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	clusterPhaseRunning = "Running"
+	nodeHealthOnline    = "online"
+	maxListedNames      = 10
+)
+
+// healthyReplicas are the health values of a volume's master or replica
+// that can serve IO.
+var healthyReplicas = []string{"online", "ready"}
+
+// AnalyzeResources reports degraded StorageOS clusters, nodes that are not
+// Ready and volumes without healthy replicas, from the files written by
+// CollectResources. getFile returns the contents of a file in the bundle.
+// Resources that were not collected are not analyzed.
+func AnalyzeResources(getFile func(string) ([]byte, error)) ([]*analyzer.AnalyzeResult, error) {
+	results := []*analyzer.AnalyzeResult{}
+
+	clusters, err := readResources(getFile, clusterKind)
+	if err != nil {
+		return nil, err
+	}
+	if clusters != nil {
+		results = append(results, analyzeClusters(clusters)...)
+	}
+
+	nodes, err := readResources(getFile, nodeKind)
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) > 0 {
+		results = append(results, analyzeNodes(nodes)...)
+	}
+
+	volumes, err := readResources(getFile, volumeKind)
+	if err != nil {
+		return nil, err
+	}
+	if len(volumes) > 0 {
+		results = append(results, analyzeVolumes(volumes)...)
+	}
+
+	return results, nil
+}
+
+// readResources returns nil if the resources were not collected.
+func readResources(getFile func(string) ([]byte, error), rk resourceKind) ([]Resource, error) {
+	b, err := getFile(rk.file())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "read %s", rk.resource.Resource)
+	}
+
+	resources := []Resource{}
+	if err := json.Unmarshal(b, &resources); err != nil {
+		return nil, errors.Wrapf(err, "parse %s", rk.resource.Resource)
+	}
+	return resources, nil
+}
+
+func analyzeClusters(clusters []Resource) []*analyzer.AnalyzeResult {
+	title := "StorageOS Cluster"
+	if len(clusters) == 0 {
+		return []*analyzer.AnalyzeResult{{
+			IsWarn:  true,
+			Title:   title,
+			Message: "No StorageOSCluster resources were found.",
+		}}
+	}
+
+	results := []*analyzer.AnalyzeResult{}
+	for _, cluster := range clusters {
+		name := objectName(cluster.Object)
+		phase, _, _ := unstructured.NestedString(cluster.Object, "status", "phase")
+		failing := failingConditions(cluster.Object)
+
+		switch {
+		case phase != clusterPhaseRunning:
+			if phase == "" {
+				phase = "not running"
+			}
+			results = append(results, &analyzer.AnalyzeResult{
+				IsFail:  true,
+				Title:   title,
+				Message: fmt.Sprintf("StorageOS cluster %s is %s.", name, phase),
+			})
+		case len(failing) > 0:
+			results = append(results, &analyzer.AnalyzeResult{
+				IsWarn:  true,
+				Title:   title,
+				Message: fmt.Sprintf("StorageOS cluster %s is degraded: %s.", name, strings.Join(failing, ", ")),
+			})
+		default:
+			results = append(results, &analyzer.AnalyzeResult{
+				IsPass:  true,
+				Title:   title,
+				Message: fmt.Sprintf("StorageOS cluster %s is running.", name),
+			})
+		}
+	}
+	return results
+}
+
+func analyzeNodes(nodes []Resource) []*analyzer.AnalyzeResult {
+	notReady := []string{}
+	for _, node := range nodes {
+		if !nodeReady(node.Object) {
+			notReady = append(notReady, objectName(node.Object))
+		}
+	}
+
+	title := "StorageOS Nodes"
+	if len(notReady) > 0 {
+		return []*analyzer.AnalyzeResult{{
+			IsFail:  true,
+			Title:   title,
+			Message: fmt.Sprintf("%d of %d StorageOS nodes are not Ready: %s.", len(notReady), len(nodes), listNames(notReady)),
+		}}
+	}
+	return []*analyzer.AnalyzeResult{{
+		IsPass:  true,
+		Title:   title,
+		Message: fmt.Sprintf("All %d StorageOS nodes are Ready.", len(nodes)),
+	}}
+}
+
+// nodeReady uses the node's Ready condition, or its health if it has no
+// conditions.
+func nodeReady(node map[string]interface{}) bool {
+	conditions, _, _ := unstructured.NestedSlice(node, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if ok && condition["type"] == "Ready" {
+			return condition["status"] == "True"
+		}
+	}
+	health, _, _ := unstructured.NestedString(node, "status", "health")
+	return health == nodeHealthOnline
+}
+
+func analyzeVolumes(volumes []Resource) []*analyzer.AnalyzeResult {
+	unavailable := []string{}
+	degraded := []string{}
+	for _, volume := range volumes {
+		name := objectName(volume.Object)
+		masterHealth, _, _ := unstructured.NestedString(volume.Object, "status", "master", "health")
+		replicas, _, _ := unstructured.NestedSlice(volume.Object, "status", "replicas")
+
+		healthy := 0
+		for _, r := range replicas {
+			replica, ok := r.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if health, ok := replica["health"].(string); ok && contains(healthyReplicas, health) {
+				healthy++
+			}
+		}
+
+		switch {
+		case masterHealth != "" && !contains(healthyReplicas, masterHealth), len(replicas) > 0 && healthy == 0:
+			unavailable = append(unavailable, name)
+		case healthy < len(replicas):
+			degraded = append(degraded, name)
+		}
+	}
+
+	title := "StorageOS Volumes"
+	results := []*analyzer.AnalyzeResult{}
+	if len(unavailable) > 0 {
+		results = append(results, &analyzer.AnalyzeResult{
+			IsFail:  true,
+			Title:   title,
+			Message: fmt.Sprintf("%d StorageOS volumes have no healthy master or replicas: %s.", len(unavailable), listNames(unavailable)),
+		})
+	}
+	if len(degraded) > 0 {
+		results = append(results, &analyzer.AnalyzeResult{
+			IsWarn:  true,
+			Title:   title,
+			Message: fmt.Sprintf("%d StorageOS volumes have unhealthy replicas: %s.", len(degraded), listNames(degraded)),
+		})
+	}
+	if len(results) == 0 {
+		results = append(results, &analyzer.AnalyzeResult{
+			IsPass:  true,
+			Title:   title,
+			Message: fmt.Sprintf("All %d StorageOS volumes have healthy replicas.", len(volumes)),
+		})
+	}
+	return results
+}
+
+// failingConditions returns the conditions of an object that are not True,
+// with their reason.
+func failingConditions(obj map[string]interface{}) []string {
+	conditions, _, _ := unstructured.NestedSlice(obj, "status", "conditions")
+	failing := []string{}
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok || condition["status"] == "True" {
+			continue
+		}
+		description := fmt.Sprintf("%v", condition["type"])
+		if message, ok := condition["message"].(string); ok && message != "" {
+			description += " (" + message + ")"
+		} else if reason, ok := condition["reason"].(string); ok && reason != "" {
+			description += " (" + reason + ")"
+		}
+		failing = append(failing, description)
+	}
+	return failing
+}
+
+func objectName(obj map[string]interface{}) string {
+	u := unstructured.Unstructured{Object: obj}
+	if u.GetNamespace() == "" {
+		return u.GetName()
+	}
+	return u.GetNamespace() + "/" + u.GetName()
+}
+
+// listNames joins the first few names, so that results stay readable in
+// large clusters.
+func listNames(names []string) string {
+	if len(names) <= maxListedNames {
+		return strings.Join(names, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(names[:maxListedNames], ", "), len(names)-maxListedNames)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package storageos
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	analyzer "github.com/replicatedhq/troubleshoot/pkg/analyze"
+)
+
+// bundleFiles returns a getFile for a bundle with files.
+func bundleFiles(files map[string]string) func(string) ([]byte, error) {
+	return func(name string) ([]byte, error) {
+		b, ok := files[name]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+		return []byte(b), nil
+	}
+}
+
+func TestAnalyzeResources(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []*analyzer.AnalyzeResult
+	}{
+		{
+			name:  "nothing collected",
+			files: map[string]string{},
+			want:  []*analyzer.AnalyzeResult{},
+		},
+		{
+			name: "healthy",
+			files: map[string]string{
+				clusterKind.file(): `[{"object": {"metadata": {"name": "storageos", "namespace": "storageos"}, "status": {"phase": "Running", "conditions": [{"type": "Ready", "status": "True"}]}}, "events": []}]`,
+				nodeKind.file(): `[
+					{"object": {"metadata": {"name": "node-a"}, "status": {"conditions": [{"type": "Ready", "status": "True"}]}}},
+					{"object": {"metadata": {"name": "node-b"}, "status": {"health": "online"}}}
+				]`,
+				volumeKind.file(): `[{"object": {"metadata": {"name": "pvc-1", "namespace": "default"}, "status": {"master": {"health": "online"}, "replicas": [{"health": "ready"}]}}}]`,
+			},
+			want: []*analyzer.AnalyzeResult{
+				{IsPass: true, Title: "StorageOS Cluster", Message: "StorageOS cluster storageos/storageos is running."},
+				{IsPass: true, Title: "StorageOS Nodes", Message: "All 2 StorageOS nodes are Ready."},
+				{IsPass: true, Title: "StorageOS Volumes", Message: "All 1 StorageOS volumes have healthy replicas."},
+			},
+		},
+		{
+			name: "no cluster",
+			files: map[string]string{
+				clusterKind.file(): `[]`,
+			},
+			want: []*analyzer.AnalyzeResult{
+				{IsWarn: true, Title: "StorageOS Cluster", Message: "No StorageOSCluster resources were found."},
+			},
+		},
+		{
+			name: "cluster not running and degraded",
+			files: map[string]string{
+				clusterKind.file(): `[
+					{"object": {"metadata": {"name": "pending", "namespace": "storageos"}, "status": {"phase": "Pending"}}},
+					{"object": {"metadata": {"name": "new", "namespace": "storageos"}}},
+					{"object": {"metadata": {"name": "degraded", "namespace": "storageos"}, "status": {"phase": "Running", "conditions": [
+						{"type": "Ready", "status": "True"},
+						{"type": "Scheduler", "status": "False", "message": "scheduler extender not ready"},
+						{"type": "CSI", "status": "Unknown", "reason": "NotFound"}
+					]}}}
+				]`,
+			},
+			want: []*analyzer.AnalyzeResult{
+				{IsFail: true, Title: "StorageOS Cluster", Message: "StorageOS cluster storageos/pending is Pending."},
+				{IsFail: true, Title: "StorageOS Cluster", Message: "StorageOS cluster storageos/new is not running."},
+				{IsWarn: true, Title: "StorageOS Cluster", Message: "StorageOS cluster storageos/degraded is degraded: Scheduler (scheduler extender not ready), CSI (NotFound)."},
+			},
+		},
+		{
+			name: "nodes not Ready",
+			files: map[string]string{
+				nodeKind.file(): `[
+					{"object": {"metadata": {"name": "node-a"}, "status": {"conditions": [{"type": "Ready", "status": "False"}], "health": "online"}}},
+					{"object": {"metadata": {"name": "node-b"}, "status": {"health": "offline"}}},
+					{"object": {"metadata": {"name": "node-c"}, "status": {"health": "online"}}}
+				]`,
+			},
+			want: []*analyzer.AnalyzeResult{
+				{IsFail: true, Title: "StorageOS Nodes", Message: "2 of 3 StorageOS nodes are not Ready: node-a, node-b."},
+			},
+		},
+		{
+			name: "unhealthy replicas",
+			files: map[string]string{
+				volumeKind.file(): `[
+					{"object": {"metadata": {"name": "degraded", "namespace": "default"}, "status": {"master": {"health": "online"}, "replicas": [{"health": "ready"}, {"health": "syncing"}]}}},
+					{"object": {"metadata": {"name": "no-master", "namespace": "default"}, "status": {"master": {"health": "offline"}, "replicas": [{"health": "ready"}]}}},
+					{"object": {"metadata": {"name": "no-replicas", "namespace": "default"}, "status": {"master": {"health": "online"}, "replicas": [{"health": "failed"}]}}},
+					{"object": {"metadata": {"name": "unreplicated", "namespace": "default"}, "status": {"master": {"health": "online"}}}}
+				]`,
+			},
+			want: []*analyzer.AnalyzeResult{
+				{IsFail: true, Title: "StorageOS Volumes", Message: "2 StorageOS volumes have no healthy master or replicas: default/no-master, default/no-replicas."},
+				{IsWarn: true, Title: "StorageOS Volumes", Message: "1 StorageOS volumes have unhealthy replicas: default/degraded."},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := AnalyzeResources(bundleFiles(tt.files))
+			if err != nil {
+				t.Fatalf("AnalyzeResources() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AnalyzeResources() =")
+				for _, r := range got {
+					t.Errorf("  %+v", r)
+				}
+			}
+		})
+	}
+}
+
+func TestAnalyzeResourcesMalformed(t *testing.T) {
+	_, err := AnalyzeResources(bundleFiles(map[string]string{nodeKind.file(): `{"not": "a list"}`}))
+	if err == nil {
+		t.Error("AnalyzeResources() of a malformed file succeeded")
+	}
+}
+
+func TestListNames(t *testing.T) {
+	names := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l"}
+	if got, want := listNames(names[:2]), "a, b"; got != want {
+		t.Errorf("listNames() = %q, want %q", got, want)
+	}
+	if got, want := listNames(names), "a, b, c, d, e, f, g, h, i, j and 2 more"; got != want {
+		t.Errorf("listNames() = %q, want %q", got, want)
+	}
+}
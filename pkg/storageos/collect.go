@@ -0,0 +1,174 @@
+// Package storageos collects and analyzes the StorageOS custom resources and
+// the CSI and volume objects StorageOS works with, which troubleshoot has no
+// collectors or analyzers for.
+package storageos
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kuberneteserrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/croomes/kubectl-plugin/pkg/discovery"
+)
+
+// Dir is the directory of the collected resources in a support bundle.
+const Dir = "storageos"
+
+// CollectorName is the name of the resources collector in the bundle
+// manifest.
+const CollectorName = "storageos-resources"
+
+var (
+	// NodeResource and VolumeResource are the nodes and volumes that the
+	// StorageOS api-manager syncs from the StorageOS API.
+	NodeResource = schema.GroupVersionResource{
+		Group:    "api.storageos.com",
+		Version:  "v1",
+		Resource: "nodes",
+	}
+	VolumeResource = schema.GroupVersionResource{
+		Group:    "api.storageos.com",
+		Version:  "v1",
+		Resource: "volumes",
+	}
+)
+
+// resourceKind is a collected resource, and the kind of its objects to find
+// their events.
+type resourceKind struct {
+	dir      string
+	resource schema.GroupVersionResource
+	kind     string
+	// fallbackVersions are tried in order if the cluster does not serve the
+	// resource's version.
+	fallbackVersions []string
+}
+
+// file is the path of the collected objects in a bundle.
+func (rk resourceKind) file() string {
+	return path.Join(rk.dir, rk.resource.Resource+".json")
+}
+
+// errorsFile is the path in a bundle of the errors collecting the objects.
+func (rk resourceKind) errorsFile() string {
+	return path.Join(rk.dir, rk.resource.Resource+"-errors.json")
+}
+
+var (
+	clusterKind = resourceKind{Dir, discovery.StorageOSClusterResource, "StorageOSCluster", nil}
+	nodeKind    = resourceKind{Dir, NodeResource, "Node", nil}
+	volumeKind  = resourceKind{Dir, VolumeResource, "Volume", nil}
+)
+
+// Resource is a collected object with the events about it.
+type Resource struct {
+	Object map[string]interface{} `json:"object"`
+	Events []corev1.Event         `json:"events"`
+}
+
+// CollectResources lists every StorageOSCluster, StorageOS node and volume in
+// all namespaces, with their events. It returns the files to add to the
+// bundle. Resources that cannot be listed, such as those of a StorageOS
+// release without them, are recorded in an errors file instead.
+func CollectResources(ctx context.Context, config *rest.Config) (map[string][]byte, error) {
+	return collectResourceKinds(ctx, config, []resourceKind{clusterKind, nodeKind, volumeKind})
+}
+
+func collectResourceKinds(ctx context.Context, config *rest.Config, kinds []resourceKind) (map[string][]byte, error) {
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create kubernetes client")
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create dynamic client")
+	}
+
+	output := map[string][]byte{}
+	for _, rk := range kinds {
+		resources, errs := collectResource(ctx, client, dynamicClient, rk)
+		if resources != nil {
+			b, err := json.MarshalIndent(resources, "", "  ")
+			if err != nil {
+				return nil, errors.Wrapf(err, "marshal %s", rk.resource.Resource)
+			}
+			output[rk.file()] = b
+		}
+		if len(errs) > 0 {
+			b, err := json.MarshalIndent(errs, "", "  ")
+			if err != nil {
+				return nil, errors.Wrapf(err, "marshal %s errors", rk.resource.Resource)
+			}
+			output[rk.errorsFile()] = b
+		}
+	}
+
+	return output, nil
+}
+
+func collectResource(ctx context.Context, client kubernetes.Interface, dynamicClient dynamic.Interface, rk resourceKind) ([]Resource, []string) {
+	resource := rk.resource
+	list, err := dynamicClient.Resource(resource).Namespace(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	for _, version := range rk.fallbackVersions {
+		if !kuberneteserrors.IsNotFound(err) {
+			break
+		}
+		resource.Version = version
+		list, err = dynamicClient.Resource(resource).Namespace(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	}
+	if err != nil {
+		return nil, []string{err.Error()}
+	}
+
+	errs := []string{}
+	events, err := listEvents(ctx, client, resource.Group, rk.kind)
+	if err != nil {
+		// the objects are still worth having without their events
+		errs = append(errs, err.Error())
+	}
+
+	resources := []Resource{}
+	for _, item := range list.Items {
+		objectEvents := events[item.GetUID()]
+		if objectEvents == nil {
+			objectEvents = []corev1.Event{}
+		}
+		resources = append(resources, Resource{
+			Object: item.Object,
+			Events: objectEvents,
+		})
+	}
+	return resources, errs
+}
+
+// listEvents returns the events about objects of kind in group, by object.
+func listEvents(ctx context.Context, client kubernetes.Interface, group string, kind string) (map[types.UID][]corev1.Event, error) {
+	list, err := client.CoreV1().Events(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		FieldSelector: "involvedObject.kind=" + kind,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "list %s events", kind)
+	}
+
+	events := map[types.UID][]corev1.Event{}
+	for _, event := range list.Items {
+		// kinds such as Node are shared with other groups
+		gv, err := schema.ParseGroupVersion(event.InvolvedObject.APIVersion)
+		if err != nil || gv.Group != group {
+			continue
+		}
+		events[event.InvolvedObject.UID] = append(events[event.InvolvedObject.UID], event)
+	}
+	return events, nil
+}
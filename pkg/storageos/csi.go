@@ -0,0 +1,306 @@
+package storageos
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	analyzer "github.com/replicatedhq/troubleshoot/pkg/analyze"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+)
+
+// CSIDir is the directory of the collected CSI and volume objects in a
+// support bundle.
+const CSIDir = "csi"
+
+// CSICollectorName is the name of the CSI collector in the bundle manifest.
+const CSICollectorName = "storageos-csi"
+
+// CSIDriverName is the name the StorageOS CSI driver registers with.
+const CSIDriverName = "csi.storageos.com"
+
+// pendingThreshold is how long a claim can be Pending without warnings
+// before it is reported as stuck.
+const pendingThreshold = 5 * time.Minute
+
+const (
+	bindingModeWaitForFirstConsumer = "WaitForFirstConsumer"
+	defaultClassAnnotation          = "storageclass.kubernetes.io/is-default-class"
+)
+
+// requiredClassParameters are the parameters the StorageOS CSI driver needs to
+// authenticate to the StorageOS API when it provisions, attaches and mounts
+// volumes.
+var requiredClassParameters = []string{
+	"csi.storage.k8s.io/provisioner-secret-name",
+	"csi.storage.k8s.io/provisioner-secret-namespace",
+	"csi.storage.k8s.io/controller-publish-secret-name",
+	"csi.storage.k8s.io/controller-publish-secret-namespace",
+	"csi.storage.k8s.io/node-publish-secret-name",
+	"csi.storage.k8s.io/node-publish-secret-namespace",
+}
+
+var (
+	csiDriverKind = resourceKind{CSIDir, storageResource("csidrivers"), "CSIDriver", []string{"v1beta1"}}
+	csiNodeKind   = resourceKind{CSIDir, storageResource("csinodes"), "CSINode", []string{"v1beta1"}}
+	attachKind    = resourceKind{CSIDir, storageResource("volumeattachments"), "VolumeAttachment", nil}
+	classKind     = resourceKind{CSIDir, storageResource("storageclasses"), "StorageClass", nil}
+	pvKind        = resourceKind{CSIDir, schema.GroupVersionResource{Version: "v1", Resource: "persistentvolumes"}, "PersistentVolume", nil}
+	pvcKind       = resourceKind{CSIDir, schema.GroupVersionResource{Version: "v1", Resource: "persistentvolumeclaims"}, "PersistentVolumeClaim", nil}
+)
+
+// now is the time Pending claims are measured against.
+var now = time.Now
+
+func storageResource(resource string) schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: "storage.k8s.io", Version: "v1", Resource: resource}
+}
+
+// CollectCSI lists the CSIDriver, CSINode, VolumeAttachment, StorageClass,
+// PersistentVolume and PersistentVolumeClaim objects, with their events. It
+// returns the files to add to the bundle.
+func CollectCSI(ctx context.Context, config *rest.Config) (map[string][]byte, error) {
+	return collectResourceKinds(ctx, config, []resourceKind{csiDriverKind, csiNodeKind, attachKind, classKind, pvKind, pvcKind})
+}
+
+// AnalyzeCSI reports claims stuck Pending, VolumeAttachments with attach
+// errors, StorageOS StorageClasses missing parameters and nodes without the
+// StorageOS CSI driver, from the files written by CollectCSI. getFile returns
+// the contents of a file in the bundle.
+func AnalyzeCSI(getFile func(string) ([]byte, error)) ([]*analyzer.AnalyzeResult, error) {
+	results := []*analyzer.AnalyzeResult{}
+
+	classes, err := readResources(getFile, classKind)
+	if err != nil {
+		return nil, err
+	}
+	results = append(results, analyzeStorageClasses(classes)...)
+
+	claims, err := readResources(getFile, pvcKind)
+	if err != nil {
+		return nil, err
+	}
+	if len(claims) > 0 {
+		results = append(results, analyzeClaims(claims, classes)...)
+	}
+
+	attachments, err := readResources(getFile, attachKind)
+	if err != nil {
+		return nil, err
+	}
+	if len(attachments) > 0 {
+		results = append(results, analyzeAttachments(attachments)...)
+	}
+
+	drivers, err := readResources(getFile, csiDriverKind)
+	if err != nil {
+		return nil, err
+	}
+	csiNodes, err := readResources(getFile, csiNodeKind)
+	if err != nil {
+		return nil, err
+	}
+	if len(csiNodes) > 0 {
+		results = append(results, analyzeCSINodes(csiNodes, drivers)...)
+	}
+
+	return results, nil
+}
+
+func analyzeStorageClasses(classes []Resource) []*analyzer.AnalyzeResult {
+	title := "StorageOS StorageClasses"
+	results := []*analyzer.AnalyzeResult{}
+	found := 0
+	for _, class := range classes {
+		provisioner, _, _ := unstructured.NestedString(class.Object, "provisioner")
+		if provisioner != CSIDriverName {
+			continue
+		}
+		found++
+
+		parameters, _, _ := unstructured.NestedStringMap(class.Object, "parameters")
+		missing := []string{}
+		for _, p := range requiredClassParameters {
+			if parameters[p] == "" {
+				missing = append(missing, p)
+			}
+		}
+		if len(missing) > 0 {
+			results = append(results, &analyzer.AnalyzeResult{
+				IsFail:  true,
+				Title:   title,
+				Message: fmt.Sprintf("StorageClass %s is missing the parameters %s.", objectName(class.Object), strings.Join(missing, ", ")),
+			})
+		}
+	}
+
+	if found > 0 && len(results) == 0 {
+		results = append(results, &analyzer.AnalyzeResult{
+			IsPass:  true,
+			Title:   title,
+			Message: fmt.Sprintf("All %d StorageOS StorageClasses have the required parameters.", found),
+		})
+	}
+	return results
+}
+
+func analyzeClaims(claims []Resource, classes []Resource) []*analyzer.AnalyzeResult {
+	waitForConsumer := map[string]bool{}
+	defaultClass := ""
+	for _, class := range classes {
+		u := unstructured.Unstructured{Object: class.Object}
+		mode, _, _ := unstructured.NestedString(class.Object, "volumeBindingMode")
+		waitForConsumer[u.GetName()] = mode == bindingModeWaitForFirstConsumer
+		if u.GetAnnotations()[defaultClassAnnotation] == "true" {
+			defaultClass = u.GetName()
+		}
+	}
+
+	title := "PersistentVolumeClaims"
+	results := []*analyzer.AnalyzeResult{}
+	for _, claim := range claims {
+		phase, _, _ := unstructured.NestedString(claim.Object, "status", "phase")
+		if phase != "Pending" {
+			continue
+		}
+		name := objectName(claim.Object)
+
+		if warning := latestWarning(claim); warning != "" {
+			results = append(results, &analyzer.AnalyzeResult{
+				IsFail:  true,
+				Title:   title,
+				Message: fmt.Sprintf("PersistentVolumeClaim %s is Pending: %s", name, warning),
+			})
+			continue
+		}
+
+		className, found, _ := unstructured.NestedString(claim.Object, "spec", "storageClassName")
+		if !found {
+			className = defaultClass
+		}
+		if waitForConsumer[className] {
+			// binding waits for a pod to use the claim
+			continue
+		}
+
+		u := unstructured.Unstructured{Object: claim.Object}
+		pending := now().Sub(u.GetCreationTimestamp().Time)
+		if pending > pendingThreshold {
+			results = append(results, &analyzer.AnalyzeResult{
+				IsWarn:  true,
+				Title:   title,
+				Message: fmt.Sprintf("PersistentVolumeClaim %s has been Pending for %s.", name, pending.Round(time.Second)),
+			})
+		}
+	}
+
+	if len(results) == 0 {
+		results = append(results, &analyzer.AnalyzeResult{
+			IsPass:  true,
+			Title:   title,
+			Message: "No PersistentVolumeClaims are stuck Pending.",
+		})
+	}
+	return results
+}
+
+// latestWarning returns the message of the most recent Warning event about
+// an object, or "" if there is none.
+func latestWarning(r Resource) string {
+	events := r.Events
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].LastTimestamp.Before(&events[j].LastTimestamp)
+	})
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].Type == "Warning" {
+			return events[i].Message
+		}
+	}
+	return ""
+}
+
+func analyzeAttachments(attachments []Resource) []*analyzer.AnalyzeResult {
+	title := "VolumeAttachments"
+	results := []*analyzer.AnalyzeResult{}
+	for _, attachment := range attachments {
+		name := objectName(attachment.Object)
+		pv, _, _ := unstructured.NestedString(attachment.Object, "spec", "source", "persistentVolumeName")
+		node, _, _ := unstructured.NestedString(attachment.Object, "spec", "nodeName")
+
+		if message, found, _ := unstructured.NestedString(attachment.Object, "status", "attachError", "message"); found {
+			results = append(results, &analyzer.AnalyzeResult{
+				IsFail:  true,
+				Title:   title,
+				Message: fmt.Sprintf("VolumeAttachment %s of %s to node %s failed to attach: %s", name, pv, node, message),
+			})
+		}
+		if message, found, _ := unstructured.NestedString(attachment.Object, "status", "detachError", "message"); found {
+			results = append(results, &analyzer.AnalyzeResult{
+				IsWarn:  true,
+				Title:   title,
+				Message: fmt.Sprintf("VolumeAttachment %s of %s to node %s failed to detach: %s", name, pv, node, message),
+			})
+		}
+	}
+
+	if len(results) == 0 {
+		results = append(results, &analyzer.AnalyzeResult{
+			IsPass:  true,
+			Title:   title,
+			Message: fmt.Sprintf("None of the %d VolumeAttachments have attach or detach errors.", len(attachments)),
+		})
+	}
+	return results
+}
+
+// analyzeCSINodes reports nodes without the StorageOS driver, once the driver
+// is installed, so that clusters StorageOS is not yet installed on pass.
+func analyzeCSINodes(csiNodes []Resource, drivers []Resource) []*analyzer.AnalyzeResult {
+	installed := false
+	for _, driver := range drivers {
+		if objectName(driver.Object) == CSIDriverName {
+			installed = true
+		}
+	}
+
+	missing := []string{}
+	for _, csiNode := range csiNodes {
+		if csiNodeHasDriver(csiNode.Object) {
+			installed = true
+		} else {
+			missing = append(missing, objectName(csiNode.Object))
+		}
+	}
+	if !installed {
+		return nil
+	}
+
+	title := "StorageOS CSI Driver"
+	if len(missing) > 0 {
+		return []*analyzer.AnalyzeResult{{
+			IsWarn:  true,
+			Title:   title,
+			Message: fmt.Sprintf("The StorageOS CSI driver is not registered on %d of %d nodes, which cannot mount StorageOS volumes: %s.", len(missing), len(csiNodes), listNames(missing)),
+		}}
+	}
+	return []*analyzer.AnalyzeResult{{
+		IsPass:  true,
+		Title:   title,
+		Message: fmt.Sprintf("The StorageOS CSI driver is registered on all %d nodes.", len(csiNodes)),
+	}}
+}
+
+func csiNodeHasDriver(csiNode map[string]interface{}) bool {
+	drivers, _, _ := unstructured.NestedSlice(csiNode, "spec", "drivers")
+	for _, d := range drivers {
+		driver, ok := d.(map[string]interface{})
+		if ok && driver["name"] == CSIDriverName {
+			return true
+		}
+	}
+	return false
+}
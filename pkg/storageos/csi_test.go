@@ -0,0 +1,164 @@
+package storageos
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	analyzer "github.com/replicatedhq/troubleshoot/pkg/analyze"
+)
+
+const storageOSClass = `{"object": {
+	"metadata": {"name": "storageos", "annotations": {"storageclass.kubernetes.io/is-default-class": "true"}},
+	"provisioner": "csi.storageos.com",
+	"parameters": {
+		"csi.storage.k8s.io/provisioner-secret-name": "csi-provisioner-secret",
+		"csi.storage.k8s.io/provisioner-secret-namespace": "storageos",
+		"csi.storage.k8s.io/controller-publish-secret-name": "csi-controller-publish-secret",
+		"csi.storage.k8s.io/controller-publish-secret-namespace": "storageos",
+		"csi.storage.k8s.io/node-publish-secret-name": "csi-node-publish-secret",
+		"csi.storage.k8s.io/node-publish-secret-namespace": "storageos"
+	}
+}, "events": []}`
+
+func TestAnalyzeCSI(t *testing.T) {
+	defer func(n func() time.Time) { now = n }(now)
+	now = func() time.Time {
+		return time.Date(2020, 6, 1, 15, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []*analyzer.AnalyzeResult
+	}{
+		{
+			name:  "nothing collected",
+			files: map[string]string{},
+			want:  []*analyzer.AnalyzeResult{},
+		},
+		{
+			name: "StorageClass parameters",
+			files: map[string]string{
+				classKind.file(): `[` + storageOSClass + `,
+					{"object": {"metadata": {"name": "fast"}, "provisioner": "csi.storageos.com", "parameters": {
+						"csi.storage.k8s.io/provisioner-secret-name": "csi-provisioner-secret",
+						"csi.storage.k8s.io/provisioner-secret-namespace": "storageos",
+						"csi.storage.k8s.io/node-publish-secret-name": ""
+					}}},
+					{"object": {"metadata": {"name": "standard"}, "provisioner": "kubernetes.io/gce-pd"}}
+				]`,
+			},
+			want: []*analyzer.AnalyzeResult{
+				{IsFail: true, Title: "StorageOS StorageClasses", Message: "StorageClass fast is missing the parameters csi.storage.k8s.io/controller-publish-secret-name, csi.storage.k8s.io/controller-publish-secret-namespace, csi.storage.k8s.io/node-publish-secret-name, csi.storage.k8s.io/node-publish-secret-namespace."},
+			},
+		},
+		{
+			name: "StorageClasses of other provisioners",
+			files: map[string]string{
+				classKind.file(): `[{"object": {"metadata": {"name": "standard"}, "provisioner": "kubernetes.io/gce-pd"}}]`,
+			},
+			want: []*analyzer.AnalyzeResult{},
+		},
+		{
+			name: "Pending claims",
+			files: map[string]string{
+				classKind.file(): `[` + storageOSClass + `,
+					{"object": {"metadata": {"name": "local"}, "provisioner": "kubernetes.io/no-provisioner", "volumeBindingMode": "WaitForFirstConsumer"}}
+				]`,
+				pvcKind.file(): `[
+					{"object": {"metadata": {"name": "bound", "namespace": "default", "creationTimestamp": "2020-06-01T12:00:00Z"}, "spec": {"storageClassName": "storageos"}, "status": {"phase": "Bound"}}},
+					{"object": {"metadata": {"name": "failing", "namespace": "default", "creationTimestamp": "2020-06-01T14:59:00Z"}, "spec": {"storageClassName": "storageos"}, "status": {"phase": "Pending"}}, "events": [
+						{"type": "Warning", "message": "failed to provision volume: rpc error: code = Unavailable", "lastTimestamp": "2020-06-01T14:59:30Z"},
+						{"type": "Warning", "message": "failed to provision volume: rpc error: code = Unauthenticated", "lastTimestamp": "2020-06-01T14:59:50Z"},
+						{"type": "Normal", "message": "waiting for a volume to be created", "lastTimestamp": "2020-06-01T14:59:55Z"}
+					]},
+					{"object": {"metadata": {"name": "stuck", "namespace": "default", "creationTimestamp": "2020-06-01T14:30:00Z"}, "spec": {}, "status": {"phase": "Pending"}}},
+					{"object": {"metadata": {"name": "new", "namespace": "default", "creationTimestamp": "2020-06-01T14:58:00Z"}, "spec": {"storageClassName": "storageos"}, "status": {"phase": "Pending"}}},
+					{"object": {"metadata": {"name": "waiting", "namespace": "default", "creationTimestamp": "2020-06-01T12:00:00Z"}, "spec": {"storageClassName": "local"}, "status": {"phase": "Pending"}}}
+				]`,
+			},
+			want: []*analyzer.AnalyzeResult{
+				{IsPass: true, Title: "StorageOS StorageClasses", Message: "All 1 StorageOS StorageClasses have the required parameters."},
+				{IsFail: true, Title: "PersistentVolumeClaims", Message: "PersistentVolumeClaim default/failing is Pending: failed to provision volume: rpc error: code = Unauthenticated"},
+				{IsWarn: true, Title: "PersistentVolumeClaims", Message: "PersistentVolumeClaim default/stuck has been Pending for 30m0s."},
+			},
+		},
+		{
+			name: "no Pending claims",
+			files: map[string]string{
+				pvcKind.file(): `[{"object": {"metadata": {"name": "bound", "namespace": "default"}, "status": {"phase": "Bound"}}}]`,
+			},
+			want: []*analyzer.AnalyzeResult{
+				{IsPass: true, Title: "PersistentVolumeClaims", Message: "No PersistentVolumeClaims are stuck Pending."},
+			},
+		},
+		{
+			name: "attach errors",
+			files: map[string]string{
+				attachKind.file(): `[
+					{"object": {"metadata": {"name": "csi-1"}, "spec": {"nodeName": "node-a", "source": {"persistentVolumeName": "pvc-1"}}, "status": {"attached": true}}},
+					{"object": {"metadata": {"name": "csi-2"}, "spec": {"nodeName": "node-b", "source": {"persistentVolumeName": "pvc-2"}}, "status": {"attached": false, "attachError": {"message": "volume is not available", "time": "2020-06-01T14:00:00Z"}}}},
+					{"object": {"metadata": {"name": "csi-3"}, "spec": {"nodeName": "node-c", "source": {"persistentVolumeName": "pvc-3"}}, "status": {"attached": true, "detachError": {"message": "node is unreachable"}}}}
+				]`,
+			},
+			want: []*analyzer.AnalyzeResult{
+				{IsFail: true, Title: "VolumeAttachments", Message: "VolumeAttachment csi-2 of pvc-2 to node node-b failed to attach: volume is not available"},
+				{IsWarn: true, Title: "VolumeAttachments", Message: "VolumeAttachment csi-3 of pvc-3 to node node-c failed to detach: node is unreachable"},
+			},
+		},
+		{
+			name: "no attach errors",
+			files: map[string]string{
+				attachKind.file(): `[{"object": {"metadata": {"name": "csi-1"}, "spec": {"nodeName": "node-a", "source": {"persistentVolumeName": "pvc-1"}}, "status": {"attached": true}}}]`,
+			},
+			want: []*analyzer.AnalyzeResult{
+				{IsPass: true, Title: "VolumeAttachments", Message: "None of the 1 VolumeAttachments have attach or detach errors."},
+			},
+		},
+		{
+			name: "CSINode missing the driver",
+			files: map[string]string{
+				csiDriverKind.file(): `[{"object": {"metadata": {"name": "csi.storageos.com"}}}]`,
+				csiNodeKind.file(): `[
+					{"object": {"metadata": {"name": "node-a"}, "spec": {"drivers": [{"name": "csi.storageos.com", "nodeID": "node-a"}]}}},
+					{"object": {"metadata": {"name": "node-b"}, "spec": {"drivers": [{"name": "pd.csi.storage.gke.io", "nodeID": "node-b"}]}}},
+					{"object": {"metadata": {"name": "node-c"}, "spec": {"drivers": null}}}
+				]`,
+			},
+			want: []*analyzer.AnalyzeResult{
+				{IsWarn: true, Title: "StorageOS CSI Driver", Message: "The StorageOS CSI driver is not registered on 2 of 3 nodes, which cannot mount StorageOS volumes: node-b, node-c."},
+			},
+		},
+		{
+			name: "CSINodes with the driver",
+			files: map[string]string{
+				csiNodeKind.file(): `[{"object": {"metadata": {"name": "node-a"}, "spec": {"drivers": [{"name": "csi.storageos.com"}]}}}]`,
+			},
+			want: []*analyzer.AnalyzeResult{
+				{IsPass: true, Title: "StorageOS CSI Driver", Message: "The StorageOS CSI driver is registered on all 1 nodes."},
+			},
+		},
+		{
+			name: "driver not installed",
+			files: map[string]string{
+				csiNodeKind.file(): `[{"object": {"metadata": {"name": "node-a"}, "spec": {"drivers": []}}}]`,
+			},
+			want: []*analyzer.AnalyzeResult{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := AnalyzeCSI(bundleFiles(tt.files))
+			if err != nil {
+				t.Fatalf("AnalyzeCSI() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AnalyzeCSI() =")
+				for _, r := range got {
+					t.Errorf("  %+v", r)
+				}
+			}
+		})
+	}
+}
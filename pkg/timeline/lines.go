@@ -0,0 +1,56 @@
+package timeline
+
+import (
+	"bufio"
+	"io"
+)
+
+// MaxLineLength is the longest log line read whole. Longer lines, such as a
+// dumped request body, are cut to this length.
+const MaxLineLength = 1024 * 1024
+
+// LineScanner reads the lines of a log like bufio.Scanner, except that a line
+// longer than MaxLineLength is cut short instead of stopping the scan.
+type LineScanner struct {
+	*bufio.Scanner
+	truncated  bool
+	discarding bool
+}
+
+// NewLineScanner returns a LineScanner reading from r.
+func NewLineScanner(r io.Reader) *LineScanner {
+	s := &LineScanner{Scanner: bufio.NewScanner(r)}
+	s.Buffer(make([]byte, 64*1024), MaxLineLength)
+	s.Split(s.split)
+	return s
+}
+
+// Truncated returns whether the last line read was cut short.
+func (s *LineScanner) Truncated() bool {
+	return s.truncated
+}
+
+// split splits like bufio.ScanLines. When the buffer fills up without a line
+// ending, the buffer is returned as the line and the rest of the line is
+// dropped.
+func (s *LineScanner) split(data []byte, atEOF bool) (int, []byte, error) {
+	advance, token, err := bufio.ScanLines(data, atEOF)
+	if advance > 0 || token != nil || err != nil {
+		if s.discarding {
+			s.discarding = false
+			return advance, nil, err
+		}
+		s.truncated = false
+		return advance, token, err
+	}
+	if len(data) < MaxLineLength {
+		return 0, nil, nil
+	}
+
+	if s.discarding {
+		return len(data), nil, nil
+	}
+	s.discarding = true
+	s.truncated = true
+	return len(data), data, nil
+}
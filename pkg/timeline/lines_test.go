@@ -0,0 +1,39 @@
+package timeline
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLineScanner(t *testing.T) {
+	long := strings.Repeat("x", 3*MaxLineLength+10)
+	input := "first\n" + long + "\nlast\r\n" + long
+
+	type line struct {
+		length    int
+		truncated bool
+	}
+	want := []line{
+		{length: len("first")},
+		{length: MaxLineLength, truncated: true},
+		{length: len("last")},
+		{length: MaxLineLength, truncated: true},
+	}
+
+	s := NewLineScanner(strings.NewReader(input))
+	got := []line{}
+	for s.Scan() {
+		got = append(got, line{length: len(s.Text()), truncated: s.Truncated()})
+	}
+	if err := s.Err(); err != nil {
+		t.Fatalf("Err() = %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("lines = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("line %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package timeline
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxTimestampOffset is how far into a line a timestamp is looked for.
+const maxTimestampOffset = 200
+
+var (
+	// kubectl logs --timestamps prefix, which is removed from the line
+	prefixTimestamp = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:\d{2})) `)
+	// logrus text format, as StorageOS logs
+	logrusTimestamp = regexp.MustCompile(`\btime="([^"]+)"`)
+	// JSON logs
+	jsonTimestamp = regexp.MustCompile(`"(?:time|ts|timestamp|@timestamp)":\s*"([^"]+)"`)
+	// zap JSON logs, in seconds since the epoch
+	epochTimestamp = regexp.MustCompile(`"ts":\s*(\d{9,}(?:\.\d+)?)`)
+	// klog, without a year: I0102 15:04:05.123456
+	klogTimestamp = regexp.MustCompile(`^[IWEF](\d{2})(\d{2}) (\d{2}:\d{2}:\d{2}(?:\.\d+)?)`)
+	// anything else that starts with a date, taken to be UTC
+	dateTimestamp = regexp.MustCompile(`^\[?(\d{4}[-/]\d{2}[-/]\d{2}[ T]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|[+-]\d{2}:?\d{2})?)`)
+)
+
+var layouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999Z0700",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z0700",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+}
+
+// LineTime returns the time of a log line, or false if it has no timestamp.
+// Timestamps without a year are taken to be in the year up to reference.
+func LineTime(line string, reference time.Time) (time.Time, bool) {
+	ts, _, ok := parseLine(line, reference)
+	return ts, ok
+}
+
+// parseLine returns the time of a log line and the line to show for it. ok
+// is false if the line has no timestamp.
+func parseLine(line string, reference time.Time) (ts time.Time, text string, ok bool) {
+	if m := prefixTimestamp.FindStringSubmatch(line); m != nil {
+		if ts, ok := parseTimestamp(m[1]); ok {
+			return ts, line[len(m[0]):], true
+		}
+	}
+
+	head := line
+	if len(head) > maxTimestampOffset {
+		head = head[:maxTimestampOffset]
+	}
+
+	for _, re := range []*regexp.Regexp{logrusTimestamp, jsonTimestamp, dateTimestamp} {
+		if m := re.FindStringSubmatch(head); m != nil {
+			if ts, ok := parseTimestamp(m[1]); ok {
+				return ts, line, true
+			}
+		}
+	}
+
+	if m := epochTimestamp.FindStringSubmatch(head); m != nil {
+		if seconds, err := strconv.ParseFloat(m[1], 64); err == nil {
+			// a float64 of seconds since the epoch holds microseconds
+			return time.Unix(0, int64(seconds*float64(time.Second))).Round(time.Microsecond).UTC(), line, true
+		}
+	}
+
+	if m := klogTimestamp.FindStringSubmatch(head); m != nil {
+		if ts, ok := parseKlogTimestamp(m[1], m[2], m[3], reference); ok {
+			return ts, line, true
+		}
+	}
+
+	return time.Time{}, line, false
+}
+
+func parseTimestamp(s string) (time.Time, bool) {
+	s = strings.Replace(s, "/", "-", 2)
+	s = strings.Replace(s, ",", ".", 1)
+	for _, layout := range layouts {
+		if ts, err := time.Parse(layout, s); err == nil {
+			return ts.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseKlogTimestamp returns the time of a klog header, in the year up to
+// reference.
+func parseKlogTimestamp(month, day, clock string, reference time.Time) (time.Time, bool) {
+	if reference.IsZero() {
+		reference = time.Now()
+	}
+	reference = reference.UTC()
+
+	year := reference.Year()
+	ts, err := time.Parse("2006-01-02 15:04:05.999999999", strconv.Itoa(year)+"-"+month+"-"+day+" "+clock)
+	if err != nil {
+		return time.Time{}, false
+	}
+	// a bundle collected in January has December's logs from the year before
+	if ts.After(reference.Add(24 * time.Hour)) {
+		ts = ts.AddDate(-1, 0, 0)
+	}
+	return ts, true
+}
@@ -0,0 +1,155 @@
+package timeline
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLine(t *testing.T) {
+	reference := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		line     string
+		want     time.Time
+		wantText string
+		wantOK   bool
+	}{
+		{
+			name:     "kubectl logs --timestamps prefix",
+			line:     "2020-06-01T10:00:00.123456789Z level=info msg=\"started\"",
+			want:     time.Date(2020, 6, 1, 10, 0, 0, 123456789, time.UTC),
+			wantText: "level=info msg=\"started\"",
+			wantOK:   true,
+		},
+		{
+			name:     "prefix with offset",
+			line:     "2020-06-01T12:00:00+02:00 started",
+			want:     time.Date(2020, 6, 1, 10, 0, 0, 0, time.UTC),
+			wantText: "started",
+			wantOK:   true,
+		},
+		{
+			name:   "logrus",
+			line:   `time="2020-06-01T10:00:00Z" level=error msg="etcd connection refused"`,
+			want:   time.Date(2020, 6, 1, 10, 0, 0, 0, time.UTC),
+			wantOK: true,
+		},
+		{
+			name:   "json",
+			line:   `{"level":"info","timestamp": "2020-06-01T10:00:00.5+01:00","msg":"started"}`,
+			want:   time.Date(2020, 6, 1, 9, 0, 0, 500000000, time.UTC),
+			wantOK: true,
+		},
+		{
+			name:   "zap epoch",
+			line:   `{"level":"info","ts":1591005600.123456,"msg":"started"}`,
+			want:   time.Date(2020, 6, 1, 10, 0, 0, 123456000, time.UTC),
+			wantOK: true,
+		},
+		{
+			name:   "klog",
+			line:   "E0601 10:00:00.123456       1 controller.go:42] sync failed",
+			want:   time.Date(2020, 6, 1, 10, 0, 0, 123456000, time.UTC),
+			wantOK: true,
+		},
+		{
+			name:   "date",
+			line:   "2020-06-01 10:00:00,250 ERROR sync failed",
+			want:   time.Date(2020, 6, 1, 10, 0, 0, 250000000, time.UTC),
+			wantOK: true,
+		},
+		{
+			name:   "bracketed date with slashes and offset",
+			line:   "[2020/06/01 10:00:00-0500] sync failed",
+			want:   time.Date(2020, 6, 1, 15, 0, 0, 0, time.UTC),
+			wantOK: true,
+		},
+		{
+			name: "no timestamp",
+			line: "goroutine 1 [running]:",
+		},
+		{
+			name: "timestamp too far into the line",
+			line: strings.Repeat("x", maxTimestampOffset) + ` time="2020-06-01T10:00:00Z"`,
+		},
+		{
+			name: "invalid timestamp",
+			line: `time="yesterday" level=info`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, text, ok := parseLine(tt.line, reference)
+			if ok != tt.wantOK {
+				t.Fatalf("parseLine() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ts.Equal(tt.want) {
+				t.Errorf("parseLine() time = %v, want %v", ts, tt.want)
+			}
+			wantText := tt.wantText
+			if wantText == "" {
+				wantText = tt.line
+			}
+			if text != wantText {
+				t.Errorf("parseLine() text = %q, want %q", text, wantText)
+			}
+		})
+	}
+}
+
+func TestParseKlogYear(t *testing.T) {
+	tests := []struct {
+		name      string
+		reference time.Time
+		line      string
+		want      time.Time
+	}{
+		{
+			name:      "same year",
+			reference: time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC),
+			line:      "I0315 08:00:00.000000 1 main.go:1] started",
+			want:      time.Date(2021, 3, 15, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "december logs in a january bundle",
+			reference: time.Date(2021, 1, 2, 12, 0, 0, 0, time.UTC),
+			line:      "I1231 23:59:59.000000 1 main.go:1] started",
+			want:      time.Date(2020, 12, 31, 23, 59, 59, 0, time.UTC),
+		},
+		{
+			// the nodes' clocks may be a little ahead of the reference
+			name:      "less than a day ahead",
+			reference: time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC),
+			line:      "I0602 06:00:00.000000 1 main.go:1] started",
+			want:      time.Date(2021, 6, 2, 6, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "more than a day ahead",
+			reference: time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC),
+			line:      "I0603 12:00:00.000000 1 main.go:1] started",
+			want:      time.Date(2020, 6, 3, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "reference in another time zone",
+			reference: time.Date(2021, 1, 1, 1, 0, 0, 0, time.FixedZone("CET", 3600)),
+			line:      "I1231 23:30:00.000000 1 main.go:1] started",
+			want:      time.Date(2020, 12, 31, 23, 30, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		ts, ok := LineTime(tt.line, tt.reference)
+		if !ok {
+			t.Errorf("%s: LineTime(%q) found no timestamp", tt.name, tt.line)
+			continue
+		}
+		if !ts.Equal(tt.want) {
+			t.Errorf("%s: LineTime(%q) = %v, want %v", tt.name, tt.line, ts, tt.want)
+		}
+	}
+
+	if _, ok := LineTime("I1332 10:00:00.000000 1 main.go:1] started", time.Now()); ok {
+		t.Error("LineTime() of an invalid klog date found a timestamp")
+	}
+}
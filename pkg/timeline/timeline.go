@@ -0,0 +1,203 @@
+// Package timeline merges the log lines and Kubernetes events in a support
+// bundle into a single list ordered by time, so that an incident can be
+// followed across pods.
+package timeline
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Filename is the name of the timeline written into a support bundle.
+const Filename = "timeline.log"
+
+// eventsDir holds the events collected by the clusterResources collector, a
+// file per namespace.
+const eventsDir = "cluster-resources/events/"
+
+// eventSourcePrefix starts the source of the events in a timeline.
+const eventSourcePrefix = "events/"
+
+// Entry is a log line or an event.
+type Entry struct {
+	Time time.Time
+	// Source is the log file the line is from, without its extension, or
+	// events/<namespace> for an event.
+	Source string
+	Text   string
+}
+
+// String formats the entry as a line of the timeline.
+func (e Entry) String() string {
+	return fmt.Sprintf("%s [%s] %s", e.Time.UTC().Format(timeFormat), e.Source, e.Text)
+}
+
+// timeFormat has a fixed width, so that the lines of a timeline line up.
+const timeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+// Timeline is built from the files of a bundle with Add.
+type Timeline struct {
+	// reference is when the bundle was collected. Timestamps without a year
+	// are taken to be in the year up to it.
+	reference time.Time
+	entries   []Entry
+	// Untimed are the logs that were left out because none of their lines
+	// have a timestamp.
+	Untimed []string
+}
+
+// New returns an empty timeline of a bundle collected at reference.
+func New(reference time.Time) *Timeline {
+	return &Timeline{reference: reference}
+}
+
+// Add adds the lines or events in a file of the bundle. name is the path of
+// the file in the bundle. Files other than logs and events are ignored.
+func (t *Timeline) Add(name string, r io.Reader) error {
+	switch {
+	case name == Filename:
+		return nil
+	case path.Ext(name) == ".log":
+		return errors.Wrapf(t.addLog(strings.TrimSuffix(name, ".log"), r), "read %s", name)
+	case strings.HasPrefix(name, eventsDir) && path.Ext(name) == ".json":
+		namespace := strings.TrimSuffix(strings.TrimPrefix(name, eventsDir), ".json")
+		return errors.Wrapf(t.addEvents(eventSourcePrefix+namespace, r), "read %s", name)
+	}
+	return nil
+}
+
+// addLog adds the lines of a log. Lines without a timestamp, such as stack
+// traces, get the time of the line before them. Over-long lines are skipped.
+func (t *Timeline) addLog(source string, r io.Reader) error {
+	var last time.Time
+	pending := []string{}
+
+	scanner := NewLineScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		// lines longer than MaxLineLength are left out, rather than cut
+		if scanner.Truncated() || strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		ts, text, ok := parseLine(line, t.reference)
+		if !ok {
+			if last.IsZero() {
+				// the first lines get the time of the first timestamp
+				pending = append(pending, line)
+				continue
+			}
+			ts, text = last, line
+		}
+		for _, p := range pending {
+			t.entries = append(t.entries, Entry{Time: ts, Source: source, Text: p})
+		}
+		pending = pending[:0]
+
+		t.entries = append(t.entries, Entry{Time: ts, Source: source, Text: text})
+		last = ts
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if last.IsZero() && len(pending) > 0 {
+		t.Untimed = append(t.Untimed, source)
+	}
+	return nil
+}
+
+func (t *Timeline) addEvents(source string, r io.Reader) error {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	events := []corev1.Event{}
+	if err := json.Unmarshal(b, &events); err != nil {
+		list := corev1.EventList{}
+		if err := json.Unmarshal(b, &list); err != nil {
+			return errors.Wrap(err, "parse events")
+		}
+		events = list.Items
+	}
+
+	for _, event := range events {
+		ts := eventTime(event)
+		if ts.IsZero() {
+			continue
+		}
+		text := fmt.Sprintf("%s %s %s/%s: %s", event.Type, event.Reason, event.InvolvedObject.Kind, event.InvolvedObject.Name, strings.TrimSpace(event.Message))
+		if event.Count > 1 {
+			text = fmt.Sprintf("%s (x%d)", text, event.Count)
+		}
+		t.entries = append(t.entries, Entry{Time: ts, Source: source, Text: text})
+	}
+	return nil
+}
+
+// eventTime returns when an event last happened.
+func eventTime(event corev1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	case event.Series != nil && !event.Series.LastObservedTime.IsZero():
+		return event.Series.LastObservedTime.Time
+	}
+	return event.FirstTimestamp.Time
+}
+
+// Entries returns the entries from since until until, in time order. Entries
+// at the same time keep the order they were added in. A zero since or until
+// is unbounded.
+func (t *Timeline) Entries(since, until time.Time) []Entry {
+	sort.SliceStable(t.entries, func(i, j int) bool {
+		return t.entries[i].Time.Before(t.entries[j].Time)
+	})
+
+	entries := []Entry{}
+	for _, e := range t.entries {
+		if !since.IsZero() && e.Time.Before(since) {
+			continue
+		}
+		if !until.IsZero() && e.Time.After(until) {
+			break
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// Last returns the time of the latest entry, or zero if there are none.
+func (t *Timeline) Last() time.Time {
+	var last time.Time
+	for _, e := range t.entries {
+		if e.Time.After(last) {
+			last = e.Time
+		}
+	}
+	return last
+}
+
+// Write writes entries a line each.
+func Write(w io.Writer, entries []Entry) error {
+	bw := bufio.NewWriter(w)
+	for _, e := range entries {
+		if _, err := fmt.Fprintln(bw, e.String()); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,119 @@
+package timeline
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// lines formats entries the way they are written, since times parsed from
+// events are not in UTC.
+func lines(entries []Entry) string {
+	buf := &bytes.Buffer{}
+	Write(buf, entries)
+	return buf.String()
+}
+
+func TestTimeline(t *testing.T) {
+	tl := New(time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC))
+
+	files := []struct {
+		name    string
+		content string
+	}{
+		{
+			name: "storageos/logs/node-a.log",
+			content: "panic: runtime error\n" +
+				`time="2020-06-01T10:00:02Z" level=error msg="crashed"` + "\n" +
+				"goroutine 1 [running]:\n" +
+				"\n" +
+				`time="2020-06-01T10:00:05Z" level=info msg="restarted"` + "\n",
+		},
+		{
+			name: "storageos/logs/node-b.log",
+			// the over-long line is left out
+			content: "E0601 10:00:02.000000       1 node.go:12] lost connection\n" +
+				"I0601 10:00:04.000000       1 node.go:15] " + strings.Repeat("x", 2*MaxLineLength) + "\n" +
+				"I0601 10:00:06.000000       1 node.go:20] reconnected\n",
+		},
+		{
+			name:    "storageos/logs/untimed.log",
+			content: "no timestamps here\n",
+		},
+		{
+			name: "cluster-resources/events/storageos.json",
+			content: `[
+				{"type": "Warning", "reason": "BackOff", "message": "Back-off restarting failed container\n", "count": 3,
+				 "involvedObject": {"kind": "Pod", "name": "storageos-node-a"}, "lastTimestamp": "2020-06-01T10:00:03Z"},
+				{"type": "Normal", "reason": "Pulled", "message": "no time", "involvedObject": {"kind": "Pod", "name": "storageos-node-b"}}
+			]`,
+		},
+		{
+			name: "cluster-resources/events/default.json",
+			content: `{"items": [
+				{"type": "Normal", "reason": "Scheduled", "message": "Assigned", "count": 1,
+				 "involvedObject": {"kind": "Pod", "name": "app"}, "eventTime": "2020-06-01T09:59:00.000000Z"}
+			]}`,
+		},
+		// ignored
+		{name: Filename, content: "2020-06-01T10:00:00.000000Z [storageos/logs/node-a] crashed\n"},
+		{name: "cluster-resources/nodes.json", content: "[]"},
+	}
+	for _, f := range files {
+		if err := tl.Add(f.name, strings.NewReader(f.content)); err != nil {
+			t.Fatalf("Add(%s) error = %v", f.name, err)
+		}
+	}
+
+	at := func(sec int) time.Time {
+		return time.Date(2020, 6, 1, 10, 0, sec, 0, time.UTC)
+	}
+	want := []Entry{
+		{Time: time.Date(2020, 6, 1, 9, 59, 0, 0, time.UTC), Source: "events/default", Text: "Normal Scheduled Pod/app: Assigned"},
+		// lines before the first timestamp get its time, and lines without
+		// one the time of the line before them
+		{Time: at(2), Source: "storageos/logs/node-a", Text: "panic: runtime error"},
+		{Time: at(2), Source: "storageos/logs/node-a", Text: `time="2020-06-01T10:00:02Z" level=error msg="crashed"`},
+		{Time: at(2), Source: "storageos/logs/node-a", Text: "goroutine 1 [running]:"},
+		{Time: at(2), Source: "storageos/logs/node-b", Text: "E0601 10:00:02.000000       1 node.go:12] lost connection"},
+		{Time: at(3), Source: "events/storageos", Text: "Warning BackOff Pod/storageos-node-a: Back-off restarting failed container (x3)"},
+		{Time: at(5), Source: "storageos/logs/node-a", Text: `time="2020-06-01T10:00:05Z" level=info msg="restarted"`},
+		{Time: at(6), Source: "storageos/logs/node-b", Text: "I0601 10:00:06.000000       1 node.go:20] reconnected"},
+	}
+	if got := tl.Entries(time.Time{}, time.Time{}); lines(got) != lines(want) {
+		t.Errorf("Entries() =\n%s\nwant\n%s", lines(got), lines(want))
+	}
+	if got := tl.Entries(at(2), at(3)); lines(got) != lines(want[1:6]) {
+		t.Errorf("Entries(since, until) =\n%s\nwant\n%s", lines(got), lines(want[1:6]))
+	}
+
+	if !reflect.DeepEqual(tl.Untimed, []string{"storageos/logs/untimed"}) {
+		t.Errorf("Untimed = %v", tl.Untimed)
+	}
+	if last := tl.Last(); !last.Equal(at(6)) {
+		t.Errorf("Last() = %v, want %v", last, at(6))
+	}
+
+	if err := tl.Add("cluster-resources/events/broken.json", strings.NewReader("not json")); err == nil {
+		t.Error("Add() of invalid events succeeded")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	entries := []Entry{
+		{Time: time.Date(2020, 6, 1, 10, 0, 2, 0, time.UTC), Source: "storageos/logs/node-a", Text: "crashed"},
+		{Time: time.Date(2020, 6, 1, 12, 0, 3, 123456789, time.FixedZone("CEST", 7200)), Source: "events/storageos", Text: "Warning BackOff"},
+	}
+
+	buf := &bytes.Buffer{}
+	if err := Write(buf, entries); err != nil {
+		t.Fatal(err)
+	}
+	want := "2020-06-01T10:00:02.000000Z [storageos/logs/node-a] crashed\n" +
+		"2020-06-01T10:00:03.123456Z [events/storageos] Warning BackOff\n"
+	if buf.String() != want {
+		t.Errorf("Write() = %q, want %q", buf, want)
+	}
+}
@@ -0,0 +1,195 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/pkg/errors"
+)
+
+// defaultS3Region is used when no region is configured in the environment or
+// profile. S3-compatible stores such as MinIO accept any region, but the
+// request signature still needs one.
+const defaultS3Region = "us-east-1"
+
+// S3Location is a bucket and key prefix parsed from an s3://bucket/prefix URI.
+type S3Location struct {
+	Bucket string
+	Prefix string
+}
+
+// ParseS3URI parses an s3://bucket/prefix URI. The prefix is optional.
+func ParseS3URI(uri string) (*S3Location, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse s3 uri")
+	}
+	if u.Scheme != "s3" {
+		return nil, fmt.Errorf("unsupported upload scheme %q, expected s3://bucket/prefix", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("no bucket in s3 uri %q", uri)
+	}
+
+	return &S3Location{
+		Bucket: u.Host,
+		Prefix: strings.Trim(u.Path, "/"),
+	}, nil
+}
+
+// Key returns the object key for name under the location's prefix.
+func (l *S3Location) Key(name string) string {
+	return path.Join(l.Prefix, name)
+}
+
+// S3Options configures an S3Uploader. Credentials are taken from the
+// environment or, if Profile is set, the shared credentials file.
+type S3Options struct {
+	// Endpoint overrides the AWS endpoint, for S3-compatible stores. Requests
+	// to a custom endpoint use path-style addressing.
+	Endpoint string
+	Region   string
+	Profile  string
+
+	// PartSize is the size of each part of a multipart upload. Files no
+	// larger than PartSize are sent in a single request. 0 means the
+	// s3manager default.
+	PartSize   int64
+	MaxRetries int
+
+	// HTTPClient defaults to a client with a transport of its own, so that
+	// the bundle is never uploaded over a connection that was allowed to
+	// skip TLS verification for something else.
+	HTTPClient *http.Client
+}
+
+// S3Uploader uploads files to S3-compatible object storage using multipart
+// uploads. Parts that fail are retried, and the upload is aborted if it
+// cannot complete so that no orphaned parts are left in the bucket.
+type S3Uploader struct {
+	uploader *s3manager.Uploader
+
+	// Progress, if set, is called as the file is read for upload.
+	Progress func(sent int64, total int64)
+}
+
+// NewS3Uploader returns an S3Uploader configured with opts.
+func NewS3Uploader(opts S3Options) (*S3Uploader, error) {
+	if opts.PartSize != 0 && opts.PartSize < s3manager.MinUploadPartSize {
+		return nil, fmt.Errorf("s3 part size must be at least %d bytes", s3manager.MinUploadPartSize)
+	}
+
+	config := aws.Config{
+		MaxRetries: aws.Int(opts.MaxRetries),
+	}
+	if opts.Endpoint != "" {
+		config.Endpoint = aws.String(opts.Endpoint)
+		config.S3ForcePathStyle = aws.Bool(true)
+	}
+	if opts.Region != "" {
+		config.Region = aws.String(opts.Region)
+	}
+	config.HTTPClient = opts.HTTPClient
+	if config.HTTPClient == nil {
+		config.HTTPClient = newS3Client()
+	}
+
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            config,
+		Profile:           opts.Profile,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "create s3 session")
+	}
+	if aws.StringValue(sess.Config.Region) == "" {
+		sess.Config.Region = aws.String(defaultS3Region)
+	}
+
+	uploader := s3manager.NewUploader(sess, func(u *s3manager.Uploader) {
+		if opts.PartSize != 0 {
+			u.PartSize = opts.PartSize
+		}
+		u.LeavePartsOnError = false
+	})
+
+	return &S3Uploader{uploader: uploader}, nil
+}
+
+// newS3Client returns a client that does not share http.DefaultTransport,
+// whose TLS config the troubleshoot HTTP collectors change.
+func newS3Client() *http.Client {
+	return &http.Client{Transport: &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}}
+}
+
+// UploadFile uploads the file at filePath to the location, named after the
+// file, and returns the URL of the uploaded object.
+func (u *S3Uploader) UploadFile(ctx context.Context, loc *S3Location, filePath string, contentType string) (string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", errors.Wrap(err, "open file")
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", errors.Wrap(err, "stat file")
+	}
+
+	input := &s3manager.UploadInput{
+		Bucket: aws.String(loc.Bucket),
+		Key:    aws.String(loc.Key(path.Base(filePath))),
+		Body: &progressReader{
+			r:        f,
+			total:    info.Size(),
+			progress: u.Progress,
+		},
+	}
+	if contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+
+	out, err := u.uploader.UploadWithContext(ctx, input)
+	if err != nil {
+		return "", errors.Wrapf(err, "upload %s to s3://%s/%s", filePath, loc.Bucket, *input.Key)
+	}
+	return out.Location, nil
+}
+
+// UploadBytes uploads data to the location as an object called name.
+func (u *S3Uploader) UploadBytes(ctx context.Context, loc *S3Location, name string, data []byte, contentType string) error {
+	input := &s3manager.UploadInput{
+		Bucket: aws.String(loc.Bucket),
+		Key:    aws.String(loc.Key(name)),
+		Body:   bytes.NewReader(data),
+	}
+	if contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+
+	if _, err := u.uploader.UploadWithContext(ctx, input); err != nil {
+		return errors.Wrapf(err, "upload %s to s3://%s/%s", name, loc.Bucket, *input.Key)
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// fakeS3 is a stand-in for the path-style S3 API calls that s3manager makes.
+// Uploads of part number failPart, if set, are refused.
+type fakeS3 struct {
+	mu       sync.Mutex
+	objects  map[string][]byte
+	parts    map[int][]byte
+	aborted  bool
+	failPart int
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{
+		objects: map[string][]byte{},
+		parts:   map[int][]byte{},
+	}
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	body, _ := ioutil.ReadAll(r.Body)
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	query := r.URL.Query()
+
+	switch {
+	case r.Method == http.MethodPost && len(query["uploads"]) > 0:
+		fmt.Fprintf(w, "<InitiateMultipartUploadResult><UploadId>upload-1</UploadId></InitiateMultipartUploadResult>")
+	case r.Method == http.MethodPut && query.Get("uploadId") != "":
+		part, _ := strconv.Atoi(query.Get("partNumber"))
+		if part == s.failPart {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprintf(w, "<Error><Code>AccessDenied</Code><Message>denied</Message></Error>")
+			return
+		}
+		s.parts[part] = body
+		w.Header().Set("ETag", fmt.Sprintf(`"part-%d"`, part))
+	case r.Method == http.MethodPost && query.Get("uploadId") != "":
+		numbers := []int{}
+		for n := range s.parts {
+			numbers = append(numbers, n)
+		}
+		sort.Ints(numbers)
+		object := []byte{}
+		for _, n := range numbers {
+			object = append(object, s.parts[n]...)
+		}
+		s.objects[key] = object
+		fmt.Fprintf(w, "<CompleteMultipartUploadResult><Location>http://%s/%s</Location></CompleteMultipartUploadResult>", r.Host, key)
+	case r.Method == http.MethodDelete && query.Get("uploadId") != "":
+		s.aborted = true
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut:
+		s.objects[key] = body
+		w.Header().Set("ETag", `"object"`)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+// newTestS3Uploader returns an uploader for the fake S3 server at endpoint,
+// with credentials from the environment.
+func newTestS3Uploader(t *testing.T, endpoint string, partSize int64) *S3Uploader {
+	t.Helper()
+
+	for k, v := range map[string]string{
+		"AWS_ACCESS_KEY_ID":           "access-key",
+		"AWS_SECRET_ACCESS_KEY":       "secret-key",
+		"AWS_SHARED_CREDENTIALS_FILE": os.DevNull,
+		"AWS_CONFIG_FILE":             os.DevNull,
+	} {
+		old, ok := os.LookupEnv(k)
+		os.Setenv(k, v)
+		if ok {
+			defer os.Setenv(k, old)
+		} else {
+			defer os.Unsetenv(k)
+		}
+	}
+
+	u, err := NewS3Uploader(S3Options{
+		Endpoint: endpoint,
+		PartSize: partSize,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Uploader() error = %v", err)
+	}
+	return u
+}
+
+func TestS3UploadFile(t *testing.T) {
+	s3 := newFakeS3()
+	ts := httptest.NewServer(s3)
+	defer ts.Close()
+
+	path, data, cleanup := writeTestFile(t, 1000)
+	defer cleanup()
+
+	u := newTestS3Uploader(t, ts.URL, 0)
+	location, err := u.UploadFile(context.Background(), &S3Location{Bucket: "bundles", Prefix: "cluster-a"}, path, "application/tar+gzip")
+	if err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+
+	// a custom endpoint uses path-style addressing
+	key := "bundles/cluster-a/bundle.tar.gz"
+	if !bytes.Equal(s3.objects[key], data) {
+		t.Errorf("object %s has %d bytes, want %d", key, len(s3.objects[key]), len(data))
+	}
+	if want := ts.URL + "/" + key; location != want {
+		t.Errorf("UploadFile() = %q, want %q", location, want)
+	}
+}
+
+func TestS3UploadFileMultipart(t *testing.T) {
+	s3 := newFakeS3()
+	ts := httptest.NewServer(s3)
+	defer ts.Close()
+
+	path, data, cleanup := writeTestFile(t, 2*int(s3manager.MinUploadPartSize)+1000)
+	defer cleanup()
+
+	u := newTestS3Uploader(t, ts.URL, s3manager.MinUploadPartSize)
+	var progress int64
+	u.Progress = func(sent int64, total int64) { progress = sent }
+
+	if _, err := u.UploadFile(context.Background(), &S3Location{Bucket: "bundles"}, path, ""); err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+
+	key := "bundles/bundle.tar.gz"
+	if !bytes.Equal(s3.objects[key], data) {
+		t.Errorf("object %s has %d bytes, want %d", key, len(s3.objects[key]), len(data))
+	}
+	if len(s3.parts) != 3 {
+		t.Errorf("uploaded %d parts, want 3", len(s3.parts))
+	}
+	if progress != int64(len(data)) {
+		t.Errorf("progress = %d, want %d", progress, len(data))
+	}
+}
+
+func TestS3UploadFileAbort(t *testing.T) {
+	s3 := newFakeS3()
+	s3.failPart = 2
+	ts := httptest.NewServer(s3)
+	defer ts.Close()
+
+	path, _, cleanup := writeTestFile(t, 2*int(s3manager.MinUploadPartSize)+1000)
+	defer cleanup()
+
+	u := newTestS3Uploader(t, ts.URL, s3manager.MinUploadPartSize)
+	if _, err := u.UploadFile(context.Background(), &S3Location{Bucket: "bundles"}, path, ""); err == nil {
+		t.Fatal("UploadFile() succeeded, want an error")
+	}
+	if !s3.aborted {
+		t.Error("failed multipart upload was not aborted")
+	}
+	if len(s3.objects) != 0 {
+		t.Errorf("objects = %v, want none", s3.objects)
+	}
+}
+
+func TestNewS3UploaderPartSize(t *testing.T) {
+	if _, err := NewS3Uploader(S3Options{PartSize: 1024}); err == nil {
+		t.Error("NewS3Uploader() with a part size under the S3 minimum succeeded")
+	}
+}
+
+func TestParseS3URI(t *testing.T) {
+	tests := []struct {
+		uri     string
+		want    S3Location
+		wantErr bool
+	}{
+		{uri: "s3://bundles", want: S3Location{Bucket: "bundles"}},
+		{uri: "s3://bundles/cluster-a/", want: S3Location{Bucket: "bundles", Prefix: "cluster-a"}},
+		{uri: "s3://bundles/a/b", want: S3Location{Bucket: "bundles", Prefix: "a/b"}},
+		{uri: "https://bundles/a", wantErr: true},
+		{uri: "s3:///a", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseS3URI(tt.uri)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseS3URI(%q) error = %v, wantErr %v", tt.uri, err, tt.wantErr)
+			continue
+		}
+		if err == nil && *got != tt.want {
+			t.Errorf("ParseS3URI(%q) = %+v, want %+v", tt.uri, *got, tt.want)
+		}
+	}
+
+	loc := S3Location{Bucket: "bundles", Prefix: "cluster-a"}
+	if key := loc.Key("bundle.tar.gz"); key != "cluster-a/bundle.tar.gz" {
+		t.Errorf("Key() = %q", key)
+	}
+}
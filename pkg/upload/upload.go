@@ -0,0 +1,300 @@
+package upload
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// statusResumeIncomplete is returned by the server for every chunk except the
+// last one.
+const statusResumeIncomplete = 308
+
+// Uploader uploads files over HTTP, retrying failed requests with exponential
+// backoff.
+//
+// Files larger than ChunkSize are sent in chunks with a Content-Range header,
+// so that a failed upload resumes from the last chunk the server acknowledged
+// instead of starting again. The server replies 308 with a Range header of
+// the bytes it has received to every chunk but the last, and 200 or 201 once
+// the upload is complete. A request with an empty body and a Content-Range of
+// "bytes */<size>" asks the server how much it has received.
+type Uploader struct {
+	Client *http.Client
+
+	// ChunkSize is the maximum request body size. Files no larger than
+	// ChunkSize, or any file if ChunkSize is 0, are sent in a single request.
+	ChunkSize int64
+
+	// MaxRetries is the number of times in a row a request is retried before
+	// the upload fails.
+	MaxRetries     int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	// Progress, if set, is called as the file is sent.
+	Progress func(sent int64, total int64)
+}
+
+// NewUploader returns an Uploader with the default backoff.
+func NewUploader(client *http.Client, chunkSize int64, maxRetries int) *Uploader {
+	return &Uploader{
+		Client:         client,
+		ChunkSize:      chunkSize,
+		MaxRetries:     maxRetries,
+		InitialBackoff: time.Second,
+		MaxBackoff:     30 * time.Second,
+	}
+}
+
+// StatusError is returned when the server replies with an unexpected status.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code %d", e.StatusCode)
+}
+
+// IncompleteError is returned when the server ends the upload before the
+// whole file was sent, or asks for more of a file that was sent in a single
+// request. Servers that ignore Content-Range, such as S3 presigned URLs,
+// reply 200 to the first chunk and keep only that.
+type IncompleteError struct {
+	StatusCode int
+	Sent       int64
+	Size       int64
+}
+
+func (e IncompleteError) Error() string {
+	return fmt.Sprintf("server replied %d after %d of %d bytes were sent", e.StatusCode, e.Sent, e.Size)
+}
+
+// Upload sends the file at path to uri.
+func (u *Uploader) Upload(method string, uri string, path string, contentType string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.Wrap(err, "open file")
+	}
+	defer f.Close()
+
+	fileStat, err := f.Stat()
+	if err != nil {
+		return errors.Wrap(err, "stat file")
+	}
+	size := fileStat.Size()
+
+	if u.ChunkSize <= 0 || size <= u.ChunkSize {
+		return u.retry(func() error {
+			status, _, err := u.send(method, uri, contentType, f, 0, size, size, false)
+			if err == nil && status == statusResumeIncomplete {
+				return IncompleteError{StatusCode: status, Sent: size, Size: size}
+			}
+			return err
+		})
+	}
+
+	var offset int64
+	backoff := u.InitialBackoff
+	failures := 0
+	for {
+		length := size - offset
+		if length > u.ChunkSize {
+			length = u.ChunkSize
+		}
+
+		status, next, err := u.send(method, uri, contentType, f, offset, length, size, true)
+		if err == nil {
+			if status != statusResumeIncomplete {
+				return completed(status, offset+length, size)
+			}
+			if next > offset && next <= size {
+				offset = next
+				backoff = u.InitialBackoff
+				failures = 0
+				continue
+			}
+			// a server that keeps asking for the same bytes is retried like
+			// a failed request, so that it can't keep the upload going
+			// forever
+			err = errors.Errorf("server has %d bytes, expected more than %d", next, offset)
+		}
+
+		if !isRetryable(err) || failures >= u.MaxRetries {
+			return errors.Wrapf(err, "upload chunk at offset %d", offset)
+		}
+		failures++
+		time.Sleep(backoff)
+		backoff = u.nextBackoff(backoff)
+
+		// resume from what the server has, or resend the chunk if it can't
+		// tell us
+		status, next, err = u.queryOffset(method, uri, size)
+		if err == nil {
+			if status != statusResumeIncomplete {
+				return completed(status, offset+length, size)
+			}
+			if next <= size {
+				offset = next
+			}
+		}
+	}
+}
+
+// completed checks a reply that ends a chunked upload after sent bytes.
+func completed(status int, sent int64, size int64) error {
+	if sent != size {
+		return IncompleteError{StatusCode: status, Sent: sent, Size: size}
+	}
+	return nil
+}
+
+// send sends length bytes of f from offset. Chunked requests get a
+// Content-Range header. It returns the status code and, for a 308, the offset
+// to send next.
+func (u *Uploader) send(method string, uri string, contentType string, f *os.File, offset int64, length int64, size int64, chunked bool) (int, int64, error) {
+	body := &progressReader{
+		r:        io.NewSectionReader(f, offset, length),
+		sent:     offset,
+		total:    size,
+		progress: u.Progress,
+	}
+
+	req, err := http.NewRequest(method, uri, body)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "create request")
+	}
+	req.ContentLength = length
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if chunked {
+		req.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, offset+length-1, size))
+	}
+
+	return u.do(req)
+}
+
+// queryOffset asks the server how much of the upload it has received.
+func (u *Uploader) queryOffset(method string, uri string, size int64) (int, int64, error) {
+	req, err := http.NewRequest(method, uri, nil)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "create request")
+	}
+	req.ContentLength = 0
+	req.Header.Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+
+	return u.do(req)
+}
+
+// do sends req. It returns the status code and, for a 308, the offset after
+// the last byte the server has.
+func (u *Uploader) do(req *http.Request) (int, int64, error) {
+	resp, err := u.Client.Do(req)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "execute request")
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode == statusResumeIncomplete:
+		next, err := parseRange(resp.Header.Get("Range"))
+		return resp.StatusCode, next, err
+	case resp.StatusCode >= 300:
+		return resp.StatusCode, 0, StatusError{StatusCode: resp.StatusCode}
+	}
+
+	return resp.StatusCode, 0, nil
+}
+
+// parseRange returns the offset after the last byte in a "bytes=0-N" Range
+// header. No header means that nothing has been received.
+func parseRange(header string) (int64, error) {
+	if header == "" {
+		return 0, nil
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(header, "bytes="), "-", 2)
+	if len(parts) != 2 {
+		return 0, errors.Errorf("invalid Range header %q", header)
+	}
+
+	last, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid Range header %q", header)
+	}
+
+	return last + 1, nil
+}
+
+// retry calls fn until it succeeds, returns an error that can't be retried,
+// or has failed MaxRetries times.
+func (u *Uploader) retry(fn func() error) error {
+	backoff := u.InitialBackoff
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		if !isRetryable(err) || attempt >= u.MaxRetries {
+			return err
+		}
+
+		time.Sleep(backoff)
+		backoff = u.nextBackoff(backoff)
+	}
+}
+
+func (u *Uploader) nextBackoff(backoff time.Duration) time.Duration {
+	backoff = backoff * 2
+	if u.MaxBackoff > 0 && backoff > u.MaxBackoff {
+		return u.MaxBackoff
+	}
+	return backoff
+}
+
+// isRetryable returns true for network errors and for server errors that may
+// go away.
+func isRetryable(err error) bool {
+	if _, ok := errors.Cause(err).(IncompleteError); ok {
+		return false
+	}
+	statusErr, ok := errors.Cause(err).(StatusError)
+	if !ok {
+		return true
+	}
+
+	switch {
+	case statusErr.StatusCode >= 500:
+		return true
+	case statusErr.StatusCode == http.StatusRequestTimeout:
+		return true
+	case statusErr.StatusCode == http.StatusTooManyRequests:
+		return true
+	}
+	return false
+}
+
+type progressReader struct {
+	r        io.Reader
+	sent     int64
+	total    int64
+	progress func(sent int64, total int64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.sent += int64(n)
+	if p.progress != nil && n > 0 {
+		p.progress(p.sent, p.total)
+	}
+	return n, err
+}
@@ -0,0 +1,298 @@
+package upload
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// resumableServer is a stand-in for a server that accepts chunked uploads
+// with Content-Range. fail is called with the number of each request and
+// returns a status to reply with instead of handling it.
+type resumableServer struct {
+	mu       sync.Mutex
+	data     []byte
+	requests int
+	fail     func(request int) int
+}
+
+var contentRange = regexp.MustCompile(`^bytes (\d+)-(\d+)/(\d+)$`)
+
+func (s *resumableServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests++
+	body, _ := ioutil.ReadAll(r.Body)
+	if s.fail != nil {
+		if status := s.fail(s.requests); status != 0 {
+			w.WriteHeader(status)
+			return
+		}
+	}
+
+	header := r.Header.Get("Content-Range")
+	if header == "" {
+		s.data = body
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var size int
+	if m := contentRange.FindStringSubmatch(header); m != nil {
+		first, _ := strconv.Atoi(m[1])
+		size, _ = strconv.Atoi(m[3])
+		if first == len(s.data) {
+			s.data = append(s.data, body...)
+		}
+	} else if _, err := fmt.Sscanf(header, "bytes */%d", &size); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if len(s.data) == size {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if len(s.data) > 0 {
+		w.Header().Set("Range", fmt.Sprintf("bytes=0-%d", len(s.data)-1))
+	}
+	w.WriteHeader(statusResumeIncomplete)
+}
+
+// writeTestFile writes size bytes to a temporary file. The caller removes it
+// with the returned func.
+func writeTestFile(t *testing.T, size int) (string, []byte, func()) {
+	t.Helper()
+
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+
+	dir, err := ioutil.TempDir("", "upload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	path := filepath.Join(dir, "bundle.tar.gz")
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	return path, data, cleanup
+}
+
+func testUploader(chunkSize int64) *Uploader {
+	u := NewUploader(http.DefaultClient, chunkSize, 3)
+	u.InitialBackoff = time.Millisecond
+	u.MaxBackoff = time.Millisecond
+	return u
+}
+
+func TestUploadSingleRequest(t *testing.T) {
+	path, data, cleanup := writeTestFile(t, 100)
+	defer cleanup()
+	server := &resumableServer{
+		// fail twice, then succeed
+		fail: func(request int) int {
+			if request <= 2 {
+				return http.StatusServiceUnavailable
+			}
+			return 0
+		},
+	}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	if err := testUploader(0).Upload(http.MethodPut, ts.URL, path, ""); err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+	if !bytes.Equal(server.data, data) {
+		t.Errorf("server has %d bytes, want %d", len(server.data), len(data))
+	}
+	if server.requests != 3 {
+		t.Errorf("server got %d requests, want 3", server.requests)
+	}
+}
+
+func TestUploadRetriesGiveUp(t *testing.T) {
+	path, _, cleanup := writeTestFile(t, 100)
+	defer cleanup()
+	server := &resumableServer{
+		fail: func(int) int { return http.StatusInternalServerError },
+	}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	err := testUploader(0).Upload(http.MethodPut, ts.URL, path, "")
+	if _, ok := errors.Cause(err).(StatusError); !ok {
+		t.Fatalf("Upload() error = %v, want StatusError", err)
+	}
+	// the first attempt and 3 retries
+	if server.requests != 4 {
+		t.Errorf("server got %d requests, want 4", server.requests)
+	}
+}
+
+func TestUploadNotRetryable(t *testing.T) {
+	path, _, cleanup := writeTestFile(t, 100)
+	defer cleanup()
+	server := &resumableServer{
+		fail: func(int) int { return http.StatusForbidden },
+	}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	if err := testUploader(0).Upload(http.MethodPut, ts.URL, path, ""); err == nil {
+		t.Fatal("Upload() succeeded, want an error")
+	}
+	if server.requests != 1 {
+		t.Errorf("server got %d requests, want 1", server.requests)
+	}
+}
+
+func TestUploadChunkedResume(t *testing.T) {
+	path, data, cleanup := writeTestFile(t, 1000)
+	defer cleanup()
+	server := &resumableServer{
+		// lose the third chunk
+		fail: func(request int) int {
+			if request == 3 {
+				return http.StatusBadGateway
+			}
+			return 0
+		},
+	}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	u := testUploader(300)
+	var progress int64
+	u.Progress = func(sent int64, total int64) { progress = sent }
+
+	if err := u.Upload(http.MethodPut, ts.URL, path, ""); err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+	if !bytes.Equal(server.data, data) {
+		t.Errorf("server has %d bytes, want %d", len(server.data), len(data))
+	}
+	// 4 chunks, the failed chunk and the query after it
+	if server.requests != 6 {
+		t.Errorf("server got %d requests, want 6", server.requests)
+	}
+	if progress != int64(len(data)) {
+		t.Errorf("progress = %d, want %d", progress, len(data))
+	}
+}
+
+func TestUploadChunkedEarlyCompletion(t *testing.T) {
+	path, _, cleanup := writeTestFile(t, 1000)
+	defer cleanup()
+	// like an S3 presigned URL, which ignores Content-Range and keeps the
+	// first chunk as the whole object
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	err := testUploader(300).Upload(http.MethodPut, ts.URL, path, "")
+	incomplete, ok := errors.Cause(err).(IncompleteError)
+	if !ok {
+		t.Fatalf("Upload() error = %v, want IncompleteError", err)
+	}
+	if incomplete.Sent != 300 || incomplete.Size != 1000 {
+		t.Errorf("Upload() error = %v, want 300 of 1000 bytes sent", err)
+	}
+}
+
+func TestUploadSingleRequestIncomplete(t *testing.T) {
+	path, _, cleanup := writeTestFile(t, 100)
+	defer cleanup()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		w.Header().Set("Range", "bytes=0-49")
+		w.WriteHeader(statusResumeIncomplete)
+	}))
+	defer ts.Close()
+
+	err := testUploader(0).Upload(http.MethodPut, ts.URL, path, "")
+	if _, ok := errors.Cause(err).(IncompleteError); !ok {
+		t.Fatalf("Upload() error = %v, want IncompleteError", err)
+	}
+}
+
+func TestUploadChunkedStuckRange(t *testing.T) {
+	path, _, cleanup := writeTestFile(t, 1000)
+	defer cleanup()
+	var mu sync.Mutex
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		ioutil.ReadAll(r.Body)
+		w.Header().Set("Range", "bytes=0-299")
+		w.WriteHeader(statusResumeIncomplete)
+	}))
+	defer ts.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- testUploader(300).Upload(http.MethodPut, ts.URL, path, "")
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("Upload() succeeded, want an error")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Upload() did not give up on a server that makes no progress")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	// the first chunk, the second chunk and a query after it for each of 3
+	// retries, and the second chunk once more before giving up
+	if requests != 1+2*3+1 {
+		t.Errorf("server got %d requests, want %d", requests, 1+2*3+1)
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		header  string
+		want    int64
+		wantErr bool
+	}{
+		{header: "", want: 0},
+		{header: "bytes=0-0", want: 1},
+		{header: "bytes=0-1048575", want: 1048576},
+		{header: "bytes=0", wantErr: true},
+		{header: "bytes=0-x", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseRange(tt.header)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseRange(%q) error = %v, wantErr %v", tt.header, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseRange(%q) = %d, want %d", tt.header, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+package version
+
+// version is set at build time with
+// -ldflags "-X github.com/croomes/kubectl-plugin/pkg/version.version=..."
+var version = "unknown"
+
+// Version returns the version of the plugin.
+func Version() string {
+	return version
+}